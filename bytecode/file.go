@@ -70,6 +70,11 @@ type Fn struct {
 	Ks     []*K
 	Ls     []int64 // locals, as indexes into the K table
 	Is     []Instr
+	// The source line of each instruction, or 0 if the instruction has no
+	// source position. It is set by the compiler for the coverage of the
+	// source, and is not part of the bytecode format, so it is empty for
+	// decoded files and assembly source.
+	Lines []int64
 }
 
 // An H is the function header representation.
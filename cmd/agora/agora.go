@@ -6,6 +6,13 @@
 // - agora asm : compile an agora assembly code file.
 // - agora dasm : disassemble an agora bytecode into assembly source.
 // - agora ast : generate the abstract syntax tree for an agora source code file.
+// - agora repl : evaluate agora statements and expressions interactively.
+// - agora fmt : format agora source code files in canonical style.
+// - agora doc : print the documentation of an agora source code file.
+// - agora vet : report suspicious constructs in agora source code files.
+// - agora test : run the tests defined in agora test files.
+// - agora cover : report a line coverage profile written by run or test.
+// - agora lsp : run the language server over stdio for editor integration.
 //
 // See `agora -h` and `agora <cmd> -h` for available options.
 package main
@@ -21,6 +28,7 @@ import (
 
 	"github.com/saward/agora/bytecode"
 	"github.com/saward/agora/compiler"
+	"github.com/saward/agora/compiler/ast"
 	"github.com/saward/agora/compiler/parser"
 	"github.com/saward/agora/runtime"
 	"github.com/saward/agora/runtime/stdlib"
@@ -122,9 +130,11 @@ type run struct {
 	Debug    bool   `short:"d" long:"debug" description:"output debug information"`
 	NoResult bool   `short:"R" long:"no-result" description:"do not print the result"`
 	Output   string `short:"o" long:"output" description:"output file"`
+	Profile  string `long:"coverprofile" description:"write a line coverage profile to this file"`
 }
 
-func (r *run) Execute(ctx context.Context, args []string) error {
+func (r *run) Execute(args []string) (err error) {
+	ctx := context.Background()
 	if len(args) < 1 {
 		return fmt.Errorf("expected an input file")
 	}
@@ -135,16 +145,23 @@ func (r *run) Execute(ctx context.Context, args []string) error {
 		c = new(compiler.Compiler)
 	}
 	ktx := runtime.NewKtx(new(runtime.FileResolver), c)
+	// Pick the compiler based on the extension of the imported modules
+	ktx.RegisterCompiler(".agoraa", new(compiler.Asm))
+	if !r.FromAsm {
+		ktx.RegisterCompiler(".agora", new(compiler.Compiler))
+	}
 	if !r.NoStdlib {
-		// Register the standard lib's Fmt package
-		ktx.RegisterNativeModule(new(stdlib.FmtMod))
-		ktx.RegisterNativeModule(new(stdlib.FilepathMod))
-		ktx.RegisterNativeModule(new(stdlib.StringsMod))
-		ktx.RegisterNativeModule(new(stdlib.MathMod))
-		ktx.RegisterNativeModule(new(stdlib.OsMod))
-		ktx.RegisterNativeModule(new(stdlib.TimeMod))
+		registerStdlib(ktx)
 	}
 	ktx.Debug = r.Debug
+	if r.Profile != "" {
+		ktx.Coverage = runtime.NewCoverage()
+		defer func() {
+			if perr := writeProfile(r.Profile, ktx.Coverage); err == nil {
+				err = perr
+			}
+		}()
+	}
 	m, err := ktx.Load(args[0])
 	if err != nil {
 		return err
@@ -166,18 +183,32 @@ func (r *run) Execute(ctx context.Context, args []string) error {
 	}
 	res, err := m.Run(ctx, vals...)
 	if err == nil && !r.NoResult {
-		fmt.Fprintf(outf, "\n= %s (%T)\n", res, res)
+		fmt.Fprint(outf, "\n")
+		printResult(ctx, outf, res)
 	}
 	return err
 }
 
-// The ast command struct
-type ast struct {
+// Register the standard library's modules in the execution context.
+func registerStdlib(ktx *runtime.Kontext) {
+	for _, m := range stdlib.Modules() {
+		ktx.RegisterNativeModule(m)
+	}
+}
+
+// Print the result value of an execution.
+func printResult(ctx context.Context, w io.Writer, v runtime.Val) {
+	fmt.Fprintf(w, "= %s (%T)\n", v.String(ctx), v)
+}
+
+// The astPrinter command struct
+type astPrinter struct {
 	Output    string `short:"o" long:"output" description:"output file"`
 	AllErrors bool   `short:"e" long:"all-errors" description:"print all errors"`
+	JSON      bool   `short:"j" long:"json" description:"print the typed syntax tree as JSON"`
 }
 
-func (a *ast) Execute(args []string) error {
+func (a *astPrinter) Execute(args []string) error {
 	if len(args) != 1 {
 		return fmt.Errorf("expected an input file")
 	}
@@ -200,13 +231,25 @@ func (a *ast) Execute(args []string) error {
 	}
 	out := stdout
 	if a.Output != "" {
-		outf, err := os.Open(a.Output)
+		outf, err := os.Create(a.Output)
 		if err != nil {
 			return err
 		}
 		defer outf.Close()
 		out = outf
 	}
+	if a.JSON {
+		tree, err := ast.FromSymbols(args[0], syms)
+		if err != nil {
+			return err
+		}
+		b, err := ast.MarshalIndentJSON(tree, "", "  ")
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintf(out, "%s\n", b)
+		return err
+	}
 	for _, sym := range syms {
 		fmt.Fprintln(out, sym)
 	}
@@ -264,15 +307,27 @@ func (v *version) Execute(args []string) error {
 }
 
 func main() {
-	a, d, r, s, b, v := new(asm), new(dasm), new(run), new(ast), new(build), new(version)
+	a, d, r, s, b, v, rp, f, vt, ls, ts, cv, dc := new(asm), new(dasm), new(run), new(astPrinter), new(build), new(version), new(repl), new(formatter), new(vetter), new(langServer), new(tester), new(coverReport), new(docPrinter)
 	p := flags.NewParser(nil, flags.Default)
 	p.AddCommand("asm", "assembler", "compile assembly to bytecode", a)
 	p.AddCommand("dasm", "disassembler", "disassemble bytecode to assembly", d)
 	p.AddCommand("run", "run", "execute a source program", r)
 	p.AddCommand("ast", "abstract syntax tree", "print the AST of a source program", s)
 	p.AddCommand("build", "compiler", "compile a source program", b)
+	p.AddCommand("repl", "interactive interpreter", "evaluate statements and expressions interactively", rp)
+	p.AddCommand("fmt", "formatter", "format source programs in canonical style", f)
+	p.AddCommand("doc", "documentation", "print the documentation of a source module", dc)
+	p.AddCommand("vet", "static analysis", "report suspicious constructs in source programs", vt)
+	p.AddCommand("test", "test runner", "run the tests of agora test files", ts)
+	p.AddCommand("cover", "coverage report", "report a line coverage profile", cv)
+	p.AddCommand("lsp", "language server", "run the language server over stdio", ls)
 	p.AddCommand("version", "print the current version", "print the current version", v)
 	// In case of errors, usage text is automatically displayed. In case of
 	// success, the Execute() method of the matching command is called.
-	p.Parse()
+	if _, err := p.Parse(); err != nil {
+		if fe, ok := err.(*flags.Error); ok && fe.Type == flags.ErrHelp {
+			return
+		}
+		os.Exit(1)
+	}
 }
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+
+	"github.com/saward/agora/cover"
+	"github.com/saward/agora/runtime"
+)
+
+// The cover command struct
+type coverReport struct {
+	Output string `short:"o" long:"output" description:"output file"`
+	HTML   bool   `short:"H" long:"html" description:"write an HTML page of the annotated source code"`
+	Text   bool   `short:"t" long:"text" description:"print the source code annotated with the line counts"`
+}
+
+// Execute the cover command, that reports the coverage profile provided as
+// argument. By default, it prints the percentage of executed lines of each
+// module.
+func (c *coverReport) Execute(args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("expected a coverage profile")
+	}
+	if c.HTML && c.Text {
+		return fmt.Errorf("the html and text options are mutually exclusive")
+	}
+	inf, err := os.Open(args[0])
+	if err != nil {
+		return err
+	}
+	defer inf.Close()
+	p, err := cover.ParseProfile(inf)
+	if err != nil {
+		return err
+	}
+	var out io.Writer = stdout
+	if c.Output != "" {
+		outf, err := os.Create(c.Output)
+		if err != nil {
+			return err
+		}
+		defer outf.Close()
+		out = outf
+	}
+	switch {
+	case c.HTML:
+		return cover.WriteHTML(out, p, readSource)
+	case c.Text:
+		return cover.WriteText(out, p, readSource)
+	}
+	return cover.WriteSummary(out, p)
+}
+
+// Returns the agora source code of the module, as found by the FileResolver
+// used by the run command.
+func readSource(id string) ([]byte, error) {
+	r, kind, err := new(runtime.FileResolver).ResolveKind(id)
+	if err != nil {
+		return nil, err
+	}
+	if rc, ok := r.(io.Closer); ok {
+		defer rc.Close()
+	}
+	if kind != ".agora" {
+		return nil, fmt.Errorf("%s: no agora source code for module", id)
+	}
+	return ioutil.ReadAll(r)
+}
+
+// Write the coverage of the modules as a profile in the file fn.
+func writeProfile(fn string, cov *runtime.Coverage) error {
+	p := cover.NewProfile()
+	p.AddCoverage(cov, "")
+	return saveProfile(fn, p)
+}
+
+// Save the profile in the file fn.
+func saveProfile(fn string, p *cover.Profile) error {
+	f, err := os.Create(fn)
+	if err != nil {
+		return err
+	}
+	if err := p.Write(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+
+	"github.com/saward/agora/compiler/doc"
+)
+
+// The doc command struct
+type docPrinter struct {
+	Output string `short:"o" long:"output" description:"output file"`
+	Format string `short:"f" long:"format" description:"output format: text, markdown or html" default:"text"`
+}
+
+// Execute the doc command, that prints the documentation of the module
+// provided as argument.
+func (d *docPrinter) Execute(args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("expected an input file")
+	}
+	var write func(*doc.Module, io.Writer) error
+	switch d.Format {
+	case "text":
+		write = (*doc.Module).WriteText
+	case "markdown", "md":
+		write = (*doc.Module).WriteMarkdown
+	case "html":
+		write = (*doc.Module).WriteHTML
+	default:
+		return fmt.Errorf("unknown format: %s", d.Format)
+	}
+	src, err := ioutil.ReadFile(args[0])
+	if err != nil {
+		return err
+	}
+	m, err := doc.Parse(args[0], src)
+	if err != nil {
+		return err
+	}
+	var out io.Writer = stdout
+	if d.Output != "" {
+		outf, err := os.Create(d.Output)
+		if err != nil {
+			return err
+		}
+		defer outf.Close()
+		out = outf
+	}
+	return write(m, out)
+}
@@ -0,0 +1,234 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"github.com/saward/agora/compiler/format"
+	"github.com/saward/agora/compiler/scanner"
+)
+
+const (
+	// The number of unchanged lines around the changes in a diff
+	diffContext = 3
+)
+
+// The fmt command struct
+type formatter struct {
+	Write bool `short:"w" long:"write" description:"write the result to the source files instead of stdout"`
+	Diff  bool `short:"d" long:"diff" description:"print the diffs instead of the formatted source"`
+}
+
+// Execute the formatter command. Each file is formatted in canonical agora style,
+// or the standard input if no file is provided.
+func (f *formatter) Execute(args []string) error {
+	if len(args) == 0 {
+		if f.Write {
+			return fmt.Errorf("cannot use -w with standard input")
+		}
+		src, err := ioutil.ReadAll(stdin)
+		if err != nil {
+			return err
+		}
+		return f.format("<standard input>", src)
+	}
+	failed := false
+	for _, fn := range args {
+		src, err := ioutil.ReadFile(fn)
+		if err == nil {
+			err = f.format(fn, src)
+		}
+		if err != nil {
+			scanner.PrintError(os.Stderr, err)
+			failed = true
+		}
+	}
+	if failed {
+		return fmt.Errorf("some files could not be formatted")
+	}
+	return nil
+}
+
+// Format the source of the file fn, and print or write the result.
+func (f *formatter) format(fn string, src []byte) error {
+	res, err := format.Source(fn, src)
+	if err != nil {
+		return err
+	}
+	if f.Diff && !bytes.Equal(src, res) {
+		printDiff(stdout, fn, src, res)
+	}
+	if f.Write {
+		if bytes.Equal(src, res) {
+			return nil
+		}
+		fi, err := os.Stat(fn)
+		if err != nil {
+			return err
+		}
+		return ioutil.WriteFile(fn, res, fi.Mode().Perm())
+	}
+	if !f.Diff {
+		_, err = stdout.Write(res)
+	}
+	return err
+}
+
+// Print the unified diff between the lines of a and b.
+func printDiff(w io.Writer, fn string, a, b []byte) {
+	// The edit script, one line per entry prefixed by ' ', '-' or '+'
+	var ops []string
+	diffLines(splitLines(a), splitLines(b), &ops)
+	// The line indices in a and b after each entry
+	ai, bi := make([]int, len(ops)), make([]int, len(ops))
+	i, j := 0, 0
+	for k, op := range ops {
+		if op[0] != '+' {
+			i++
+		}
+		if op[0] != '-' {
+			j++
+		}
+		ai[k], bi[k] = i, j
+	}
+
+	fmt.Fprintf(w, "--- %s.orig\n+++ %s\n", fn, fn)
+	for k := 0; k < len(ops); {
+		if ops[k][0] == ' ' {
+			k++
+			continue
+		}
+		// Extend the hunk while changes are close enough
+		start, end := k-diffContext, k
+		if start < 0 {
+			start = 0
+		}
+		for end < len(ops) {
+			if ops[end][0] != ' ' {
+				end++
+				continue
+			}
+			next := end
+			for next < len(ops) && ops[next][0] == ' ' {
+				next++
+			}
+			if next == len(ops) || next-end > 2*diffContext {
+				end += diffContext
+				if end > len(ops) {
+					end = len(ops)
+				}
+				break
+			}
+			end = next
+		}
+		// The line numbers before the first entry of the hunk
+		as, bs := 0, 0
+		if start > 0 {
+			as, bs = ai[start-1], bi[start-1]
+		}
+		fmt.Fprintf(w, "@@ -%d,%d +%d,%d @@\n", as+1, ai[end-1]-as, bs+1, bi[end-1]-bs)
+		for _, op := range ops[start:end] {
+			fmt.Fprintln(w, op)
+		}
+		k = end
+	}
+}
+
+// Append to ops the shortest edit script that turns the lines a into b,
+// using Myers' linear space algorithm: the middle snake of an optimal path
+// splits the problem in two smaller ones.
+func diffLines(a, b []string, ops *[]string) {
+	for len(a) > 0 && len(b) > 0 && a[0] == b[0] {
+		*ops = append(*ops, " "+a[0])
+		a, b = a[1:], b[1:]
+	}
+	n := 0
+	for n < len(a) && n < len(b) && a[len(a)-1-n] == b[len(b)-1-n] {
+		n++
+	}
+	suffix := a[len(a)-n:]
+	a, b = a[:len(a)-n], b[:len(b)-n]
+
+	switch {
+	case len(a) == 0:
+		for _, l := range b {
+			*ops = append(*ops, "+"+l)
+		}
+	case len(b) == 0:
+		for _, l := range a {
+			*ops = append(*ops, "-"+l)
+		}
+	default:
+		x, y, u, v := middleSnake(a, b)
+		diffLines(a[:x], b[:y], ops)
+		for _, l := range a[x:u] {
+			*ops = append(*ops, " "+l)
+		}
+		diffLines(a[u:], b[v:], ops)
+	}
+	for _, l := range suffix {
+		*ops = append(*ops, " "+l)
+	}
+}
+
+// Return the middle snake of an optimal edit path between a and b, from
+// (x, y) to (u, v). The forward and reverse searches progress on each
+// diagonal k = x - y, the reverse diagonal k matching the forward diagonal
+// len(a) - len(b) - k.
+func middleSnake(a, b []string) (x, y, u, v int) {
+	n, m := len(a), len(b)
+	delta := n - m
+	odd := delta%2 != 0
+	max := (n + m + 1) / 2
+	off := max + 1
+	vf, vb := make([]int, 2*max+3), make([]int, 2*max+3)
+	// Furthest x reached on diagonal k, from the start of the diagonal k-1
+	// or k+1 of the previous round
+	furthest := vf
+	next := func(k, d int) int {
+		if k == -d || (k != d && furthest[off+k-1] < furthest[off+k+1]) {
+			return furthest[off+k+1]
+		}
+		return furthest[off+k-1] + 1
+	}
+	for d := 0; d <= max; d++ {
+		furthest = vf
+		for k := -d; k <= d; k += 2 {
+			x0 := next(k, d)
+			x := x0
+			for x < n && x-k < m && a[x] == b[x-k] {
+				x++
+			}
+			vf[off+k] = x
+			if rk := delta - k; odd && rk >= -(d-1) && rk <= d-1 && x+vb[off+rk] >= n {
+				return x0, x0 - k, x, x - k
+			}
+		}
+		furthest = vb
+		for k := -d; k <= d; k += 2 {
+			x0 := next(k, d)
+			x := x0
+			for x < n && x-k < m && a[n-1-x] == b[m-1-(x-k)] {
+				x++
+			}
+			vb[off+k] = x
+			if fk := delta - k; !odd && fk >= -d && fk <= d && x+vf[off+fk] >= n {
+				return n - x, m - (x - k), n - x0, m - (x0 - k)
+			}
+		}
+	}
+	panic("unreachable")
+}
+
+// Split the source in lines, without the line terminators.
+func splitLines(b []byte) []string {
+	s := strings.TrimSuffix(string(b), "\n")
+	if s == "" {
+		return nil
+	}
+	return strings.Split(s, "\n")
+}
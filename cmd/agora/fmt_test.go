@@ -0,0 +1,84 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+var (
+	diffCases = []struct {
+		a, b  string
+		edits int
+		exp   string // The expected script, if there is only one shortest one
+	}{
+		0: {},
+		1: {a: "abc", b: "abc", exp: " a b c"},
+		2: {a: "", b: "abc", edits: 3, exp: "+a+b+c"},
+		3: {a: "abc", b: "", edits: 3, exp: "-a-b-c"},
+		4: {a: "ac", b: "abc", edits: 1, exp: " a+b c"},
+		5: {a: "abc", b: "ac", edits: 1, exp: " a-b c"},
+		6: {a: "abcabba", b: "cbabac", edits: 5},
+		7: {a: "axbxcx", b: "ybycy", edits: 7},
+		8: {a: "abcdefg", b: "xbcdyfgz", edits: 5},
+		9: {a: "aaab", b: "baaa", edits: 2, exp: "+b a a a-b"},
+	}
+)
+
+// Returns the lines of s, one per character.
+func chars(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return strings.Split(s, "")
+}
+
+func TestDiffLines(t *testing.T) {
+	for i, c := range diffCases {
+		var ops []string
+		diffLines(chars(c.a), chars(c.b), &ops)
+		// The script must turn a into b
+		var a, b string
+		edits := 0
+		for _, op := range ops {
+			if op[0] != '+' {
+				a += op[1:]
+			}
+			if op[0] != '-' {
+				b += op[1:]
+			}
+			if op[0] != ' ' {
+				edits++
+			}
+		}
+		if a != c.a || b != c.b {
+			t.Errorf("[%d] - expected the script to turn %q into %q, got %q into %q", i, c.a, c.b, a, b)
+		}
+		if edits != c.edits {
+			t.Errorf("[%d] - expected %d edits, got %d", i, c.edits, edits)
+		}
+		if got := strings.Join(ops, ""); c.exp != "" && got != c.exp {
+			t.Errorf("[%d] - expected script %q, got %q", i, c.exp, got)
+		}
+	}
+}
+
+func TestPrintDiff(t *testing.T) {
+	buf := bytes.NewBuffer(nil)
+	printDiff(buf, "f.agora", []byte("a\nb\nc\nd\ne\nf\ng\nh\ni\n"), []byte("a\nb\nc\nd\nE\nf\ng\nh\ni\n"))
+	exp := `--- f.agora.orig
++++ f.agora
+@@ -2,7 +2,7 @@
+ b
+ c
+ d
+-e
++E
+ f
+ g
+ h
+`
+	if buf.String() != exp {
+		t.Errorf("expected\n%s\ngot\n%s", exp, buf.String())
+	}
+}
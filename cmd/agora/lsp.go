@@ -0,0 +1,20 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/saward/agora/lsp"
+)
+
+// The lsp command struct
+type langServer struct{}
+
+// Execute the language server command. The server communicates over the
+// standard input and output, as started by an editor.
+func (l *langServer) Execute(args []string) error {
+	if len(args) != 0 {
+		return fmt.Errorf("unexpected arguments")
+	}
+	return lsp.NewServer().Serve(stdin, os.Stdout)
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/saward/agora/bytecode"
+	"github.com/saward/agora/compiler"
+	"github.com/saward/agora/compiler/emitter"
+	"github.com/saward/agora/compiler/parser"
+	"github.com/saward/agora/compiler/scanner"
+	"github.com/saward/agora/compiler/token"
+	"github.com/saward/agora/runtime"
+)
+
+const (
+	// The module identifier of the code entered in the REPL
+	replID = "<repl>"
+
+	// The prompts
+	replPrompt     = "> "
+	replContPrompt = "... "
+)
+
+var (
+	// For test purpose
+	stdin io.Reader = os.Stdin
+)
+
+// The repl command struct
+type repl struct {
+	NoStdlib bool `short:"S" long:"no-stdlib" description:"do not import the stdlib"`
+	Debug    bool `short:"d" long:"debug" description:"output debug information"`
+}
+
+// Execute the interactive interpreter. Each statement or expression entered is
+// compiled and executed in the same execution context, and the variables defined
+// at the top level persist across lines.
+func (r *repl) Execute(args []string) error {
+	ctx := context.Background()
+	ktx := runtime.NewKtx(new(runtime.FileResolver), new(compiler.Compiler))
+	ktx.RegisterCompiler(".agoraa", new(compiler.Asm))
+	if !r.NoStdlib {
+		registerStdlib(ktx)
+	}
+	ktx.Debug = r.Debug
+	ses := runtime.NewSession(ktx)
+
+	buf := bytes.NewBuffer(nil)
+	s := bufio.NewScanner(stdin)
+	fmt.Fprint(stdout, replPrompt)
+	for s.Scan() {
+		buf.WriteString(s.Text())
+		buf.WriteByte('\n')
+		if !isCompleteInput(buf.Bytes()) {
+			fmt.Fprint(stdout, replContPrompt)
+			continue
+		}
+		if src := strings.TrimSpace(buf.String()); src != "" {
+			r.eval(ctx, ses, src)
+		}
+		buf.Reset()
+		fmt.Fprint(stdout, replPrompt)
+	}
+	fmt.Fprintln(stdout)
+	// Report the errors of incomplete input at the end of the stream
+	if src := strings.TrimSpace(buf.String()); src != "" {
+		r.eval(ctx, ses, src)
+	}
+	return s.Err()
+}
+
+// Compile and execute the source, printing the result if it is not nil, or
+// the error.
+func (r *repl) eval(ctx context.Context, ses *runtime.Session, src string) {
+	f, err := compileReplInput(ses, src)
+	if err != nil {
+		scanner.PrintError(stdout, err)
+		return
+	}
+	v, err := ses.Run(ctx, f)
+	if err != nil {
+		fmt.Fprintf(stdout, "error: %s\n", err)
+		return
+	}
+	if v != runtime.Nil {
+		printResult(ctx, stdout, v)
+	}
+}
+
+// Compile the source entered in the REPL. The source is parsed as statements,
+// and if this fails or if it is a single function call, as an expression whose
+// value is returned. The variables of the session are predeclared.
+func compileReplInput(ses *runtime.Session, src string) (f *bytecode.File, err error) {
+	// The parser may panic on invalid input
+	defer runtime.PanicToError(&err)
+	p := parser.New()
+	p.Predeclared = ses.Vars()
+	syms, scp, err := p.Parse(replID, []byte(src))
+	// Statements are followed by the implicit `return nil`
+	if err != nil || (len(syms) == 2 && syms[0].Id == "(") {
+		if esyms, escp, eerr := p.Parse(replID, []byte("return "+src)); eerr == nil {
+			syms, scp, err = esyms, escp, nil
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	return new(emitter.Emitter).Emit(replID, syms, scp)
+}
+
+// Returns true if the source is complete, that is, if all parentheses, brackets
+// and braces are closed, and raw strings and block comments are terminated.
+func isCompleteInput(src []byte) bool {
+	var s scanner.Scanner
+	errMsg := ""
+	s.Init(replID, src, func(_ token.Position, msg string) {
+		errMsg = msg
+	})
+	depth := 0
+	for tok, lit, _ := s.Scan(); tok != token.EOF; tok, lit, _ = s.Scan() {
+		switch tok {
+		case token.LPAREN, token.LBRACK, token.LBRACE:
+			depth++
+		case token.RPAREN, token.RBRACK, token.RBRACE:
+			depth--
+		case token.STRING, token.COMMENT:
+			// Interpreted strings and line comments cannot span lines
+			if strings.HasSuffix(errMsg, "not terminated") && (lit[0] == '`' || lit[1] == '*') {
+				return false
+			}
+		}
+		errMsg = ""
+	}
+	return depth <= 0
+}
@@ -0,0 +1,383 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/saward/agora/compiler"
+	"github.com/saward/agora/compiler/ast"
+	"github.com/saward/agora/cover"
+	"github.com/saward/agora/runtime"
+	"github.com/saward/agora/runtime/stdlib"
+)
+
+const (
+	// The suffix of the test files
+	testSuffix = "_test.agora"
+)
+
+var (
+	// The names of the test functions
+	testFuncRe = regexp.MustCompile(`^Test($|[^a-z])`)
+
+	// The error returned when at least one test failed
+	errTestsFailed = errors.New("tests failed")
+)
+
+// The tester command struct
+type tester struct {
+	Verbose  bool   `short:"v" long:"verbose" description:"print the name, status and logs of all tests"`
+	Run      string `short:"r" long:"run" description:"run only the tests whose name matches this regular expression"`
+	NoStdlib bool   `short:"S" long:"no-stdlib" description:"do not import the stdlib"`
+	Cover    bool   `short:"c" long:"cover" description:"report the line coverage of the modules imported by the tests"`
+	Profile  string `long:"coverprofile" description:"write a line coverage profile to this file (implies --cover)"`
+
+	runRe *regexp.Regexp
+	cov   *runtime.Coverage // The coverage of the directory being tested
+	prof  *cover.Profile    // The coverage of all directories
+}
+
+// Execute the tests of the test files found in the directories or files
+// provided as arguments, or in the current directory. A directory that ends
+// with /... is searched recursively.
+//
+// Each test file is run as a module, and if it defines a front matter, its
+// result, output or error are checked. Then each top-level function whose name
+// starts with Test is called with the object of a stdlib.TestCase as argument.
+func (t *tester) Execute(args []string) error {
+	if t.Run != "" {
+		re, err := regexp.Compile(t.Run)
+		if err != nil {
+			return err
+		}
+		t.runRe = re
+	}
+	if t.Profile != "" {
+		t.Cover = true
+	}
+	if t.Cover {
+		t.prof = cover.NewProfile()
+	}
+	if len(args) == 0 {
+		args = []string{"."}
+	}
+	dirs, files, err := findTestFiles(args)
+	if err != nil {
+		return err
+	}
+	failed := false
+	for _, dir := range dirs {
+		ok, err := t.testDir(dir, files[dir])
+		if err != nil {
+			return err
+		}
+		failed = failed || !ok
+	}
+	if t.Profile != "" {
+		if err := saveProfile(t.Profile, t.prof); err != nil {
+			return err
+		}
+	}
+	if failed {
+		return errTestsFailed
+	}
+	return nil
+}
+
+// Returns the directories to test, in order, and their test files.
+func findTestFiles(args []string) ([]string, map[string][]string, error) {
+	var dirs []string
+	files := make(map[string][]string)
+	add := func(dir, fn string) {
+		if _, ok := files[dir]; !ok {
+			dirs = append(dirs, dir)
+			files[dir] = nil
+		}
+		if fn != "" {
+			files[dir] = append(files[dir], fn)
+		}
+	}
+	for _, arg := range args {
+		if root := strings.TrimSuffix(arg, "/..."); root != arg {
+			err := filepath.Walk(root, func(path string, fi os.FileInfo, err error) error {
+				if err != nil {
+					return err
+				}
+				if !fi.IsDir() && strings.HasSuffix(fi.Name(), testSuffix) {
+					add(filepath.Dir(path), fi.Name())
+				}
+				return nil
+			})
+			if err != nil {
+				return nil, nil, err
+			}
+			continue
+		}
+		fi, err := os.Stat(arg)
+		if err != nil {
+			return nil, nil, err
+		}
+		if !fi.IsDir() {
+			add(filepath.Dir(arg), filepath.Base(arg))
+			continue
+		}
+		fis, err := ioutil.ReadDir(arg)
+		if err != nil {
+			return nil, nil, err
+		}
+		add(arg, "")
+		for _, fi := range fis {
+			if !fi.IsDir() && strings.HasSuffix(fi.Name(), testSuffix) {
+				add(arg, fi.Name())
+			}
+		}
+	}
+	for _, fns := range files {
+		sort.Strings(fns)
+	}
+	return dirs, files, nil
+}
+
+// Run the test files of the directory, and print its summary. The tests are
+// executed in the directory, so that the modules are imported relative to it.
+// It returns false if a test failed.
+func (t *tester) testDir(dir string, fns []string) (bool, error) {
+	if len(fns) == 0 {
+		fmt.Fprintf(stdout, "?   \t%s\t[no test files]\n", dir)
+		return true, nil
+	}
+	wd, err := os.Getwd()
+	if err != nil {
+		return false, err
+	}
+	if err := os.Chdir(dir); err != nil {
+		return false, err
+	}
+	defer os.Chdir(wd)
+
+	start := time.Now()
+	if t.Cover {
+		t.cov = runtime.NewCoverage()
+	}
+	ok := true
+	for _, fn := range fns {
+		ok = t.testFile(fn) && ok
+	}
+	elapsed := time.Since(start).Seconds()
+	summary := ""
+	if t.Cover {
+		summary = "\t" + t.coverDir(dir)
+	}
+	if !ok {
+		fmt.Fprintf(stdout, "FAIL\nFAIL\t%s\t%.3fs%s\n", dir, elapsed, summary)
+		return false, nil
+	}
+	if t.Verbose {
+		fmt.Fprintln(stdout, "PASS")
+	}
+	fmt.Fprintf(stdout, "ok  \t%s\t%.3fs%s\n", dir, elapsed, summary)
+	return true, nil
+}
+
+// Add the coverage of the directory to the profile, without the test files,
+// and return its summary.
+func (t *tester) coverDir(dir string) string {
+	p := cover.NewProfile()
+	p.AddCoverage(t.cov, "")
+	t.prof.AddCoverage(t.cov, filepath.ToSlash(dir))
+	for _, prof := range []*cover.Profile{p, t.prof} {
+		for id := range prof.Counts {
+			if strings.HasSuffix(id+".agora", testSuffix) {
+				delete(prof.Counts, id)
+			}
+		}
+	}
+	if _, total := p.Stats(""); total == 0 {
+		return "coverage: [no statements]"
+	}
+	return fmt.Sprintf("coverage: %.1f%% of lines", p.Percent(""))
+}
+
+// Run the test file and its test functions. It returns false if a test failed.
+func (t *tester) testFile(fn string) bool {
+	ctx := context.Background()
+	src, err := ioutil.ReadFile(fn)
+	if err != nil {
+		return t.report(fn, 0, false, false, []string{err.Error()})
+	}
+	fm := readFrontMatter(src)
+	buf := bytes.NewBuffer(nil)
+	ktx := runtime.NewKtx(new(runtime.FileResolver), new(compiler.Compiler))
+	ktx.RegisterCompiler(".agoraa", new(compiler.Asm))
+	if !t.NoStdlib {
+		registerStdlib(ktx)
+	} else {
+		ktx.RegisterNativeModule(new(stdlib.TestingMod))
+	}
+	ktx.Coverage = t.cov
+	out := ktx.Stdout
+	ktx.Stdout = buf
+	ses := runtime.NewSession(ktx)
+
+	// Run the file as a module, and check the front matter expectations
+	if fm != nil && t.Verbose {
+		fmt.Fprintf(stdout, "=== RUN   %s\n", fn)
+	}
+	start := time.Now()
+	id := strings.TrimSuffix(fn, filepath.Ext(fn))
+	var ret runtime.Val
+	f, err := new(compiler.Compiler).Compile(id, bytes.NewReader(src))
+	if err == nil {
+		var args []runtime.Val
+		if v, ok := fm["args"]; ok {
+			for _, arg := range strings.Split(v, " ") {
+				args = append(args, runtime.String(arg))
+			}
+		}
+		ret, err = ses.Run(ctx, f, args...)
+	}
+	if _, ok := fm["output"]; !ok {
+		io.Copy(stdout, buf)
+	}
+	if fm != nil || err != nil {
+		fails := checkFrontMatter(ctx, fm, ret, buf.String(), err)
+		if !t.report(fn, time.Since(start), false, len(fails) > 0, fails) || f == nil {
+			return len(fails) == 0
+		}
+	}
+
+	// Run the test functions, in source order
+	ktx.Stdout = out
+	tree, err := ast.Parse(fn, src)
+	if err != nil {
+		return t.report(fn, 0, false, true, []string{err.Error()})
+	}
+	ok := true
+	for _, st := range tree.Stmts {
+		fd, isFunc := st.(*ast.FuncDecl)
+		if !isFunc || !testFuncRe.MatchString(fd.Name.Name) {
+			continue
+		}
+		if t.runRe != nil && !t.runRe.MatchString(fd.Name.Name) {
+			continue
+		}
+		tfn, isFunc := ses.Get(fd.Name.Name).(runtime.Func)
+		if !isFunc {
+			continue
+		}
+		if t.Verbose {
+			fmt.Fprintf(stdout, "=== RUN   %s\n", fd.Name.Name)
+		}
+		tc := stdlib.NewTestCase(ktx, fd.Name.Name)
+		start := time.Now()
+		tc.Run(ctx, tfn)
+		ok = t.report(tc.Name(), time.Since(start), tc.Skipped(), tc.Failed(), tc.Logs()) && ok
+	}
+	return ok
+}
+
+// Print the result of a test, if it failed or in verbose mode. It returns
+// false if the test failed.
+func (t *tester) report(nm string, d time.Duration, skipped, failed bool, logs []string) bool {
+	status := "PASS"
+	switch {
+	case failed:
+		status = "FAIL"
+	case skipped:
+		status = "SKIP"
+	}
+	if failed || t.Verbose {
+		fmt.Fprintf(stdout, "--- %s: %s (%.2fs)\n", status, nm, d.Seconds())
+		for _, l := range logs {
+			fmt.Fprintf(stdout, "    %s\n", strings.Replace(l, "\n", "\n    ", -1))
+		}
+	}
+	return !failed
+}
+
+// Returns the failures of the expectations of the front matter, given the
+// result, output and error of the execution of the module. Without front
+// matter, the only expectation is that the execution succeeds.
+func checkFrontMatter(ctx context.Context, fm map[string]string, ret runtime.Val, out string, err error) []string {
+	var fails []string
+	if v, ok := fm["error"]; ok {
+		if err == nil {
+			fails = append(fails, fmt.Sprintf("expected error '%s', got none", v))
+		} else if err.Error() != v {
+			fails = append(fails, fmt.Sprintf("expected error '%s', got '%s'", v, err))
+		}
+		return fails
+	}
+	if err != nil {
+		return append(fails, err.Error())
+	}
+	if v, ok := fm["result"]; ok {
+		if got := ret.String(ctx); normalizeObjects(got) != normalizeObjects(unescapeFrontMatter(v)) {
+			fails = append(fails, fmt.Sprintf("expected result '%s', got '%s'", v, got))
+		}
+	}
+	if v, ok := fm["output"]; ok {
+		if normalizeObjects(out) != normalizeObjects(unescapeFrontMatter(v)) {
+			fails = append(fails, fmt.Sprintf("expected output '%s', got '%s'", v, out))
+		}
+	}
+	return fails
+}
+
+// The string representation of an object without nested objects
+var objectRe = regexp.MustCompile(`\{[^{}]*\}`)
+
+// Sort the key-value pairs of the string representations of the objects in s,
+// so that the order of the keys is ignored when comparing results or outputs.
+func normalizeObjects(s string) string {
+	return objectRe.ReplaceAllStringFunc(s, func(ob string) string {
+		kvs := strings.Split(ob[1:len(ob)-1], ",")
+		sort.Strings(kvs)
+		return "{" + strings.Join(kvs, ",") + "}"
+	})
+}
+
+// Replace the escaped newlines and tabs of a front matter value.
+func unescapeFrontMatter(v string) string {
+	v = strings.Replace(v, "\\n", "\n", -1)
+	return strings.Replace(v, "\\t", "\t", -1)
+}
+
+// Returns the fields of the YAML front matter of the source, or nil if it has
+// none. The front matter is a block comment at the start of the file, delimited
+// by /*--- and ---*/ lines, with one `field: value` per line.
+func readFrontMatter(src []byte) map[string]string {
+	m := make(map[string]string)
+	infm := false
+	s := bufio.NewScanner(bytes.NewReader(src))
+	for s.Scan() {
+		l := strings.Trim(s.Text(), " ")
+		switch {
+		case l == "/*---" || l == "---*/":
+			if infm {
+				return m
+			}
+			infm = true
+		case infm:
+			sections := strings.SplitN(l, ":", 2)
+			if len(sections) != 2 {
+				return nil
+			}
+			m[sections[0]] = strings.Trim(sections[1], " ")
+		case l != "":
+			return nil
+		}
+	}
+	return nil
+}
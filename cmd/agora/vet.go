@@ -0,0 +1,55 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"strings"
+
+	"github.com/saward/agora/compiler/scanner"
+	"github.com/saward/agora/compiler/vet"
+)
+
+// The vet command struct
+type vetter struct {
+	Checks string `short:"c" long:"checks" description:"comma-separated list of checks to run (default all)"`
+	List   bool   `short:"l" long:"list" description:"list the available checks"`
+}
+
+// Execute the vet command. The checks are run on each source file, and the
+// problems are printed one per line, prefixed with their position.
+func (v *vetter) Execute(args []string) error {
+	if v.List {
+		for _, c := range vet.Checks {
+			fmt.Fprintf(stdout, "%-12s %s\n", c.Name, c.Doc)
+		}
+		return nil
+	}
+	var checks []*vet.Check
+	if v.Checks != "" {
+		for _, nm := range strings.Split(v.Checks, ",") {
+			c := vet.Lookup(strings.TrimSpace(nm))
+			if c == nil {
+				return fmt.Errorf("unknown check: %s", nm)
+			}
+			checks = append(checks, c)
+		}
+	}
+	if len(args) == 0 {
+		return fmt.Errorf("expected at least one input file name")
+	}
+	failed := false
+	for _, fn := range args {
+		src, err := ioutil.ReadFile(fn)
+		if err == nil {
+			err = vet.Source(fn, src, checks...)
+		}
+		if err != nil {
+			scanner.PrintError(stdout, err)
+			failed = true
+		}
+	}
+	if failed {
+		return fmt.Errorf("vet found problems")
+	}
+	return nil
+}
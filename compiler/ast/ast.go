@@ -0,0 +1,302 @@
+// Package ast declares the types used to represent the syntax trees of agora
+// source code.
+//
+// The parser produces a tree of parser.Symbol values, whose children are
+// untyped and depend on the arity of each symbol, which is what the emitter
+// needs to generate the bytecode. This package converts that tree to typed
+// nodes, with the positions of their tokens, so that tools such as formatters,
+// linters and code generators can inspect the source code without knowing the
+// internals of the parser.
+package ast
+
+import (
+	"github.com/saward/agora/compiler/token"
+)
+
+// Node is implemented by all the node types.
+type Node interface {
+	// Pos returns the position of the first token of the node, or the zero
+	// position if the node has no token in the source.
+	Pos() token.Position
+}
+
+// Expr is implemented by all the expression nodes.
+type Expr interface {
+	Node
+	exprNode()
+}
+
+// Stmt is implemented by all the statement nodes.
+type Stmt interface {
+	Node
+	stmtNode()
+}
+
+// ----------------------------------------------------------------------------
+// Expressions
+
+type (
+	// An Ident is a variable name, or one of the builtins, the this and args
+	// keywords or the true, false and nil constants.
+	Ident struct {
+		NamePos token.Position
+		Name    string
+	}
+
+	// A BasicLit is a number or string literal.
+	BasicLit struct {
+		ValuePos token.Position
+		Kind     token.Token // token.INT, token.FLOAT or token.STRING
+		Value    string      // The literal as written in the source, e.g. "foo" with its quotes
+	}
+
+	// A FuncLit is a function literal.
+	FuncLit struct {
+		Func   token.Position // Position of the func keyword
+		Params []*Ident
+		Body   *BlockStmt
+	}
+
+	// An ObjectLit is an object literal.
+	ObjectLit struct {
+		Lbrace token.Position
+		Fields []*Field
+	}
+
+	// A Field is a key-value pair of an object literal.
+	Field struct {
+		Doc   string // The doc comment of the key, without comment markers
+		Key   string // The key as written in the source, a name or a literal
+		Value Expr
+	}
+
+	// A SelectorExpr is a field selection with the dot notation.
+	SelectorExpr struct {
+		X   Expr
+		Sel *Ident
+	}
+
+	// An IndexExpr is a field selection with the array notation.
+	IndexExpr struct {
+		X     Expr
+		Index Expr
+	}
+
+	// A CallExpr is a function call. Method calls have a SelectorExpr or an
+	// IndexExpr as function.
+	CallExpr struct {
+		Fun    Expr
+		Lparen token.Position
+		Args   []Expr
+	}
+
+	// A UnaryExpr is a unary expression.
+	UnaryExpr struct {
+		OpPos token.Position
+		Op    token.Token // token.SUB or token.NOT
+		X     Expr
+	}
+
+	// A BinaryExpr is a binary expression.
+	BinaryExpr struct {
+		X     Expr
+		OpPos token.Position
+		Op    token.Token
+		Y     Expr
+	}
+
+	// A CondExpr is a ternary conditional expression.
+	CondExpr struct {
+		Cond     Expr
+		Question token.Position
+		Then     Expr
+		Else     Expr
+	}
+
+	// A RangeExpr is the range expression of a ForRange statement.
+	RangeExpr struct {
+		Range token.Position
+		Args  []Expr
+	}
+
+	// A YieldExpr is a yield expression. Value is nil if no value is yielded.
+	YieldExpr struct {
+		Yield token.Position
+		Value Expr
+	}
+)
+
+// Pos implementations for the expression nodes.
+
+func (x *Ident) Pos() token.Position        { return x.NamePos }
+func (x *BasicLit) Pos() token.Position     { return x.ValuePos }
+func (x *FuncLit) Pos() token.Position      { return x.Func }
+func (x *ObjectLit) Pos() token.Position    { return x.Lbrace }
+func (x *Field) Pos() token.Position        { return x.Value.Pos() }
+func (x *SelectorExpr) Pos() token.Position { return x.X.Pos() }
+func (x *IndexExpr) Pos() token.Position    { return x.X.Pos() }
+func (x *CallExpr) Pos() token.Position     { return x.Fun.Pos() }
+func (x *UnaryExpr) Pos() token.Position    { return x.OpPos }
+func (x *BinaryExpr) Pos() token.Position   { return x.X.Pos() }
+func (x *CondExpr) Pos() token.Position     { return x.Cond.Pos() }
+func (x *RangeExpr) Pos() token.Position    { return x.Range }
+func (x *YieldExpr) Pos() token.Position    { return x.Yield }
+
+func (*Ident) exprNode()        {}
+func (*BasicLit) exprNode()     {}
+func (*FuncLit) exprNode()      {}
+func (*ObjectLit) exprNode()    {}
+func (*SelectorExpr) exprNode() {}
+func (*IndexExpr) exprNode()    {}
+func (*CallExpr) exprNode()     {}
+func (*UnaryExpr) exprNode()    {}
+func (*BinaryExpr) exprNode()   {}
+func (*CondExpr) exprNode()     {}
+func (*RangeExpr) exprNode()    {}
+func (*YieldExpr) exprNode()    {}
+
+// ----------------------------------------------------------------------------
+// Statements
+
+type (
+	// An AssignStmt is a definition (:=), an assignment (=) or an assignment
+	// operation (+=, -=, etc.). Assignments are also expressions.
+	AssignStmt struct {
+		Doc    string // The doc comment of the statement, empty in an expression
+		Lhs    Expr
+		TokPos token.Position
+		Tok    token.Token
+		Rhs    Expr
+	}
+
+	// An IncDecStmt is an increment or decrement statement. It is also an
+	// expression.
+	IncDecStmt struct {
+		X      Expr
+		TokPos token.Position
+		Tok    token.Token // token.INC or token.DEC
+	}
+
+	// An ExprStmt is an expression used as statement, a call or a yield.
+	ExprStmt struct {
+		X Expr
+	}
+
+	// A FuncDecl is a function declared with the func statement.
+	FuncDecl struct {
+		Doc    string         // The doc comment, without comment markers
+		Func   token.Position // Position of the func keyword
+		Name   *Ident
+		Params []*Ident
+		Body   *BlockStmt
+	}
+
+	// A ReturnStmt is a return statement. Result is nil for an empty return.
+	ReturnStmt struct {
+		Return token.Position
+		Result Expr
+	}
+
+	// A BranchStmt is a break or continue statement.
+	BranchStmt struct {
+		TokPos token.Position
+		Tok    token.Token // token.BREAK or token.CONTINUE
+	}
+
+	// A DebugStmt is a debug statement. Count is nil if no number of stack
+	// traces is specified.
+	DebugStmt struct {
+		Debug token.Position
+		Count *BasicLit
+	}
+
+	// An IfStmt is an if statement. Else is nil, a *BlockStmt or an *IfStmt.
+	IfStmt struct {
+		If   token.Position
+		Cond Expr
+		Body *BlockStmt
+		Else Stmt
+	}
+
+	// A ForStmt is a for loop. Init, Cond and Post are nil for an infinite
+	// loop, and only Cond is set for the single expression form.
+	ForStmt struct {
+		For  token.Position
+		Init Stmt
+		Cond Expr
+		Post Stmt
+		Body *BlockStmt
+	}
+
+	// A ForRange is a for loop over a range expression. Key is the variable
+	// defined (Tok is token.DEFINE) or assigned (Tok is token.ASSIGN) with each
+	// value of the range, it is nil if the values are discarded.
+	ForRange struct {
+		For   token.Position
+		Key   Expr
+		Tok   token.Token
+		Range *RangeExpr
+		Body  *BlockStmt
+	}
+
+	// A BlockStmt is a list of statements in braces. The positions of the
+	// braces are not recorded.
+	BlockStmt struct {
+		List []Stmt
+	}
+)
+
+// Pos implementations for the statement nodes.
+
+func (s *AssignStmt) Pos() token.Position { return s.Lhs.Pos() }
+func (s *IncDecStmt) Pos() token.Position { return s.X.Pos() }
+func (s *ExprStmt) Pos() token.Position   { return s.X.Pos() }
+func (s *FuncDecl) Pos() token.Position   { return s.Func }
+func (s *ReturnStmt) Pos() token.Position { return s.Return }
+func (s *BranchStmt) Pos() token.Position { return s.TokPos }
+func (s *DebugStmt) Pos() token.Position  { return s.Debug }
+func (s *IfStmt) Pos() token.Position     { return s.If }
+func (s *ForStmt) Pos() token.Position    { return s.For }
+func (s *ForRange) Pos() token.Position   { return s.For }
+
+// Pos returns the position of the first statement of the block.
+func (s *BlockStmt) Pos() token.Position {
+	if len(s.List) == 0 {
+		return token.Position{}
+	}
+	return s.List[0].Pos()
+}
+
+func (*AssignStmt) stmtNode() {}
+func (*IncDecStmt) stmtNode() {}
+func (*ExprStmt) stmtNode()   {}
+func (*FuncDecl) stmtNode()   {}
+func (*ReturnStmt) stmtNode() {}
+func (*BranchStmt) stmtNode() {}
+func (*DebugStmt) stmtNode()  {}
+func (*IfStmt) stmtNode()     {}
+func (*ForStmt) stmtNode()    {}
+func (*ForRange) stmtNode()   {}
+func (*BlockStmt) stmtNode()  {}
+
+// Assignments and increments are both statements and expressions.
+func (*AssignStmt) exprNode() {}
+func (*IncDecStmt) exprNode() {}
+
+// ----------------------------------------------------------------------------
+// File
+
+// A File is the syntax tree of a source file, which is the body of the
+// top-level function of a module.
+type File struct {
+	Name  string // The file name given to the parser
+	Stmts []Stmt
+}
+
+// Pos returns the position of the first statement of the file.
+func (f *File) Pos() token.Position {
+	if len(f.Stmts) == 0 {
+		return token.Position{}
+	}
+	return f.Stmts[0].Pos()
+}
@@ -0,0 +1,258 @@
+package ast
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/saward/agora/compiler/token"
+)
+
+// Returns the types of the nodes of the tree in depth-first order, with the
+// names of identifiers and the values of literals.
+func nodeList(n Node) string {
+	var l []string
+	Inspect(n, func(n Node) bool {
+		switch n := n.(type) {
+		case nil:
+			return false
+		case *Ident:
+			l = append(l, n.Name)
+		case *BasicLit:
+			l = append(l, n.Value)
+		case *File:
+		default:
+			l = append(l, strings.TrimPrefix(fmt.Sprintf("%T", n), "*ast."))
+		}
+		return true
+	})
+	return strings.Join(l, " ")
+}
+
+var (
+	cases = []struct {
+		src string
+		exp string
+	}{
+		0: {
+			src: `a := 1`,
+			exp: `AssignStmt a 1`,
+		},
+		1: {
+			src: `func add(x, y) {
+	return x + y
+}`,
+			exp: `FuncDecl add x y BlockStmt ReturnStmt BinaryExpr x y`,
+		},
+		2: {
+			src: `f := func() {
+	return
+}`,
+			exp: `AssignStmt f FuncLit BlockStmt ReturnStmt`,
+		},
+		3: {
+			src: `o := {a: 1, "b": nil}
+o.c = o["b"]
+o.m(2)
+o["n"]()`,
+			exp: `AssignStmt o ObjectLit Field 1 Field nil ` +
+				`AssignStmt SelectorExpr o c IndexExpr o "b" ` +
+				`ExprStmt CallExpr SelectorExpr o m 2 ` +
+				`ExprStmt CallExpr IndexExpr o "n"`,
+		},
+		4: {
+			src: `a := 2
+if a > 1 {
+	a--
+} else if !(a < 0) {
+	a = -a
+} else {
+	a += 1.5
+}`,
+			exp: `AssignStmt a 2 IfStmt BinaryExpr a 1 BlockStmt IncDecStmt a ` +
+				`IfStmt UnaryExpr BinaryExpr a 0 BlockStmt AssignStmt a UnaryExpr a ` +
+				`BlockStmt AssignStmt a 1.5`,
+		},
+		5: {
+			src: `for {
+	break
+}
+for true {
+	continue
+}
+for i := 0; i < 3; i++ {
+	debug 2
+}`,
+			exp: `ForStmt BlockStmt BranchStmt ForStmt true BlockStmt BranchStmt ` +
+				`ForStmt AssignStmt i 0 BinaryExpr i 3 IncDecStmt i BlockStmt DebugStmt 2`,
+		},
+		6: {
+			src: `k := 0
+for k = range 10, 2 {
+}
+for v := range {} {
+	yield v
+}
+x := k == 1 ? len(k) : args`,
+			exp: `AssignStmt k 0 ForRange k RangeExpr 10 2 BlockStmt ` +
+				`ForRange v RangeExpr ObjectLit BlockStmt ExprStmt YieldExpr v ` +
+				`AssignStmt x CondExpr BinaryExpr k 1 CallExpr len k args`,
+		},
+	}
+)
+
+func TestParse(t *testing.T) {
+	for i, c := range cases {
+		f, err := Parse("test", []byte(c.src))
+		if err != nil {
+			t.Errorf("[%d] - unexpected error: %s", i, err)
+			continue
+		}
+		if got := nodeList(f); got != c.exp {
+			t.Errorf("[%d] - expected\n%s\ngot\n%s", i, c.exp, got)
+		}
+	}
+}
+
+func TestParseError(t *testing.T) {
+	if _, err := Parse("test", []byte("a := ")); err == nil {
+		t.Error("expected an error")
+	}
+}
+
+func TestNodes(t *testing.T) {
+	src := `func f(x) {
+	return x.y(2)
+}
+for k := range 3 {
+	k++
+}
+`
+	f, err := Parse("test", []byte(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	pos := func(n Node) string {
+		p := n.Pos()
+		return fmt.Sprintf("%d:%d", p.Line, p.Column)
+	}
+	fd := f.Stmts[0].(*FuncDecl)
+	if pos(fd) != "1:1" || pos(fd.Name) != "1:6" || pos(fd.Params[0]) != "1:8" {
+		t.Errorf("func: unexpected positions %s %s %s", pos(fd), pos(fd.Name), pos(fd.Params[0]))
+	}
+	ret := fd.Body.List[0].(*ReturnStmt)
+	call := ret.Result.(*CallExpr)
+	if pos(ret) != "2:2" || pos(call) != "2:9" || fmt.Sprintf("%d:%d", call.Lparen.Line, call.Lparen.Column) != "2:12" {
+		t.Errorf("return: unexpected positions %s %s %v", pos(ret), pos(call), call.Lparen)
+	}
+	fr := f.Stmts[1].(*ForRange)
+	if fr.Tok != token.DEFINE || fr.Key.(*Ident).Name != "k" || len(fr.Range.Args) != 1 {
+		t.Errorf("for range: unexpected node %#v", fr)
+	}
+	if lit := fr.Range.Args[0].(*BasicLit); lit.Kind != token.INT {
+		t.Errorf("literal: expected kind %s, got %s", token.INT, lit.Kind)
+	}
+	if id := fr.Body.List[0].(*IncDecStmt); id.Tok != token.INC || pos(id) != "5:2" {
+		t.Errorf("inc: unexpected node %#v", id)
+	}
+}
+
+func TestWalkLeave(t *testing.T) {
+	f, err := Parse("test", []byte("a := b := 1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	depth, max := 0, 0
+	Inspect(f, func(n Node) bool {
+		if n == nil {
+			depth--
+			return false
+		}
+		depth++
+		if depth > max {
+			max = depth
+		}
+		return true
+	})
+	if depth != 0 || max != 4 {
+		t.Errorf("expected depth 0 and max 4, got %d and %d", depth, max)
+	}
+}
+
+func TestMarshalJSON(t *testing.T) {
+	f, err := Parse("test", []byte("x := -1\nreturn x"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := MarshalIndentJSON(f, "", "  ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var v struct {
+		Type  string
+		Stmts []struct {
+			Type string
+			Tok  string
+			Rhs  struct {
+				Type  string
+				Op    string
+				OpPos struct{ Line, Column int }
+			}
+		}
+	}
+	if err := json.Unmarshal(b, &v); err != nil {
+		t.Fatal(err)
+	}
+	if v.Type != "File" || len(v.Stmts) != 2 || v.Stmts[1].Type != "ReturnStmt" {
+		t.Fatalf("unexpected JSON: %s", b)
+	}
+	if s := v.Stmts[0]; s.Tok != ":=" || s.Rhs.Type != "UnaryExpr" || s.Rhs.Op != "-" || s.Rhs.OpPos.Column != 6 {
+		t.Errorf("unexpected JSON: %s", b)
+	}
+	if !strings.HasPrefix(string(b), "{\n  \"Type\": \"File\",\n  \"Name\": \"test\",") {
+		t.Errorf("expected the members in declaration order, got %s", b)
+	}
+}
+
+func TestTestdata(t *testing.T) {
+	files, err := filepath.Glob("../../testdata/src/*.agora")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, fn := range files {
+		b, err := ioutil.ReadFile(fn)
+		if err != nil {
+			t.Fatal(err)
+		}
+		f, err := Parse(fn, b)
+		if err != nil {
+			if _, ok := err.(convertError); ok {
+				t.Errorf("%s: %s", fn, err)
+			}
+			continue
+		}
+		if _, err := MarshalJSON(f); err != nil {
+			t.Errorf("%s: %s", fn, err)
+		}
+	}
+}
+
+func TestDoc(t *testing.T) {
+	f, err := Parse("test", []byte("// F is f.\nfunc F() {\n}\n// X is x.\nX := {\n\t// K is k.\n\tK: 1,\n}\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d := f.Stmts[0].(*FuncDecl).Doc; d != "F is f." {
+		t.Errorf("expected the func doc, got %q", d)
+	}
+	as := f.Stmts[1].(*AssignStmt)
+	if as.Doc != "X is x." {
+		t.Errorf("expected the definition doc, got %q", as.Doc)
+	}
+	if d := as.Rhs.(*ObjectLit).Fields[0].Doc; d != "K is k." {
+		t.Errorf("expected the key doc, got %q", d)
+	}
+}
@@ -0,0 +1,305 @@
+package ast
+
+import (
+	"fmt"
+
+	"github.com/saward/agora/compiler/parser"
+	"github.com/saward/agora/compiler/scanner"
+	"github.com/saward/agora/compiler/token"
+)
+
+// Parse parses the source code and returns its syntax tree. The error is a
+// scanner.ErrorList if the source is invalid.
+func Parse(filename string, src []byte) (f *File, err error) {
+	defer func() {
+		if e := recover(); e != nil {
+			if el, ok := e.(scanner.ErrorList); ok {
+				err = el
+				return
+			}
+			err = fmt.Errorf("%s: invalid source: %v", filename, e)
+		}
+	}()
+	syms, _, err := parser.New().Parse(filename, src)
+	if err != nil {
+		return nil, err
+	}
+	return FromSymbols(filename, syms)
+}
+
+// FromSymbols converts the symbols returned by the parser to a syntax tree.
+// The return statements added by the parser at the end of the functions are
+// not part of the tree.
+func FromSymbols(filename string, syms []*parser.Symbol) (f *File, err error) {
+	defer func() {
+		if e := recover(); e != nil {
+			if ce, ok := e.(convertError); ok {
+				err = ce
+				return
+			}
+			panic(e)
+		}
+	}()
+	return &File{Name: filename, Stmts: stmtList(syms)}, nil
+}
+
+// A convertError is raised when a symbol cannot be converted to a node.
+type convertError struct {
+	sym *parser.Symbol
+	msg string
+}
+
+func (e convertError) Error() string {
+	return fmt.Sprintf("%s: %s (symbol %s)", e.sym.Pos(), e.msg, e.sym.Id)
+}
+
+func fail(sym *parser.Symbol, msg string) {
+	panic(convertError{sym, msg})
+}
+
+// Returns the child of the symbol as a single symbol.
+func child(sym *parser.Symbol, v interface{}) *parser.Symbol {
+	s, ok := v.(*parser.Symbol)
+	if !ok || s == nil {
+		fail(sym, "expected a symbol operand")
+	}
+	return s
+}
+
+// Returns the child of the symbol as a list of symbols.
+func children(sym *parser.Symbol, v interface{}) []*parser.Symbol {
+	if v == nil {
+		return nil
+	}
+	l, ok := v.([]*parser.Symbol)
+	if !ok {
+		fail(sym, "expected a list of symbols")
+	}
+	return l
+}
+
+// Returns true if the symbol was generated by the parser and is not in the
+// source, such as the implicit return at the end of functions.
+func implicit(sym *parser.Symbol) bool {
+	return !sym.Pos().IsValid()
+}
+
+func stmtList(syms []*parser.Symbol) []Stmt {
+	var l []Stmt
+	for _, s := range syms {
+		if s.Id == "return" && implicit(s) {
+			continue
+		}
+		l = append(l, stmt(s))
+	}
+	return l
+}
+
+func block(sym *parser.Symbol, v interface{}) *BlockStmt {
+	return &BlockStmt{List: stmtList(children(sym, v))}
+}
+
+func stmt(sym *parser.Symbol) Stmt {
+	switch sym.Id {
+	case "func":
+		if sym.Name == "" {
+			return &ExprStmt{expr(sym)}
+		}
+		fl := funcLit(sym)
+		return &FuncDecl{
+			Doc:    sym.Doc(),
+			Func:   fl.Func,
+			Name:   &Ident{sym.NamePos(), sym.Name},
+			Params: fl.Params,
+			Body:   fl.Body,
+		}
+
+	case "return":
+		s := &ReturnStmt{Return: sym.Pos()}
+		if r := child(sym, sym.First); !(r.Id == "nil" && implicit(r)) {
+			s.Result = expr(r)
+		}
+		return s
+
+	case "break":
+		return &BranchStmt{sym.Pos(), token.BREAK}
+
+	case "continue":
+		return &BranchStmt{sym.Pos(), token.CONTINUE}
+
+	case "debug":
+		s := &DebugStmt{Debug: sym.Pos()}
+		if sym.First != nil {
+			s.Count = expr(child(sym, sym.First)).(*BasicLit)
+		}
+		return s
+
+	case "if":
+		s := &IfStmt{
+			If:   sym.Pos(),
+			Cond: expr(child(sym, sym.First)),
+			Body: block(sym, sym.Second),
+		}
+		switch e := sym.Third.(type) {
+		case *parser.Symbol:
+			s.Else = stmt(e)
+		case []*parser.Symbol:
+			s.Else = block(sym, e)
+		}
+		return s
+
+	case "for":
+		s := &ForStmt{For: sym.Pos(), Body: block(sym, sym.Second)}
+		switch f := sym.First.(type) {
+		case *parser.Symbol:
+			s.Cond = expr(f)
+		case []interface{}:
+			s.Init = simpleStmt(child(sym, f[0]))
+			s.Cond = expr(child(sym, f[1]))
+			s.Post = simpleStmt(child(sym, f[2]))
+		}
+		return s
+
+	case "forr":
+		s := &ForRange{For: sym.Pos(), Body: block(sym, sym.Second)}
+		r := child(sym, sym.First)
+		if r.Id != "range" {
+			s.Key = expr(child(r, r.First))
+			s.Tok = r.Tok()
+			r = child(r, r.Second)
+		}
+		rng, ok := expr(r).(*RangeExpr)
+		if !ok {
+			fail(sym, "expected a range expression")
+		}
+		s.Range = rng
+		return s
+	}
+	return simpleStmt(sym)
+}
+
+// Returns the statement for an expression symbol.
+func simpleStmt(sym *parser.Symbol) Stmt {
+	switch x := expr(sym).(type) {
+	case *AssignStmt:
+		x.Doc = sym.Doc()
+		return x
+	case *IncDecStmt:
+		return x
+	default:
+		return &ExprStmt{x}
+	}
+}
+
+func funcLit(sym *parser.Symbol) *FuncLit {
+	fl := &FuncLit{Func: sym.Pos(), Body: block(sym, sym.Second)}
+	for _, p := range children(sym, sym.First) {
+		fl.Params = append(fl.Params, ident(p))
+	}
+	return fl
+}
+
+func ident(sym *parser.Symbol) *Ident {
+	if nm, ok := sym.Val.(string); ok && sym.Id == "(name)" {
+		return &Ident{sym.Pos(), nm}
+	}
+	return &Ident{sym.Pos(), sym.Id}
+}
+
+func exprList(sym *parser.Symbol, v interface{}) []Expr {
+	var l []Expr
+	for _, s := range children(sym, v) {
+		l = append(l, expr(s))
+	}
+	return l
+}
+
+func expr(sym *parser.Symbol) Expr {
+	switch sym.Id {
+	case "(name)":
+		return ident(sym)
+
+	case "(literal)":
+		lit, _ := sym.Val.(string)
+		return &BasicLit{sym.Pos(), sym.Tok(), lit}
+
+	case "true", "false", "nil", "this", "args":
+		return &Ident{sym.Pos(), sym.Id}
+
+	case "func":
+		return funcLit(sym)
+
+	case "{":
+		ol := &ObjectLit{Lbrace: sym.Pos()}
+		for _, v := range children(sym, sym.First) {
+			ol.Fields = append(ol.Fields, &Field{v.Doc(), fmt.Sprint(v.Key), expr(v)})
+		}
+		return ol
+
+	case ".":
+		return &SelectorExpr{expr(child(sym, sym.First)), ident(child(sym, sym.Second))}
+
+	case "[":
+		return &IndexExpr{expr(child(sym, sym.First)), expr(child(sym, sym.Second))}
+
+	case "(":
+		if sym.Ar == parser.ArTernary {
+			// Method call, the selector is folded in the call symbol
+			x, key := expr(child(sym, sym.First)), child(sym, sym.Second)
+			var fn Expr
+			if key.Id == "(name)" {
+				fn = &SelectorExpr{x, ident(key)}
+			} else {
+				fn = &IndexExpr{x, expr(key)}
+			}
+			return &CallExpr{fn, sym.Pos(), exprList(sym, sym.Third)}
+		}
+		return &CallExpr{expr(child(sym, sym.First)), sym.Pos(), exprList(sym, sym.Second)}
+
+	case "?":
+		return &CondExpr{
+			Cond:     expr(child(sym, sym.First)),
+			Question: sym.Pos(),
+			Then:     expr(child(sym, sym.Second)),
+			Else:     expr(child(sym, sym.Third)),
+		}
+
+	case "range":
+		return &RangeExpr{sym.Pos(), exprList(sym, sym.First)}
+
+	case "yield":
+		y := &YieldExpr{Yield: sym.Pos()}
+		if v := child(sym, sym.First); !(v.Id == "nil" && implicit(v)) {
+			y.Value = expr(v)
+		}
+		return y
+
+	case ":=", "=", "+=", "-=", "*=", "/=", "%=":
+		return &AssignStmt{
+			Lhs:    expr(child(sym, sym.First)),
+			TokPos: sym.Pos(),
+			Tok:    sym.Tok(),
+			Rhs:    expr(child(sym, sym.Second)),
+		}
+
+	case "++", "--":
+		return &IncDecStmt{expr(child(sym, sym.First)), sym.Pos(), sym.Tok()}
+	}
+
+	switch sym.Ar {
+	case parser.ArName:
+		// The builtins
+		return ident(sym)
+	case parser.ArUnary:
+		return &UnaryExpr{sym.Pos(), sym.Tok(), expr(child(sym, sym.First))}
+	case parser.ArBinary:
+		return &BinaryExpr{
+			X:     expr(child(sym, sym.First)),
+			OpPos: sym.Pos(),
+			Op:    sym.Tok(),
+			Y:     expr(child(sym, sym.Second)),
+		}
+	}
+	fail(sym, "unexpected symbol")
+	return nil
+}
@@ -0,0 +1,97 @@
+package ast
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+
+	"github.com/saward/agora/compiler/token"
+)
+
+// MarshalJSON returns the JSON encoding of the syntax tree rooted at node.
+// Each node is encoded as an object with a "Type" member holding the name of
+// its type, e.g. "IfStmt", followed by its fields in declaration order.
+// Positions are encoded as objects with the line and column, tokens as their
+// string representation, and nil nodes as null.
+func MarshalJSON(node Node) ([]byte, error) {
+	return json.Marshal(jsonValue(reflect.ValueOf(node)))
+}
+
+// MarshalIndentJSON is like MarshalJSON but applies json.Indent to format the
+// output, with each element of the tree on a new line.
+func MarshalIndentJSON(node Node, prefix, indent string) ([]byte, error) {
+	b, err := MarshalJSON(node)
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	if err := json.Indent(&buf, b, prefix, indent); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// A jsonObject is a JSON object that keeps the order of its members.
+type jsonObject []jsonMember
+
+type jsonMember struct {
+	name string
+	val  interface{}
+}
+
+func (o jsonObject) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, m := range o {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		k, _ := json.Marshal(m.name)
+		buf.Write(k)
+		buf.WriteByte(':')
+		v, err := json.Marshal(m.val)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(v)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+var (
+	posType = reflect.TypeOf(token.Position{})
+	tokType = reflect.TypeOf(token.Token(0))
+)
+
+// Returns the value to encode for the field or node value v.
+func jsonValue(v reflect.Value) interface{} {
+	switch v.Kind() {
+	case reflect.Interface, reflect.Ptr:
+		if v.IsNil() {
+			return nil
+		}
+		return jsonValue(v.Elem())
+	case reflect.Slice:
+		l := make([]interface{}, v.Len())
+		for i := range l {
+			l[i] = jsonValue(v.Index(i))
+		}
+		return l
+	}
+	switch v.Type() {
+	case posType:
+		p := v.Interface().(token.Position)
+		return jsonObject{{"Line", p.Line}, {"Column", p.Column}}
+	case tokType:
+		return v.Interface().(token.Token).String()
+	}
+	if v.Kind() != reflect.Struct {
+		return v.Interface()
+	}
+	o := jsonObject{{"Type", v.Type().Name()}}
+	for i := 0; i < v.NumField(); i++ {
+		o = append(o, jsonMember{v.Type().Field(i).Name, jsonValue(v.Field(i))})
+	}
+	return o
+}
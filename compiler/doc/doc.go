@@ -0,0 +1,164 @@
+// Package doc extracts the documentation of an agora module from its source
+// code: the top-level functions with their parameters, the top-level
+// definitions and the keys of the object returned by the module, each with
+// the doc comment that precedes it. The documentation can be rendered as
+// plain text, Markdown or HTML.
+//
+// A doc comment is the group of consecutive comment lines that ends on the
+// line just before the func statement, the definition or the key:
+//
+//	// Add returns the sum of x and y.
+//	func Add(x, y) {
+//		return x + y
+//	}
+package doc
+
+import (
+	"path/filepath"
+	"strings"
+
+	"github.com/saward/agora/compiler/ast"
+	"github.com/saward/agora/compiler/token"
+)
+
+// A Func is the documentation of a function, declared by a func statement or
+// defined as a func literal.
+type Func struct {
+	Name   string
+	Params []string
+	Doc    string
+	Pos    token.Position
+}
+
+// Signature returns the name and parameters of the function, e.g. `Add(x, y)`.
+func (f *Func) Signature() string {
+	return f.Name + "(" + strings.Join(f.Params, ", ") + ")"
+}
+
+// A Value is the documentation of a top-level definition that is not a
+// function.
+type Value struct {
+	Name string
+	Doc  string
+	Pos  token.Position
+}
+
+// A Key is the documentation of a key of the object returned by the module,
+// that is of its exported API. If the key has no doc comment, its Doc is the
+// one of the function or value that it exports.
+type Key struct {
+	Name string
+	Doc  string
+	Func *Func // The exported function, nil if the value is not a function
+}
+
+// A Module is the documentation of an agora module.
+type Module struct {
+	Name   string   // The name of the module, its file name without extension
+	Keys   []*Key   // The keys of the returned object, in source order
+	Funcs  []*Func  // The top-level functions, in source order
+	Values []*Value // The other top-level definitions, in source order
+}
+
+// Parse parses the agora source code of the file and returns the
+// documentation of the module.
+func Parse(filename string, src []byte) (*Module, error) {
+	f, err := ast.Parse(filename, src)
+	if err != nil {
+		return nil, err
+	}
+	return New(f), nil
+}
+
+// New returns the documentation of the module of the syntax tree.
+func New(f *ast.File) *Module {
+	nm := filepath.Base(f.Name)
+	m := &Module{Name: strings.TrimSuffix(nm, filepath.Ext(nm))}
+	funcs := make(map[string]*Func)
+	values := make(map[string]*Value)
+	objects := make(map[string]*ast.ObjectLit)
+	var ret *ast.ReturnStmt
+	for _, st := range f.Stmts {
+		switch st := st.(type) {
+		case *ast.FuncDecl:
+			fn := newFunc(st.Name.Name, st.Params, st.Doc, st.Func)
+			m.Funcs = append(m.Funcs, fn)
+			funcs[fn.Name] = fn
+		case *ast.AssignStmt:
+			id, ok := st.Lhs.(*ast.Ident)
+			if !ok || st.Tok != token.DEFINE {
+				continue
+			}
+			switch rhs := st.Rhs.(type) {
+			case *ast.FuncLit:
+				fn := newFunc(id.Name, rhs.Params, st.Doc, id.NamePos)
+				m.Funcs = append(m.Funcs, fn)
+				funcs[fn.Name] = fn
+				continue
+			case *ast.ObjectLit:
+				objects[id.Name] = rhs
+			}
+			v := &Value{id.Name, st.Doc, id.NamePos}
+			m.Values = append(m.Values, v)
+			values[v.Name] = v
+		case *ast.ReturnStmt:
+			ret = st
+		}
+	}
+	if ret == nil {
+		return m
+	}
+
+	// The exported keys are those of the returned object literal, or of the
+	// object literal assigned to the returned variable and of the keys that
+	// are set on this variable at the top level.
+	addKey := func(nm, doc string, val ast.Expr) {
+		k := &Key{Name: nm, Doc: doc}
+		switch val := val.(type) {
+		case *ast.FuncLit:
+			k.Func = newFunc(nm, val.Params, doc, val.Func)
+		case *ast.Ident:
+			if fn, ok := funcs[val.Name]; ok {
+				k.Func = fn
+				if k.Doc == "" {
+					k.Doc = fn.Doc
+				}
+			} else if v, ok := values[val.Name]; ok && k.Doc == "" {
+				k.Doc = v.Doc
+			}
+		}
+		m.Keys = append(m.Keys, k)
+	}
+	switch res := ret.Result.(type) {
+	case *ast.ObjectLit:
+		for _, fld := range res.Fields {
+			addKey(fld.Key, fld.Doc, fld.Value)
+		}
+	case *ast.Ident:
+		if ob, ok := objects[res.Name]; ok {
+			for _, fld := range ob.Fields {
+				addKey(fld.Key, fld.Doc, fld.Value)
+			}
+		}
+		for _, st := range f.Stmts {
+			as, ok := st.(*ast.AssignStmt)
+			if !ok || as.Tok != token.ASSIGN {
+				continue
+			}
+			if sel, ok := as.Lhs.(*ast.SelectorExpr); ok {
+				if id, ok := sel.X.(*ast.Ident); ok && id.Name == res.Name {
+					addKey(sel.Sel.Name, as.Doc, as.Rhs)
+				}
+			}
+		}
+	}
+	return m
+}
+
+func newFunc(nm string, params []*ast.Ident, doc string, pos token.Position) *Func {
+	fn := &Func{Name: nm, Doc: doc, Pos: pos}
+	for _, p := range params {
+		fn.Params = append(fn.Params, p.Name)
+	}
+	return fn
+}
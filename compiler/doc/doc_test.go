@@ -0,0 +1,128 @@
+package doc
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+var testSrc = `// Not a doc comment, followed by a blank line.
+
+// Max is the maximum value.
+Max := 10
+
+// Add returns the sum
+// of x and y.
+func Add(x, y) {
+	return x + y
+}
+
+// sub is a func literal.
+sub := func(x, y) {
+	return x - y
+}
+
+ob := {
+	// Add adds.
+	Add: Add,
+	Max: Max,
+	Mul: func(a, b) {
+		return a * b
+	},
+}
+// Sub subtracts.
+ob.Sub = sub
+return ob
+`
+
+func TestNew(t *testing.T) {
+	m, err := Parse("lib/math.agora", []byte(testSrc))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.Name != "math" {
+		t.Errorf("expected module name math, got %s", m.Name)
+	}
+
+	var got []string
+	for _, fn := range m.Funcs {
+		got = append(got, fn.Signature()+"|"+fn.Doc)
+	}
+	exp := "Add(x, y)|Add returns the sum\nof x and y.,sub(x, y)|sub is a func literal."
+	if s := strings.Join(got, ","); s != exp {
+		t.Errorf("expected funcs %q, got %q", exp, s)
+	}
+	if m.Funcs[0].Pos.Line != 8 {
+		t.Errorf("expected Add at line 8, got %d", m.Funcs[0].Pos.Line)
+	}
+
+	got = nil
+	for _, v := range m.Values {
+		got = append(got, v.Name+"|"+v.Doc)
+	}
+	exp = "Max|Max is the maximum value.,ob|"
+	if s := strings.Join(got, ","); s != exp {
+		t.Errorf("expected values %q, got %q", exp, s)
+	}
+
+	got = nil
+	for _, k := range m.Keys {
+		s := k.Name
+		if k.Func != nil {
+			s = k.Func.Signature()
+		}
+		got = append(got, s+"|"+k.Doc)
+	}
+	exp = "Add(x, y)|Add adds.,Max|Max is the maximum value.,Mul(a, b)|,sub(x, y)|Sub subtracts."
+	if s := strings.Join(got, ","); s != exp {
+		t.Errorf("expected keys %q, got %q", exp, s)
+	}
+}
+
+func TestNewReturnLiteral(t *testing.T) {
+	m, err := Parse("x", []byte("// F does nothing.\nfunc F() {\n}\nreturn {\n\t// G is F.\n\tG: F,\n\tH: F,\n}\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(m.Keys) != 2 || m.Keys[0].Doc != "G is F." || m.Keys[1].Doc != "F does nothing." || m.Keys[1].Func == nil {
+		t.Errorf("unexpected keys %+v", m.Keys)
+	}
+	if _, err := Parse("x", []byte("return )")); err == nil {
+		t.Errorf("expected a parse error")
+	}
+}
+
+func TestWrite(t *testing.T) {
+	m, err := Parse("math.agora", []byte(testSrc))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cases := []struct {
+		fn  func(*bytes.Buffer) error
+		exp []string
+	}{
+		0: {
+			func(b *bytes.Buffer) error { return m.WriteText(b) },
+			[]string{"module math\n", "\nEXPORTS\n\nAdd(x, y)\n    Add adds.\n", "\nFUNCTIONS\n", "\nfunc Add(x, y)\n    Add returns the sum\n    of x and y.\n", "\nVALUES\n\nMax\n"},
+		},
+		1: {
+			func(b *bytes.Buffer) error { return m.WriteMarkdown(b) },
+			[]string{"# math\n", "\n## Exports\n\n### Add(x, y)\n\nAdd adds.\n", "\n### func sub(x, y)\n\nsub is a func literal.\n", "\n## Values\n"},
+		},
+		2: {
+			func(b *bytes.Buffer) error { return m.WriteHTML(b) },
+			[]string{"<title>math</title>", `<h3 id="Mul">Mul(a, b)</h3>`, `<h3 id="func-Add">func Add(x, y)</h3>`, "<pre>Max is the maximum value.</pre>"},
+		},
+	}
+	for i, c := range cases {
+		buf := bytes.NewBuffer(nil)
+		if err := c.fn(buf); err != nil {
+			t.Fatalf("[%d] - %s", i, err)
+		}
+		for _, s := range c.exp {
+			if !strings.Contains(buf.String(), s) {
+				t.Errorf("[%d] - expected output to contain %q, got:\n%s", i, s, buf)
+			}
+		}
+	}
+}
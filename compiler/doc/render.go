@@ -0,0 +1,128 @@
+package doc
+
+import (
+	"bufio"
+	"fmt"
+	"html/template"
+	"io"
+	"strings"
+)
+
+// Write the doc text, indented with the prefix.
+func writeIndented(w io.Writer, doc, prefix string) {
+	if doc == "" {
+		return
+	}
+	for _, l := range strings.Split(doc, "\n") {
+		if strings.TrimSpace(l) == "" {
+			fmt.Fprintln(w)
+			continue
+		}
+		fmt.Fprintf(w, "%s%s\n", prefix, l)
+	}
+}
+
+// WriteText writes the documentation of the module as plain text.
+func (m *Module) WriteText(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "module %s\n", m.Name)
+	if len(m.Keys) > 0 {
+		fmt.Fprintf(bw, "\nEXPORTS\n")
+		for _, k := range m.Keys {
+			if k.Func != nil {
+				fmt.Fprintf(bw, "\n%s(%s)\n", k.Name, strings.Join(k.Func.Params, ", "))
+			} else {
+				fmt.Fprintf(bw, "\n%s\n", k.Name)
+			}
+			writeIndented(bw, k.Doc, "    ")
+		}
+	}
+	if len(m.Funcs) > 0 {
+		fmt.Fprintf(bw, "\nFUNCTIONS\n")
+		for _, fn := range m.Funcs {
+			fmt.Fprintf(bw, "\nfunc %s\n", fn.Signature())
+			writeIndented(bw, fn.Doc, "    ")
+		}
+	}
+	if len(m.Values) > 0 {
+		fmt.Fprintf(bw, "\nVALUES\n")
+		for _, v := range m.Values {
+			fmt.Fprintf(bw, "\n%s\n", v.Name)
+			writeIndented(bw, v.Doc, "    ")
+		}
+	}
+	return bw.Flush()
+}
+
+// WriteMarkdown writes the documentation of the module as Markdown, with a
+// section per kind of declaration. The doc comments are written as is, so
+// they may use the Markdown syntax.
+func (m *Module) WriteMarkdown(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "# %s\n", m.Name)
+	if len(m.Keys) > 0 {
+		fmt.Fprintf(bw, "\n## Exports\n")
+		for _, k := range m.Keys {
+			if k.Func != nil {
+				fmt.Fprintf(bw, "\n### %s(%s)\n", k.Name, strings.Join(k.Func.Params, ", "))
+			} else {
+				fmt.Fprintf(bw, "\n### %s\n", k.Name)
+			}
+			if k.Doc != "" {
+				fmt.Fprintf(bw, "\n%s\n", k.Doc)
+			}
+		}
+	}
+	if len(m.Funcs) > 0 {
+		fmt.Fprintf(bw, "\n## Functions\n")
+		for _, fn := range m.Funcs {
+			fmt.Fprintf(bw, "\n### func %s\n", fn.Signature())
+			if fn.Doc != "" {
+				fmt.Fprintf(bw, "\n%s\n", fn.Doc)
+			}
+		}
+	}
+	if len(m.Values) > 0 {
+		fmt.Fprintf(bw, "\n## Values\n")
+		for _, v := range m.Values {
+			fmt.Fprintf(bw, "\n### %s\n", v.Name)
+			if v.Doc != "" {
+				fmt.Fprintf(bw, "\n%s\n", v.Doc)
+			}
+		}
+	}
+	return bw.Flush()
+}
+
+// WriteHTML writes the documentation of the module as a standalone HTML page.
+func (m *Module) WriteHTML(w io.Writer) error {
+	return htmlTemplate.Execute(w, m)
+}
+
+var htmlTemplate = template.Must(template.New("doc").Funcs(template.FuncMap{
+	"join": strings.Join,
+}).Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{.Name}}</title>
+<style>
+body { font-family: sans-serif; max-width: 50em; }
+h3 { font-family: monospace; }
+pre { white-space: pre-wrap; }
+</style>
+</head>
+<body>
+<h1>{{.Name}}</h1>
+{{if .Keys}}<h2>Exports</h2>
+{{range .Keys}}<h3 id="{{.Name}}">{{.Name}}{{if .Func}}({{join .Func.Params ", "}}){{end}}</h3>
+{{if .Doc}}<pre>{{.Doc}}</pre>
+{{end}}{{end}}{{end}}{{if .Funcs}}<h2>Functions</h2>
+{{range .Funcs}}<h3 id="func-{{.Name}}">func {{.Signature}}</h3>
+{{if .Doc}}<pre>{{.Doc}}</pre>
+{{end}}{{end}}{{end}}{{if .Values}}<h2>Values</h2>
+{{range .Values}}<h3 id="value-{{.Name}}">{{.Name}}</h3>
+{{if .Doc}}<pre>{{.Doc}}</pre>
+{{end}}{{end}}{{end}}</body>
+</html>
+`))
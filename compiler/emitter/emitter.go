@@ -65,6 +65,7 @@ type Emitter struct {
 	stackSz map[*bytecode.Fn]int64
 	forNest map[*bytecode.Fn][]*forData
 	fnIx    []int64
+	line    int64 // The source line of the symbol being emitted
 }
 
 // Emit takes a module identifier, the symbols generated by the parser (the headless *AST*),
@@ -77,6 +78,7 @@ func (e *Emitter) Emit(id string, syms []*parser.Symbol, scps *parser.Scope) (*b
 	e.kMap = make(map[*bytecode.Fn]map[kId]int)
 	e.stackSz = make(map[*bytecode.Fn]int64)
 	e.forNest = make(map[*bytecode.Fn][]*forData)
+	e.line = 0
 
 	// Create the bytecode representation structure
 	f := bytecode.NewFile(id)
@@ -154,6 +156,14 @@ func (e *Emitter) emitSymbol(f *bytecode.File, fn *bytecode.Fn, sym *parser.Symb
 	if e.err != nil {
 		return
 	}
+	// The instructions of the symbol are on its line, symbols added by the parser
+	// (e.g. the implicit return) are on the line of their parent.
+	if l := int64(sym.Pos().Line); l > 0 {
+		defer func(prev int64) {
+			e.line = prev
+		}(e.line)
+		e.line = l
+	}
 	switch sym.Id {
 	case "nil":
 		e.assert(asg == atFalse, errors.New("invalid assignment to nil"))
@@ -520,6 +530,7 @@ func (e *Emitter) addInstr(fn *bytecode.Fn, op bytecode.Opcode, flg bytecode.Fla
 		fn.Header.StackSz = e.stackSz[fn]
 	}
 	fn.Is = append(fn.Is, bytecode.NewInstr(op, flg, ix))
+	fn.Lines = append(fn.Lines, e.line)
 }
 
 func (e *Emitter) registerK(fn *bytecode.Fn, val interface{}, isName bool, local bool) uint64 {
@@ -0,0 +1,324 @@
+// Package format implements standard formatting of agora source code.
+//
+// The formatter works on the token stream returned by the scanner, so that the
+// comments are preserved and the tokens of the program are never changed, only
+// the whitespace between them and the semicolons that the scanner inserts
+// automatically. The source must be valid agora code.
+package format
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"github.com/saward/agora/compiler/parser"
+	"github.com/saward/agora/compiler/scanner"
+	"github.com/saward/agora/compiler/token"
+)
+
+// Source formats src in canonical agora style and returns the result or an
+// error. The filename is used in error messages. The source is parsed first,
+// and if it is not valid agora code, the parse error is returned.
+//
+// The canonical style uses tabs for indentation, one statement per line,
+// single spaces around binary operators and after commas, and at most one
+// blank line between statements. The line breaks of the source are otherwise
+// preserved, and formatting formatted source returns it unchanged.
+func Source(filename string, src []byte) (res []byte, err error) {
+	if err := check(filename, src); err != nil {
+		return nil, err
+	}
+	items, err := scan(filename, src)
+	if err != nil {
+		return nil, err
+	}
+	p := &printer{items: items}
+	p.print()
+	return p.buf.Bytes(), nil
+}
+
+// Check that the source is valid agora code.
+func check(filename string, src []byte) (err error) {
+	// The parser may panic on some invalid input
+	defer func() {
+		if e := recover(); e != nil {
+			if el, ok := e.(scanner.ErrorList); ok {
+				err = el
+				return
+			}
+			err = fmt.Errorf("%s: invalid source: %v", filename, e)
+		}
+	}()
+	p := parser.New()
+	_, _, err = p.Parse(filename, src)
+	return err
+}
+
+// An item is a token of the source, with its lines in the source.
+type item struct {
+	tok       token.Token
+	lit       string
+	line, end int // The first and last lines of the token
+	unary     bool
+}
+
+// The text of the item as it is printed.
+func (it *item) text() string {
+	switch {
+	case it.tok == token.COMMENT && strings.HasPrefix(it.lit, "//"),
+		it.tok == token.COMMENT && strings.HasPrefix(it.lit, "#!"):
+		return strings.TrimRight(it.lit, " \t")
+	case it.lit != "":
+		return it.lit
+	}
+	return it.tok.String()
+}
+
+// Scan the source into items, dropping the automatically inserted semicolons.
+func scan(filename string, src []byte) ([]*item, error) {
+	var s scanner.Scanner
+	var errs scanner.ErrorList
+	s.Init(filename, src, func(pos token.Position, msg string) {
+		errs.Add(pos, msg)
+	})
+	var items []*item
+	for tok, lit, pos := s.Scan(); tok != token.EOF; tok, lit, pos = s.Scan() {
+		if tok == token.SEMICOLON && lit == "\n" {
+			continue
+		}
+		// The position is the line at the end of the token
+		items = append(items, &item{
+			tok:  tok,
+			lit:  lit,
+			line: pos.Line - strings.Count(lit, "\n"),
+			end:  pos.Line,
+		})
+	}
+	if errs.Len() > 0 {
+		return nil, errs.Err()
+	}
+	return items, nil
+}
+
+// An opened parenthesis, bracket or brace.
+type bracket struct {
+	tok   token.Token
+	line  int  // The output line where the bracket is opened
+	block bool // The brace opens a block of statements
+}
+
+// The printer keeps the state of the formatting.
+type printer struct {
+	items []*item
+	buf   bytes.Buffer
+	line  int // The current output line
+
+	prev     *item // The previous item, excluding comments
+	last     *item // The previous item, including comments
+	stack    []bracket
+	ternary  []int // The depths of the pending ternary operators
+	header   int   // The depth of the if or for header, or -1
+	forHdr   bool  // The header is a for header
+	brk      bool  // A statement ended with an explicit semicolon
+	lastOpen bool  // The previous item opened a block
+}
+
+func (p *printer) print() {
+	p.header = -1
+	for i, it := range p.items {
+		if it.tok == token.SEMICOLON {
+			if p.forHdr && len(p.stack) == p.header {
+				p.emit(i, it)
+				continue
+			}
+			if p.prev != nil && insertsSemi(p.prev.tok) {
+				// Replaced by a newline
+				p.brk = true
+				continue
+			}
+		}
+		p.emit(i, it)
+	}
+	if p.buf.Len() > 0 {
+		p.buf.WriteByte('\n')
+	}
+}
+
+// Print the item at index i, preceded by the required whitespace.
+func (p *printer) emit(i int, it *item) {
+	var block bool
+	if it.tok == token.LBRACE {
+		block = p.opensBlock()
+	}
+	if it.tok == token.SUB || it.tok == token.ADD || it.tok == token.NOT {
+		it.unary = p.prev == nil || !endsOperand(p.prev.tok)
+	}
+	colon := it.tok == token.COLON && p.isTernaryColon()
+
+	if p.last != nil {
+		switch {
+		case it.line > p.last.end:
+			p.newline(i, it.line-p.last.end > 1)
+		case it.tok == token.COMMENT:
+			// A trailing comment stays on the line of the statement
+			p.buf.WriteByte(' ')
+		case p.brk, p.lastOpen && it.tok != token.RBRACE,
+			it.tok == token.RBRACE && p.closesBlock() && !p.lastOpen:
+			p.newline(i, false)
+		case p.space(it, colon):
+			p.buf.WriteByte(' ')
+		}
+	}
+	if it.tok != token.COMMENT {
+		p.brk = false
+		p.lastOpen = block
+	}
+	txt := it.text()
+	p.buf.WriteString(txt)
+	p.line += strings.Count(txt, "\n")
+
+	switch it.tok {
+	case token.LPAREN, token.LBRACK, token.LBRACE:
+		p.stack = append(p.stack, bracket{it.tok, p.line, block})
+	case token.RPAREN, token.RBRACK, token.RBRACE:
+		if n := len(p.stack); n > 0 {
+			p.stack = p.stack[:n-1]
+		}
+	case token.TERNARY:
+		p.ternary = append(p.ternary, len(p.stack))
+	case token.COLON:
+		if colon {
+			p.ternary = p.ternary[:len(p.ternary)-1]
+		}
+	case token.IF, token.FOR:
+		p.header = len(p.stack)
+		p.forHdr = it.tok == token.FOR
+	}
+	if it.tok != token.COMMENT {
+		p.prev = it
+	}
+	p.last = it
+}
+
+// Start a new line before the item at index i, with an additional blank line
+// if blank is true.
+func (p *printer) newline(i int, blank bool) {
+	p.buf.WriteByte('\n')
+	p.line++
+	if blank && !p.lastOpen {
+		p.buf.WriteByte('\n')
+		p.line++
+	}
+	// The brackets closed at the start of the line do not indent it
+	n := len(p.stack)
+	for j := i; j < len(p.items) && n > 0 && isClosing(p.items[j].tok) &&
+		p.items[j].line == p.items[i].line; j++ {
+		n--
+	}
+	indent := 0
+	for j := 0; j < n; j++ {
+		if j == 0 || p.stack[j].line != p.stack[j-1].line {
+			indent++
+		}
+	}
+	if !p.brk && continues(p.prev) && !isClosing(p.items[i].tok) {
+		indent++
+	}
+	p.buf.WriteString(strings.Repeat("\t", indent))
+}
+
+// Returns true if the brace about to be printed opens a block of statements,
+// as opposed to an object literal.
+func (p *printer) opensBlock() bool {
+	// In a header, the brace of an object literal follows an operator or range
+	if p.header >= 0 && p.header == len(p.stack) &&
+		(p.prev.tok == token.FOR || endsOperand(p.prev.tok)) {
+		p.header = -1
+		p.forHdr = false
+		return true
+	}
+	return p.prev != nil && (p.prev.tok == token.RPAREN || p.prev.tok == token.ELSE)
+}
+
+// Returns true if the brace about to be printed closes a block.
+func (p *printer) closesBlock() bool {
+	n := len(p.stack)
+	return n > 0 && p.stack[n-1].block
+}
+
+// Returns true if the colon about to be printed is part of a ternary operator.
+func (p *printer) isTernaryColon() bool {
+	n := len(p.ternary)
+	return n > 0 && p.ternary[n-1] == len(p.stack)
+}
+
+// Returns true if a space separates the previous item and it.
+func (p *printer) space(it *item, ternaryColon bool) bool {
+	if p.last.tok == token.COMMENT {
+		return true
+	}
+	prev := p.prev
+	switch prev.tok {
+	case token.LPAREN, token.LBRACK, token.PERIOD:
+		return false
+	case token.LBRACE:
+		return false
+	case token.SUB, token.ADD:
+		if prev.unary {
+			// Keep - -x from becoming --x
+			return strings.HasPrefix(it.text(), prev.text())
+		}
+	case token.NOT:
+		return !prev.unary
+	}
+	switch it.tok {
+	case token.COMMA, token.RPAREN, token.RBRACK, token.RBRACE, token.PERIOD,
+		token.INC, token.DEC, token.SEMICOLON:
+		return false
+	case token.COLON:
+		return ternaryColon
+	case token.LPAREN, token.LBRACK:
+		return !endsOperand(prev.tok) && prev.tok != token.FUNC
+	}
+	return true
+}
+
+// Returns true if the token ends an operand, so that a following + or - is
+// a binary operator.
+func endsOperand(tok token.Token) bool {
+	switch tok {
+	case token.IDENT, token.INT, token.FLOAT, token.STRING,
+		token.RPAREN, token.RBRACK, token.RBRACE, token.INC, token.DEC:
+		return true
+	}
+	return false
+}
+
+// Returns true if the scanner inserts a semicolon when a newline follows the
+// token.
+func insertsSemi(tok token.Token) bool {
+	switch tok {
+	case token.RETURN, token.DEBUG, token.BREAK, token.CONTINUE, token.YIELD:
+		return true
+	}
+	return endsOperand(tok)
+}
+
+// Returns true if the token is a closing parenthesis, bracket or brace.
+func isClosing(tok token.Token) bool {
+	return tok == token.RPAREN || tok == token.RBRACK || tok == token.RBRACE
+}
+
+// Returns true if an expression continues on the next line after the item,
+// in which case the next line is indented.
+func continues(it *item) bool {
+	if it == nil {
+		return false
+	}
+	switch it.tok {
+	case token.LPAREN, token.LBRACK, token.LBRACE, token.COMMA, token.SEMICOLON,
+		token.RPAREN, token.RBRACK, token.RBRACE, token.INC, token.DEC:
+		return false
+	}
+	return it.tok.IsOperator()
+}
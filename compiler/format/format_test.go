@@ -0,0 +1,125 @@
+package format
+
+import (
+	"bytes"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/saward/agora/bytecode"
+	"github.com/saward/agora/compiler"
+)
+
+var (
+	cases = []struct {
+		src string
+		exp string
+		err bool
+	}{
+		0: {
+			src: `a:=1;b:=a*2+ -a`,
+			exp: "a := 1\nb := a * 2 + -a\n",
+		},
+		1: {
+			src: "func add(a,b){return a+b;}\n\n\n\nreturn add(1,2)\n",
+			exp: "func add(a, b) {\n\treturn a + b\n}\n\nreturn add(1, 2)\n",
+		},
+		2: {
+			src: "x := 1 // one\n/* block */\nif x>0{\n    return x\n  } else {\nreturn -x\n}",
+			exp: "x := 1 // one\n/* block */\nif x > 0 {\n\treturn x\n} else {\n\treturn -x\n}\n",
+		},
+		3: {
+			src: "ob := {a:1, b:{c:true},\n  d: func(){ return 2; },\n}",
+			exp: "ob := {a: 1, b: {c: true},\n\td: func() {\n\t\treturn 2\n\t},\n}\n",
+		},
+		4: {
+			src: "for i:=0;i<3;i++{\nx := i>1?{a: 1}:- -i\n}",
+			exp: "for i := 0; i < 3; i++ {\n\tx := i > 1 ? {a: 1} : - -i\n}\n",
+		},
+		5: {
+			src: "for k:=range {a: 1} {\nreturn k +\n1\n}",
+			exp: "for k := range {a: 1} {\n\treturn k +\n\t\t1\n}\n",
+		},
+		6: {
+			src: "#!/usr/bin/env agora run \nreturn 1",
+			exp: "#!/usr/bin/env agora run\nreturn 1\n",
+		},
+		7: {
+			src: `return 1 +`,
+			err: true,
+		},
+		8: {
+			src: `return a`,
+			err: true,
+		},
+	}
+)
+
+func TestSource(t *testing.T) {
+	for i, c := range cases {
+		res, err := Source("test", []byte(c.src))
+		if (err != nil) != c.err {
+			t.Errorf("[%d] - expected error %v, got %v", i, c.err, err)
+			continue
+		}
+		if c.err {
+			continue
+		}
+		if string(res) != c.exp {
+			t.Errorf("[%d] - expected\n%q\ngot\n%q", i, c.exp, res)
+		}
+		res2, err := Source("test", res)
+		if err != nil {
+			t.Errorf("[%d] - expected no error when formatting again, got %s", i, err)
+		} else if !bytes.Equal(res, res2) {
+			t.Errorf("[%d] - expected formatting to be idempotent, got\n%q", i, res2)
+		}
+	}
+}
+
+// Formatting the test scripts must not change their compiled bytecode.
+func TestTestdata(t *testing.T) {
+	files, err := filepath.Glob(filepath.Join("..", "..", "testdata", "src", "*.agora"))
+	if err != nil {
+		panic(err)
+	}
+	for _, fn := range files {
+		b, err := ioutil.ReadFile(fn)
+		if err != nil {
+			panic(err)
+		}
+		exp, err := compile(fn, b)
+		if err != nil {
+			// Not a valid standalone module
+			continue
+		}
+		res, err := Source(fn, b)
+		if err != nil {
+			t.Errorf("%s: expected no error, got %s", fn, err)
+			continue
+		}
+		got, err := compile(fn, res)
+		if err != nil {
+			t.Errorf("%s: expected formatted source to compile, got %s", fn, err)
+			continue
+		}
+		if !bytes.Equal(exp, got) {
+			t.Errorf("%s: formatting changed the bytecode", fn)
+		}
+		if res2, _ := Source(fn, res); !bytes.Equal(res, res2) {
+			t.Errorf("%s: expected formatting to be idempotent", fn)
+		}
+	}
+}
+
+func compile(fn string, src []byte) ([]byte, error) {
+	f, err := new(compiler.Compiler).Compile(fn, bytes.NewReader(src))
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	if err := bytecode.NewEncoder(&buf).Encode(f); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -303,6 +303,9 @@ func (p *Parser) defineGrammar() {
 				p.advance(":")
 				v := p.expression(0)
 				v.Key = n.Val
+				if n.doc != "" {
+					v.doc = n.doc
+				}
 				a = append(a, v)
 				if p.tkn.Id != "," {
 					break
@@ -331,6 +334,7 @@ func makeFuncParser(p *Parser, prefix bool) func(*Symbol) *Symbol {
 		if !prefix && p.tkn.Ar == ArName { // Only for statement notation
 			p.scp.define(p.tkn)
 			sym.Name = p.tkn.Val.(string)
+			sym.npos = p.tkn.pos
 			p.advance(_SYM_ANY)
 		}
 		p.newScope()
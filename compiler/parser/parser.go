@@ -6,6 +6,7 @@ package parser
 
 import (
 	"fmt"
+	"strings"
 
 	"github.com/saward/agora/compiler/scanner"
 	"github.com/saward/agora/compiler/token"
@@ -38,9 +39,13 @@ type Parser struct {
 	scp     *Scope             // the top-level (universe) scope
 	err     *scanner.ErrorList // the error handler
 	isRange bool
+	doc     []string // lines of the current comment group
+	docEnd  int      // line where the current comment group ends
+	lastEnd int      // line where the last token ends
 
 	// Exported fields
-	Debug bool
+	Debug       bool
+	Predeclared []string // Names defined in a scope enclosing the top-level scope
 }
 
 // New returns a new parser, initialized with its scanner.Scanner.
@@ -57,9 +62,19 @@ func (p *Parser) Parse(filename string, src []byte) ([]*Symbol, *Scope, error) {
 	p.tbl = make(map[string]*Symbol)
 	p.err = new(scanner.ErrorList)
 	p.isRange = false
-	u := p.newScope()
+	p.doc, p.docEnd, p.lastEnd = nil, 0, 0
+	p.scp = nil
 	p.defineRequiredSymbols()
 	p.defineGrammar()
+	if len(p.Predeclared) > 0 {
+		// The predeclared names are in their own scope, so that they can
+		// be redefined in the top-level scope.
+		p.newScope()
+		for _, nm := range p.Predeclared {
+			p.scp.define(&Symbol{p: p, Id: _SYM_NAME, Val: nm, Ar: ArName})
+		}
+	}
+	u := p.newScope()
 
 	// Initialize the scanner
 	p.scn.Init(filename, src, p.err.Add)
@@ -143,7 +158,20 @@ func (p *Parser) advance(id string) *Symbol {
 	)
 scan:
 	for tok, lit, pos = p.scn.Scan(); tok == token.ILLEGAL || tok == token.COMMENT; tok, lit, pos = p.scn.Scan() {
-		// Skip Illegal and Comment tokens
+		// Skip Illegal and Comment tokens, collecting the comments that may
+		// document the next token
+		if tok == token.COMMENT {
+			p.addComment(lit, pos)
+		}
+	}
+	var doc string
+	if p.doc != nil && p.docEnd == pos.Line-1 {
+		doc = strings.Join(p.doc, "\n")
+	}
+	p.doc = nil
+	if tok != token.SEMICOLON || lit != "\n" {
+		// The automatic semicolons are not on the line of the previous token
+		p.lastEnd = pos.Line
 	}
 	if p.Debug {
 		fmt.Println("SCAN: ", tok, lit, pos)
@@ -182,9 +210,71 @@ scan:
 	p.tkn.Val = lit
 	p.tkn.tok = tok
 	p.tkn.pos = pos
+	p.tkn.doc = doc
 	return p.tkn
 }
 
+// Add the comment to the current comment group, or start a new group if the
+// comment does not immediately follow it. A comment on the same line as the
+// preceding token is not part of a doc comment.
+func (p *Parser) addComment(lit string, pos token.Position) {
+	start := pos.Line - strings.Count(lit, "\n")
+	if start == p.lastEnd || !(strings.HasPrefix(lit, "//") || strings.HasPrefix(lit, "/*")) {
+		// Trailing comment, or hashbang line
+		p.doc = nil
+		return
+	}
+	if p.doc == nil || start > p.docEnd+1 {
+		p.doc = []string{}
+	}
+	p.doc = append(p.doc, commentText(lit)...)
+	p.docEnd = pos.Line
+}
+
+// Returns the lines of text of the comment, without the comment markers.
+func commentText(lit string) []string {
+	if strings.HasPrefix(lit, "//") {
+		lit = strings.TrimPrefix(lit[2:], " ")
+		return []string{strings.TrimRight(lit, " \t")}
+	}
+	lit = strings.TrimSuffix(lit[2:], "*/")
+	lines := strings.Split(lit, "\n")
+	for i, l := range lines {
+		lines[i] = strings.TrimRight(l, " \t")
+	}
+	// Text on the line of the /* marker loses its leading space, as with //,
+	// and the other lines lose the indentation common to all of them
+	lines[0] = strings.TrimPrefix(lines[0], " ")
+	var indent string
+	first := true
+	for _, l := range lines[1:] {
+		if l == "" {
+			continue
+		}
+		ws := l[:len(l)-len(strings.TrimLeft(l, " \t"))]
+		if first {
+			indent, first = ws, false
+			continue
+		}
+		n := 0
+		for n < len(indent) && n < len(ws) && indent[n] == ws[n] {
+			n++
+		}
+		indent = indent[:n]
+	}
+	for i := 1; i < len(lines); i++ {
+		lines[i] = strings.TrimPrefix(lines[i], indent)
+	}
+	// Remove the leading and trailing blank lines
+	for len(lines) > 0 && lines[0] == "" {
+		lines = lines[1:]
+	}
+	for len(lines) > 0 && lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
 func (p *Parser) expression(rbp int) *Symbol {
 	t := p.tkn
 	p.advance(_SYM_ANY)
@@ -337,11 +427,15 @@ func (p *Parser) statements() []*Symbol {
 			break
 		}
 		tok := p.tkn
+		doc := tok.doc
 		s := p.statement()
 		switch v := s.(type) {
 		case []*Symbol:
 			a = append(a, v...)
 		case *Symbol:
+			if doc != "" {
+				v.doc = doc
+			}
 			a = append(a, v)
 		default:
 			p.error(tok, "unexpected statement type")
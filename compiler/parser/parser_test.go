@@ -749,3 +749,75 @@ func TestParse(t *testing.T) {
 		}
 	}
 }
+
+func TestParsePredeclared(t *testing.T) {
+	p := New()
+	// Without predeclared names, using an unknown name is an error
+	if _, _, err := p.Parse("test", []byte(`return a + 1`)); err == nil {
+		t.Errorf("expected error without predeclared name, got none")
+	}
+	p.Predeclared = []string{"a"}
+	if _, _, err := p.Parse("test", []byte(`return a + 1`)); err != nil {
+		t.Errorf("expected no error using predeclared name, got %s", err)
+	}
+	// Predeclared names can be redefined in the top-level scope
+	if _, _, err := p.Parse("test", []byte(`a := 2`)); err != nil {
+		t.Errorf("expected no error redefining predeclared name, got %s", err)
+	}
+}
+
+func TestParseDoc(t *testing.T) {
+	src := `#!/usr/bin/env agora run
+// Max is the maximum.
+// It is a constant.
+Max := 10 // not a doc comment
+
+// Not a doc comment either, followed by a blank line.
+
+/*
+  Add returns the sum
+  of x and y.
+*/
+func Add(x, y) {
+	// Not at the top level, but a doc comment
+	z := x + y
+	return z
+}
+/* F is the sum. */
+f := Add(1, Max) // trailing
+return {
+	// Add is exported.
+	Add: Add,
+	Max: Max,
+}
+`
+	syms, _, err := New().Parse("test", []byte(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	exp := []string{
+		"Max is the maximum.\nIt is a constant.",
+		"Add returns the sum\nof x and y.",
+		"F is the sum.",
+		"",
+	}
+	if len(syms) != len(exp) {
+		t.Fatalf("expected %d statements, got %d", len(exp), len(syms))
+	}
+	for i, s := range syms {
+		if s.Doc() != exp[i] {
+			t.Errorf("[%d] - expected doc %q, got %q", i, exp[i], s.Doc())
+		}
+	}
+	body := syms[1].Second.([]*Symbol)
+	if d := body[0].Doc(); d != "Not at the top level, but a doc comment" {
+		t.Errorf("expected the doc of the nested statement, got %q", d)
+	}
+	keys := syms[3].First.(*Symbol).First.([]*Symbol)
+	if d := keys[0].Doc(); d != "Add is exported." {
+		t.Errorf("expected the doc of the Add key, got %q", d)
+	}
+	if d := keys[1].Doc(); d != "" {
+		t.Errorf("expected no doc for the Max key, got %q", d)
+	}
+}
@@ -1,5 +1,7 @@
 package parser
 
+import "sort"
+
 // A Scope holds the valid identifiers. In agora, the only scopes are the functions,
 // so each function starts a new scope, and the top-level code is in an implicit
 // top-level function (and thus scope).
@@ -9,6 +11,30 @@ type Scope struct {
 	p      *Parser
 }
 
+// Parent returns the enclosing scope, or nil if s is the outermost scope.
+func (s *Scope) Parent() *Scope {
+	return s.parent
+}
+
+// Lookup returns the Symbol that defines the name in this scope, ignoring the
+// parent scopes, or nil if the name is not defined.
+func (s *Scope) Lookup(nm string) *Symbol {
+	return s.def[nm]
+}
+
+// Names returns the names defined in this scope, sorted in lexical order. The
+// reserved identifiers used in the scope are not included.
+func (s *Scope) Names() []string {
+	nms := make([]string, 0, len(s.def))
+	for k, v := range s.def {
+		if !v.res {
+			nms = append(nms, k)
+		}
+	}
+	sort.Strings(nms)
+	return nms
+}
+
 func (s *Scope) define(n *Symbol) *Symbol {
 	t, ok := s.def[n.Val.(string)]
 	if ok {
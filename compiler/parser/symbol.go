@@ -74,7 +74,9 @@ type Symbol struct {
 	asg    bool
 	tok    token.Token
 	pos    token.Position
-	First  interface{} // May all be []*Symbol or *Symbol
+	npos   token.Position // Position of the name of a func statement
+	doc    string         // Doc comment of the token or statement
+	First  interface{}    // May all be []*Symbol or *Symbol
 	Second interface{}
 	Third  interface{}
 
@@ -96,6 +98,8 @@ func (s Symbol) clone() *Symbol {
 		s.asg,
 		s.tok,
 		s.pos,
+		s.npos,
+		s.doc,
 		nil,
 		nil,
 		nil,
@@ -126,6 +130,31 @@ func (s *Symbol) nud() *Symbol {
 	return s.nudfn(s)
 }
 
+// Pos returns the position of the Symbol's token in the source.
+func (s *Symbol) Pos() token.Position {
+	return s.pos
+}
+
+// Doc returns the text of the doc comment of a statement or of the value of
+// an object literal's key, without the comment markers. A doc comment is the
+// group of consecutive comment lines that ends on the line just before the
+// statement or the key. It returns an empty string if there is no doc comment.
+func (s *Symbol) Doc() string {
+	return s.doc
+}
+
+// NamePos returns the position of the name of a func statement, or the zero
+// position for other symbols.
+func (s *Symbol) NamePos() token.Position {
+	return s.npos
+}
+
+// Tok returns the token that produced the Symbol, which distinguishes the kinds
+// of literals.
+func (s *Symbol) Tok() token.Token {
+	return s.tok
+}
+
 // String returns a literal string representation of the Symbol.
 func (s *Symbol) String() string {
 	return s.indentString(0)
@@ -0,0 +1,140 @@
+package vet
+
+import (
+	"github.com/saward/agora/compiler/parser"
+)
+
+// Undeclared reports the uses of names that are not declared, which fail at
+// runtime with "variable not found". A variable declared in a block that may
+// not have executed is not reported: all the locals of a function are set to
+// nil on entry, so it is merely nil.
+var Undeclared = &Check{
+	Name: "undeclared",
+	Doc:  "report the uses of names that are not declared",
+	Run: func(p *Pass) {
+		for _, r := range p.Info.Refs {
+			switch {
+			case r.Assign:
+			case r.Obj == nil:
+				p.Reportf(r.Sym, "undeclared name: %s", r.Sym.Val)
+			}
+		}
+	},
+}
+
+// Assign reports the assignments to names that are not declared, which fail
+// at runtime with "unknown variable".
+var Assign = &Check{
+	Name: "assign",
+	Doc:  "report the assignments to names that are not declared",
+	Run: func(p *Pass) {
+		for _, r := range p.Info.Refs {
+			switch {
+			case !r.Assign:
+			case r.Obj == nil:
+				p.Reportf(r.Sym, "assignment to undeclared name: %s", r.Sym.Val)
+			}
+		}
+	},
+}
+
+// Unused reports the variables that are declared but never read.
+var Unused = &Check{
+	Name: "unused",
+	Doc:  "report the variables that are declared but never used",
+	Run: func(p *Pass) {
+		for _, o := range p.Info.Objects {
+			if o.Kind == ObjVar && !o.Used() {
+				p.Reportf(o.Decl, "%s declared but not used", o.Name)
+			}
+		}
+	},
+}
+
+// The number of arguments accepted by the builtin functions, -1 if there is
+// no maximum.
+var builtinArgs = map[string][2]int{
+	"import":  {1, 1},
+	"panic":   {1, 1},
+	"recover": {1, -1},
+	"len":     {1, 1},
+	"keys":    {1, 1},
+	"number":  {1, 1},
+	"string":  {1, 1},
+	"bool":    {1, 1},
+	"type":    {1, 1},
+	"status":  {1, 1},
+	"reset":   {1, 1},
+}
+
+// Builtin reports the calls to builtin functions with the wrong number of
+// arguments. Missing arguments fail at runtime, extra arguments are ignored.
+var Builtin = &Check{
+	Name: "builtin",
+	Doc:  "report the calls to builtin functions with the wrong number of arguments",
+	Run: func(p *Pass) {
+		inspect(p.Syms, func(sym *parser.Symbol) {
+			if sym.Id != "(" || sym.Ar != parser.ArBinary {
+				return
+			}
+			fn, ok := sym.First.(*parser.Symbol)
+			if !ok || fn.Ar != parser.ArName {
+				return
+			}
+			lim, ok := builtinArgs[fn.Id]
+			if !ok {
+				return
+			}
+			args, _ := sym.Second.([]*parser.Symbol)
+			switch n := len(args); {
+			case n < lim[0]:
+				p.Reportf(fn, "not enough arguments in call to %s (expected %d, got %d)", fn.Id, lim[0], n)
+			case lim[1] >= 0 && n > lim[1]:
+				p.Reportf(fn, "too many arguments in call to %s (expected %d, got %d)", fn.Id, lim[1], n)
+			}
+		})
+	},
+}
+
+// Shadow reports the declarations that shadow a declaration of an enclosing
+// function.
+var Shadow = &Check{
+	Name: "shadow",
+	Doc:  "report the declarations that shadow a variable of an enclosing function",
+	Run: func(p *Pass) {
+		for _, o := range p.Info.Objects {
+			if o.Shadows != nil {
+				p.Reportf(o.Decl, "declaration of %s shadows declaration at %s", o.Name, linePos(o.Shadows.Decl))
+			}
+		}
+	},
+}
+
+// Returns the line and column of the symbol, without the file name.
+func linePos(sym *parser.Symbol) string {
+	pos := sym.Pos()
+	pos.Filename = ""
+	return pos.String()
+}
+
+// Call fn for each symbol of the tree, in depth-first order.
+func inspect(v interface{}, fn func(*parser.Symbol)) {
+	switch v := v.(type) {
+	case *parser.Symbol:
+		if v == nil {
+			return
+		}
+		fn(v)
+		inspect(v.First, fn)
+		inspect(v.Second, fn)
+		inspect(v.Third, fn)
+	case []*parser.Symbol:
+		for _, s := range v {
+			inspect(s, fn)
+		}
+	case []interface{}:
+		for _, c := range v {
+			inspect(c, fn)
+		}
+	}
+}
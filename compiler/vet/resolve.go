@@ -0,0 +1,233 @@
+package vet
+
+import (
+	"github.com/saward/agora/compiler/parser"
+)
+
+// The Symbol identifiers used by the resolution.
+const (
+	symName = "(name)"
+	symFunc = "func"
+)
+
+// An ObjKind is the kind of a declared Object.
+type ObjKind int
+
+const (
+	ObjVar   ObjKind = iota // A variable declared with :=
+	ObjParam                // A function parameter
+	ObjFunc                 // A function declared with the func statement
+)
+
+// An Object is a name declared in a function.
+type Object struct {
+	Name    string
+	Kind    ObjKind
+	Decl    *parser.Symbol // The declaring Symbol
+	Func    *parser.Symbol // The declaring function, nil for the top-level function
+	Refs    []*Ref         // The references to the object
+	Shadows *Object        // The object of an enclosing function with the same name
+}
+
+// Used returns true if the value of the object is read by at least one
+// reference.
+func (o *Object) Used() bool {
+	for _, r := range o.Refs {
+		if !r.Assign {
+			return true
+		}
+	}
+	return false
+}
+
+// A Ref is a reference to a name, resolved to its declaring Object.
+type Ref struct {
+	Sym    *parser.Symbol
+	Obj    *Object // The declaring object, or nil if the name is not declared
+	Assign bool    // The reference is the target of an assignment
+}
+
+// Info holds the resolution of the names of a module.
+type Info struct {
+	Objects []*Object // In declaration order
+	Refs    []*Ref    // In source order
+}
+
+// Resolve resolves the names used by the symbols, as returned by the parser,
+// to their declarations.
+//
+// In agora, the only scopes are the functions: a declaration made in a block
+// (the body of an if or a for) is visible after the block, and its variable
+// is nil if the block did not execute, as all the locals of a function are
+// set to nil when it is called.
+func Resolve(syms []*parser.Symbol) *Info {
+	r := &resolver{info: new(Info)}
+	r.openFunc(nil)
+	r.stmts(syms)
+	r.closeFunc()
+	return r.info
+}
+
+// A function scope during the resolution.
+type funcScope struct {
+	parent *funcScope
+	sym    *parser.Symbol
+	objs   map[string]*Object
+}
+
+type resolver struct {
+	info *Info
+	fn   *funcScope
+}
+
+func (r *resolver) openFunc(sym *parser.Symbol) {
+	r.fn = &funcScope{
+		parent: r.fn,
+		sym:    sym,
+		objs:   make(map[string]*Object),
+	}
+}
+
+func (r *resolver) closeFunc() {
+	r.fn = r.fn.parent
+}
+
+// Declare the name of the symbol in the current function.
+func (r *resolver) declare(sym *parser.Symbol, nm string, kind ObjKind) {
+	o := &Object{
+		Name: nm,
+		Kind: kind,
+		Decl: sym,
+		Func: r.fn.sym,
+	}
+	for fn := r.fn.parent; fn != nil; fn = fn.parent {
+		if so, ok := fn.objs[nm]; ok {
+			o.Shadows = so
+			break
+		}
+	}
+	r.fn.objs[nm] = o
+	r.info.Objects = append(r.info.Objects, o)
+}
+
+// Resolve a reference to the name of the symbol.
+func (r *resolver) ref(sym *parser.Symbol, asg bool) {
+	nm, _ := sym.Val.(string)
+	ref := &Ref{Sym: sym, Assign: asg}
+	for fn := r.fn; fn != nil; fn = fn.parent {
+		if o, ok := fn.objs[nm]; ok {
+			ref.Obj = o
+			o.Refs = append(o.Refs, ref)
+			break
+		}
+	}
+	r.info.Refs = append(r.info.Refs, ref)
+}
+
+func (r *resolver) stmts(syms []*parser.Symbol) {
+	for _, sym := range syms {
+		r.sym(sym)
+	}
+}
+
+func (r *resolver) any(v interface{}) {
+	switch v := v.(type) {
+	case *parser.Symbol:
+		r.sym(v)
+	case []*parser.Symbol:
+		r.stmts(v)
+	case []interface{}:
+		for _, c := range v {
+			r.any(c)
+		}
+	}
+}
+
+// Returns true if the symbol is a reference to a variable name.
+func isName(sym *parser.Symbol) bool {
+	return sym.Id == symName && sym.Ar == parser.ArName
+}
+
+func (r *resolver) sym(sym *parser.Symbol) {
+	if sym == nil {
+		return
+	}
+	switch sym.Id {
+	case symName:
+		if sym.Ar == parser.ArName {
+			r.ref(sym, false)
+		}
+
+	case ":=":
+		// As in the parser, the variable is declared before its value is
+		// evaluated, so that a function literal can call itself.
+		if nm, ok := sym.First.(*parser.Symbol); ok {
+			r.declare(nm, nm.Val.(string), ObjVar)
+		}
+		r.any(sym.Second)
+
+	case "=", "+=", "-=", "*=", "/=", "%=", "++", "--":
+		if nm, ok := sym.First.(*parser.Symbol); ok && isName(nm) {
+			r.ref(nm, true)
+		} else {
+			r.any(sym.First)
+		}
+		r.any(sym.Second)
+
+	case symFunc:
+		if sym.Name != "" {
+			r.declare(sym, sym.Name, ObjFunc)
+		}
+		r.openFunc(sym)
+		if params, ok := sym.First.([]*parser.Symbol); ok {
+			for _, p := range params {
+				r.declare(p, p.Val.(string), ObjParam)
+			}
+		}
+		r.any(sym.Second)
+		r.closeFunc()
+
+	case "if":
+		r.any(sym.First)
+		r.any(sym.Second)
+		r.any(sym.Third)
+
+	case "for":
+		// The init and condition parts of the 3-part for always execute, the
+		// post part executes after the body.
+		if parts, ok := sym.First.([]interface{}); ok && len(parts) == 3 {
+			r.any(parts[0])
+			r.any(parts[1])
+			r.any(sym.Second)
+			r.any(parts[2])
+			return
+		}
+		r.any(sym.First)
+		r.any(sym.Second)
+
+	case "forr":
+		r.any(sym.First)
+		r.any(sym.Second)
+
+	case ".":
+		// The field name is not a reference
+		r.any(sym.First)
+
+	case "(":
+		r.any(sym.First)
+		if sym.Ar == parser.ArTernary {
+			// Method call, the key is a field name if it was a selector
+			if key, ok := sym.Second.(*parser.Symbol); ok && !(key.Id == symName && key.Ar == parser.ArLiteral) {
+				r.any(key)
+			}
+			r.any(sym.Third)
+			return
+		}
+		r.any(sym.Second)
+
+	default:
+		r.any(sym.First)
+		r.any(sym.Second)
+		r.any(sym.Third)
+	}
+}
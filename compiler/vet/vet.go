@@ -0,0 +1,103 @@
+// Package vet implements the static analysis of agora source code. It reports
+// the suspicious constructs that the compiler accepts but that are likely to
+// fail or misbehave at runtime, such as the use of a variable that may not be
+// declared when the code executes.
+//
+// The analysis runs Checks over the Symbol tree and the Scope chain returned
+// by the parser. Each Check receives a Pass that gives access to the parsed
+// module and to the resolution of its identifiers, and reports the problems
+// it finds at the position of the offending Symbol.
+package vet
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/saward/agora/compiler/parser"
+	"github.com/saward/agora/compiler/scanner"
+)
+
+// A Check is an analysis that runs on a parsed module and reports problems.
+type Check struct {
+	Name string // The name of the check, used to select it
+	Doc  string // A one-line description of the check
+	Run  func(*Pass)
+}
+
+// A Pass holds the state of a check running on a parsed module.
+type Pass struct {
+	Check *Check
+	Syms  []*parser.Symbol // The top-level statements of the module
+	Scope *parser.Scope    // The top-level scope of the module
+	Info  *Info            // The resolution of the identifiers
+
+	errs *scanner.ErrorList
+}
+
+// Reportf reports a problem found at the position of the symbol.
+func (p *Pass) Reportf(sym *parser.Symbol, format string, args ...interface{}) {
+	p.errs.Add(sym.Pos(), fmt.Sprintf(format, args...))
+}
+
+// Checks is the list of all available checks, in the order they run by default.
+var Checks = []*Check{
+	Undeclared,
+	Assign,
+	Unused,
+	Builtin,
+	Shadow,
+}
+
+// Lookup returns the check identified by nm, or nil if there is no such check.
+func Lookup(nm string) *Check {
+	for _, c := range Checks {
+		if c.Name == nm {
+			return c
+		}
+	}
+	return nil
+}
+
+// Source parses src and runs the checks on it. If checks is empty, all Checks
+// run. If the source cannot be parsed, the parse error is returned. Otherwise,
+// the problems reported by the checks are returned as a scanner.ErrorList
+// sorted by position, or nil if there is none.
+func Source(filename string, src []byte, checks ...*Check) (err error) {
+	// The parser may panic on some invalid input
+	defer func() {
+		if e := recover(); e != nil {
+			if el, ok := e.(scanner.ErrorList); ok {
+				err = el
+				return
+			}
+			err = fmt.Errorf("%s: invalid source: %v", filename, e)
+		}
+	}()
+	syms, scp, err := parser.New().Parse(filename, src)
+	if err != nil {
+		return err
+	}
+	return Run(syms, scp, checks...)
+}
+
+// Run runs the checks on the symbols and scope returned by the parser. If checks
+// is empty, all Checks run. The problems are returned as a scanner.ErrorList
+// sorted by position, or nil if there is none.
+func Run(syms []*parser.Symbol, scp *parser.Scope, checks ...*Check) error {
+	if len(checks) == 0 {
+		checks = Checks
+	}
+	var errs scanner.ErrorList
+	info := Resolve(syms)
+	for _, c := range checks {
+		c.Run(&Pass{
+			Check: c,
+			Syms:  syms,
+			Scope: scp,
+			Info:  info,
+			errs:  &errs,
+		})
+	}
+	sort.Stable(errs)
+	return errs.Err()
+}
@@ -0,0 +1,161 @@
+package vet
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/saward/agora/compiler/parser"
+	"github.com/saward/agora/compiler/scanner"
+)
+
+var (
+	cases = []struct {
+		src    string
+		checks []*Check
+		exp    []string
+	}{
+		0: {
+			src: `a := 1
+return a`,
+		},
+		1: {
+			src: `if true {
+	a := 1
+} else {
+	a = 2
+}
+return a`,
+			checks: []*Check{Undeclared, Assign},
+		},
+		2: {
+			src: `for i := 0; i < 2; i++ {
+	j := i
+	return j
+}`,
+			checks: []*Check{Undeclared, Assign},
+		},
+		3: {
+			src: `a := 1
+b := 2
+b = 3
+c := 4
+c++
+return a`,
+			checks: []*Check{Unused},
+			exp: []string{
+				"2:1: b declared but not used",
+				"4:1: c declared but not used",
+			},
+		},
+		4: {
+			src: `f := func(x) {
+	return len(x, 2) + len()
+}
+return f(recover(f, 1, 2))`,
+			checks: []*Check{Builtin},
+			exp: []string{
+				"2:9: too many arguments in call to len (expected 1, got 2)",
+				"2:21: not enough arguments in call to len (expected 1, got 0)",
+			},
+		},
+		5: {
+			src: `a := 1
+func f(a) {
+	b := func() {
+		a := 2
+		return a
+	}
+	return b()
+}
+return f(a)`,
+			checks: []*Check{Shadow},
+			exp: []string{
+				"2:8: declaration of a shadows declaration at 1:1",
+				"4:3: declaration of a shadows declaration at 2:8",
+			},
+		},
+		6: {
+			src: `fact := func(n) {
+	return n <= 1 ? 1 : n * fact(n - 1)
+}
+ob := {a: 1}
+return ob.a + fact(ob.b)`,
+		},
+	}
+)
+
+func TestSource(t *testing.T) {
+	for i, c := range cases {
+		err := Source("", []byte(c.src), c.checks...)
+		var el scanner.ErrorList
+		if err != nil {
+			var ok bool
+			if el, ok = err.(scanner.ErrorList); !ok {
+				t.Errorf("[%d] - expected an ErrorList, got %T", i, err)
+				continue
+			}
+		}
+		if len(el) != len(c.exp) {
+			t.Errorf("[%d] - expected %d problems, got %d: %v", i, len(c.exp), len(el), el)
+			continue
+		}
+		for j, e := range el {
+			if e.Error() != c.exp[j] {
+				t.Errorf("[%d] - expected '%s', got '%s'", i, c.exp[j], e.Error())
+			}
+		}
+	}
+}
+
+func TestUndeclaredPredeclared(t *testing.T) {
+	p := parser.New()
+	p.Predeclared = []string{"x"}
+	syms, scp, err := p.Parse("", []byte("x = x + 1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = Run(syms, scp, Undeclared, Assign)
+	el, _ := err.(scanner.ErrorList)
+	exp := []string{
+		"1:1: assignment to undeclared name: x",
+		"1:5: undeclared name: x",
+	}
+	if len(el) != len(exp) {
+		t.Fatalf("expected %d problems, got %v", len(exp), err)
+	}
+	for i, e := range el {
+		if e.Error() != exp[i] {
+			t.Errorf("expected '%s', got '%s'", exp[i], e.Error())
+		}
+	}
+}
+
+func TestParseError(t *testing.T) {
+	if err := Source("", []byte("return b")); err == nil {
+		t.Errorf("expected a parse error")
+	}
+}
+
+// The checks for the problems that fail at runtime report nothing on the
+// source files of the test suite, which run successfully.
+func TestTestdata(t *testing.T) {
+	files, err := filepath.Glob("../../testdata/src/*.agora")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, fn := range files {
+		b, err := ioutil.ReadFile(fn)
+		if err != nil {
+			t.Fatal(err)
+		}
+		syms, scp, err := parser.New().Parse(fn, b)
+		if err != nil {
+			// Invalid files test the compiler errors
+			continue
+		}
+		if err := Run(syms, scp, Undeclared, Assign, Builtin); err != nil {
+			t.Errorf("%s: %s", fn, err)
+		}
+	}
+}
@@ -0,0 +1,176 @@
+package cover
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/saward/agora/compiler"
+	"github.com/saward/agora/runtime"
+)
+
+var testSrc = map[string]string{
+	"main.agora": `lib := import("./lib")
+return lib.Small(1)
+`,
+	"lib.agora": `func Small(n) {
+	if n > 10 {
+		return false
+	}
+	return true
+}
+return {Small: Small}
+`,
+}
+
+// A resolver of the test sources that keeps the module IDs unqualified, like
+// the runtime.FileResolver.
+type testResolver struct{}
+
+func (testResolver) Resolve(id string) (io.Reader, error) {
+	src, err := testSource(id)
+	if err != nil {
+		return nil, err
+	}
+	return bytes.NewReader(src), nil
+}
+
+// Returns the coverage of the execution of the test sources.
+func testCoverage(t *testing.T) *runtime.Coverage {
+	ktx := runtime.NewKtx(testResolver{}, new(compiler.Compiler))
+	ktx.Coverage = runtime.NewCoverage()
+	m, err := ktx.Load("main")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.Run(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	return ktx.Coverage
+}
+
+// Returns a profile of the execution of the test sources.
+func testProfile(t *testing.T, dir string) *Profile {
+	p := NewProfile()
+	p.AddCoverage(testCoverage(t), dir)
+	return p
+}
+
+func testSource(id string) ([]byte, error) {
+	id = strings.TrimPrefix(id, "./")
+	if src, ok := testSrc[id+".agora"]; ok {
+		return []byte(src), nil
+	}
+	return nil, fmt.Errorf("unknown module %s", id)
+}
+
+func TestProfileRoundTrip(t *testing.T) {
+	p := testProfile(t, "")
+	buf := bytes.NewBuffer(nil)
+	if err := p.Write(buf); err != nil {
+		t.Fatal(err)
+	}
+	exp := `mode: count
+./lib:1 1
+./lib:2 1
+./lib:3 0
+./lib:5 1
+./lib:7 1
+main:1 1
+main:2 1
+`
+	if buf.String() != exp {
+		t.Fatalf("expected profile:\n%s\ngot:\n%s", exp, buf)
+	}
+	p2, err := ParseProfile(strings.NewReader(exp))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c, tot := p2.Stats(""); c != 6 || tot != 7 {
+		t.Errorf("expected 6/7 lines, got %d/%d", c, tot)
+	}
+	if pct := p2.Percent("./lib"); pct != 80 {
+		t.Errorf("expected 80%% for lib, got %f", pct)
+	}
+	if pct := p2.Percent("none"); pct != 0 {
+		t.Errorf("expected 0%% for an unknown module, got %f", pct)
+	}
+}
+
+func TestAddCoverageDir(t *testing.T) {
+	p := testProfile(t, "sub/dir")
+	if ids := strings.Join(p.Modules(), ","); ids != "sub/dir/lib,sub/dir/main" {
+		t.Errorf("unexpected module IDs %s", ids)
+	}
+
+	// Adding the same modules sums the counts
+	p = testProfile(t, "")
+	p.AddCoverage(testCoverage(t), "")
+	if n := p.Counts["main"][1]; n != 2 {
+		t.Errorf("expected a count of 2, got %d", n)
+	}
+}
+
+func TestParseProfileErrors(t *testing.T) {
+	cases := []string{
+		"",
+		"mode: set\n",
+		"mode: count\nmain 1\n",
+		"mode: count\nmain:x 1\n",
+		"mode: count\nmain:1 y\n",
+	}
+	for i, c := range cases {
+		if _, err := ParseProfile(strings.NewReader(c)); err == nil {
+			t.Errorf("[%d] - expected an error", i)
+		}
+	}
+}
+
+func TestWriteReports(t *testing.T) {
+	p := testProfile(t, "")
+	buf := bytes.NewBuffer(nil)
+	if err := WriteSummary(buf, p); err != nil {
+		t.Fatal(err)
+	}
+	exp := "./lib\t4/5\t80.0%\nmain\t2/2\t100.0%\ntotal:\t6/7\t85.7%\n"
+	if buf.String() != exp {
+		t.Errorf("expected summary %q, got %q", exp, buf)
+	}
+
+	buf.Reset()
+	if err := WriteText(buf, p, testSource); err != nil {
+		t.Fatal(err)
+	}
+	for _, l := range []string{
+		"== ./lib (80.0%)",
+		"       1  func Small(n) {",
+		"       !  \t\treturn false",
+		"          \t}",
+		"== main (100.0%)",
+	} {
+		if !strings.Contains(buf.String(), l+"\n") {
+			t.Errorf("expected text report to contain %q, got:\n%s", l, buf)
+		}
+	}
+
+	buf.Reset()
+	if err := WriteHTML(buf, p, testSource); err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range []string{
+		"Coverage: 85.7% of lines",
+		`<span class="nocov">		return false</span>`,
+		`<span class="cov">return lib.Small(1)</span>`,
+	} {
+		if !strings.Contains(buf.String(), s) {
+			t.Errorf("expected HTML report to contain %q", s)
+		}
+	}
+
+	if err := WriteText(buf, p, func(string) ([]byte, error) { return nil, fmt.Errorf("x") }); err == nil {
+		t.Errorf("expected the source error to be returned")
+	}
+}
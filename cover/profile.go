@@ -0,0 +1,163 @@
+// Package cover reads and writes the line coverage profiles of agora modules,
+// and reports them as a summary, as annotated source code or as HTML.
+//
+// The coverage is recorded by the runtime when the Coverage field of the
+// execution context is set. A profile is a text file that starts with a
+// `mode: count` line, followed by one line per executable source line:
+//
+//	module-id:line count
+//
+// where count is the number of times the execution entered the line.
+package cover
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/saward/agora/runtime"
+)
+
+// The header of the profiles, the only supported mode.
+const modeLine = "mode: count"
+
+// A Profile holds the execution counts of the lines of modules.
+type Profile struct {
+	// The execution counts by module ID, then by line number. Only the lines
+	// with executable code are present.
+	Counts map[string]map[int]int64
+}
+
+// NewProfile returns a new, empty profile.
+func NewProfile() *Profile {
+	return &Profile{Counts: make(map[string]map[int]int64)}
+}
+
+// AddCoverage adds the coverage recorded by the runtime to the profile. If dir
+// is not empty, it is joined to the relative module IDs, so that they can be
+// resolved from another directory. The counts of a module that is already in
+// the profile are added.
+func (p *Profile) AddCoverage(cov *runtime.Coverage, dir string) {
+	for _, id := range cov.Modules() {
+		lines := cov.Lines(id)
+		if dir != "" && !path.IsAbs(id) {
+			id = path.Join(dir, id)
+		}
+		p.add(id, lines)
+	}
+}
+
+func (p *Profile) add(id string, lines map[int]int64) {
+	m, ok := p.Counts[id]
+	if !ok {
+		m = make(map[int]int64, len(lines))
+		p.Counts[id] = m
+	}
+	for l, n := range lines {
+		m[l] += n
+	}
+}
+
+// Modules returns the IDs of the modules of the profile, sorted in lexical
+// order.
+func (p *Profile) Modules() []string {
+	ids := make([]string, 0, len(p.Counts))
+	for id := range p.Counts {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// Stats returns the number of executed lines and the number of executable
+// lines of the module identified by id, or of all modules if id is empty.
+func (p *Profile) Stats(id string) (covered, total int) {
+	for mid, lines := range p.Counts {
+		if id != "" && mid != id {
+			continue
+		}
+		for _, n := range lines {
+			total++
+			if n > 0 {
+				covered++
+			}
+		}
+	}
+	return covered, total
+}
+
+// Percent returns the percentage of executed lines of the module identified by
+// id, or of all modules if id is empty. It returns 0 if there is no executable
+// line.
+func (p *Profile) Percent(id string) float64 {
+	c, t := p.Stats(id)
+	if t == 0 {
+		return 0
+	}
+	return 100 * float64(c) / float64(t)
+}
+
+// Returns the line numbers of the module, sorted.
+func sortedLines(lines map[int]int64) []int {
+	ls := make([]int, 0, len(lines))
+	for l := range lines {
+		ls = append(ls, l)
+	}
+	sort.Ints(ls)
+	return ls
+}
+
+// Write writes the profile to w, in the format read by ParseProfile.
+func (p *Profile) Write(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	fmt.Fprintln(bw, modeLine)
+	for _, id := range p.Modules() {
+		lines := p.Counts[id]
+		for _, l := range sortedLines(lines) {
+			fmt.Fprintf(bw, "%s:%d %d\n", id, l, lines[l])
+		}
+	}
+	return bw.Flush()
+}
+
+// ParseProfile reads a profile written by Profile.Write.
+func ParseProfile(r io.Reader) (*Profile, error) {
+	p := NewProfile()
+	s := bufio.NewScanner(r)
+	n := 0
+	for s.Scan() {
+		n++
+		txt := s.Text()
+		if n == 1 {
+			if txt != modeLine {
+				return nil, fmt.Errorf("cover: line 1: expected %q, got %q", modeLine, txt)
+			}
+			continue
+		}
+		if txt == "" {
+			continue
+		}
+		sp := strings.LastIndex(txt, " ")
+		col := strings.LastIndex(txt, ":")
+		if sp < 0 || col < 0 || col > sp {
+			return nil, fmt.Errorf("cover: line %d: invalid format: %q", n, txt)
+		}
+		l, err1 := strconv.Atoi(txt[col+1 : sp])
+		cnt, err2 := strconv.ParseInt(txt[sp+1:], 10, 64)
+		if err1 != nil || err2 != nil {
+			return nil, fmt.Errorf("cover: line %d: invalid format: %q", n, txt)
+		}
+		p.add(txt[:col], map[int]int64{l: cnt})
+	}
+	if err := s.Err(); err != nil {
+		return nil, err
+	}
+	if n == 0 {
+		return nil, fmt.Errorf("cover: empty profile")
+	}
+	return p, nil
+}
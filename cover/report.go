@@ -0,0 +1,140 @@
+package cover
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"html/template"
+	"io"
+	"text/tabwriter"
+)
+
+// A SourceFunc returns the source code of the module identified by id, so that
+// the counts of the profile can be mapped back onto the source lines.
+type SourceFunc func(id string) ([]byte, error)
+
+// WriteSummary writes the percentage of executed lines of each module of the
+// profile, and of all modules, to w.
+func WriteSummary(w io.Writer, p *Profile) error {
+	tw := tabwriter.NewWriter(w, 0, 8, 1, '\t', 0)
+	for _, id := range p.Modules() {
+		c, t := p.Stats(id)
+		fmt.Fprintf(tw, "%s\t%d/%d\t%.1f%%\n", id, c, t, p.Percent(id))
+	}
+	c, t := p.Stats("")
+	fmt.Fprintf(tw, "total:\t%d/%d\t%.1f%%\n", c, t, p.Percent(""))
+	return tw.Flush()
+}
+
+// Returns the lines of the source code.
+func splitLines(src []byte) []string {
+	var lines []string
+	s := bufio.NewScanner(bytes.NewReader(src))
+	for s.Scan() {
+		lines = append(lines, s.Text())
+	}
+	return lines
+}
+
+// WriteText writes the source code of each module of the profile to w, with
+// the execution count of each line in the left margin. The lines without
+// executable code have an empty margin, and the lines never executed are
+// marked with `!`.
+func WriteText(w io.Writer, p *Profile, src SourceFunc) error {
+	bw := bufio.NewWriter(w)
+	for i, id := range p.Modules() {
+		b, err := src(id)
+		if err != nil {
+			return err
+		}
+		if i > 0 {
+			fmt.Fprintln(bw)
+		}
+		fmt.Fprintf(bw, "== %s (%.1f%%)\n", id, p.Percent(id))
+		counts := p.Counts[id]
+		for l, txt := range splitLines(b) {
+			n, ok := counts[l+1]
+			switch {
+			case !ok:
+				fmt.Fprintf(bw, "%8s  %s\n", "", txt)
+			case n == 0:
+				fmt.Fprintf(bw, "%8s  %s\n", "!", txt)
+			default:
+				fmt.Fprintf(bw, "%8d  %s\n", n, txt)
+			}
+		}
+	}
+	return bw.Flush()
+}
+
+// The data of the HTML report
+type htmlModule struct {
+	ID      string
+	Percent float64
+	Lines   []htmlLine
+}
+
+type htmlLine struct {
+	Num   int
+	Count int64
+	Class string
+	Text  string
+}
+
+// WriteHTML writes an HTML page to w that shows the source code of each module
+// of the profile, with the executed lines in green and the lines never executed
+// in red.
+func WriteHTML(w io.Writer, p *Profile, src SourceFunc) error {
+	var mods []htmlModule
+	for _, id := range p.Modules() {
+		b, err := src(id)
+		if err != nil {
+			return err
+		}
+		m := htmlModule{ID: id, Percent: p.Percent(id)}
+		counts := p.Counts[id]
+		for l, txt := range splitLines(b) {
+			hl := htmlLine{Num: l + 1, Text: txt}
+			if n, ok := counts[l+1]; ok {
+				hl.Count = n
+				hl.Class = "cov"
+				if n == 0 {
+					hl.Class = "nocov"
+				}
+			}
+			m.Lines = append(m.Lines, hl)
+		}
+		mods = append(mods, m)
+	}
+	return htmlTemplate.Execute(w, struct {
+		Percent float64
+		Modules []htmlModule
+	}{p.Percent(""), mods})
+}
+
+var htmlTemplate = template.Must(template.New("cover").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>agora coverage</title>
+<style>
+body { font-family: sans-serif; }
+pre { font-family: monospace; line-height: 1.3; }
+.num, .cnt { color: #999; display: inline-block; text-align: right; padding-right: 1em; }
+.num { width: 3em; }
+.cnt { width: 4em; }
+.cov { background-color: #c8f0c8; }
+.nocov { background-color: #f6c6c6; }
+</style>
+</head>
+<body>
+<h1>Coverage: {{printf "%.1f" .Percent}}% of lines</h1>
+<ul>
+{{range $i, $m := .Modules}}<li><a href="#mod{{$i}}">{{$m.ID}}</a> ({{printf "%.1f" $m.Percent}}%)</li>
+{{end}}</ul>
+{{range $i, $m := .Modules}}<h2 id="mod{{$i}}">{{$m.ID}} ({{printf "%.1f" $m.Percent}}%)</h2>
+<pre>{{range $m.Lines}}<span class="num">{{.Num}}</span><span class="cnt">{{if .Class}}{{.Count}}{{end}}</span><span class="{{.Class}}">{{.Text}}</span>
+{{end}}</pre>
+{{end}}</body>
+</html>
+`))
@@ -1,5 +1,8 @@
 module github.com/saward/agora
 
-go 1.13
+go 1.16
 
-require github.com/mna/gocoro v0.0.0-20130801164648-ca4c750d8428
+require (
+	github.com/jessevdk/go-flags v1.6.1
+	github.com/mna/gocoro v0.0.0-20130801164648-ca4c750d8428
+)
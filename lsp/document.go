@@ -0,0 +1,372 @@
+package lsp
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+
+	"github.com/saward/agora/compiler/parser"
+	"github.com/saward/agora/compiler/scanner"
+	"github.com/saward/agora/compiler/vet"
+)
+
+// The signatures of the builtin functions, for the hover information.
+var builtins = map[string]string{
+	"import":  "func import(id)",
+	"panic":   "func panic(v)",
+	"recover": "func recover(fn, args...)",
+	"len":     "func len(v)",
+	"keys":    "func keys(ob)",
+	"number":  "func number(v)",
+	"string":  "func string(v)",
+	"bool":    "func bool(v)",
+	"type":    "func type(v)",
+	"status":  "func status(fn)",
+	"reset":   "func reset(fn)",
+}
+
+// The keywords of the language, for completion.
+var keywords = []string{
+	"break", "continue", "debug", "else", "false", "for", "func", "if", "nil",
+	"range", "return", "this", "true", "args", "yield",
+}
+
+// A document is an open source file and the result of its analysis. If the
+// source cannot be parsed, only the diagnostics are available.
+type document struct {
+	uri   string
+	lines []string
+	diags []Diagnostic
+
+	info    *vet.Info
+	funcs   map[*parser.Symbol]*parser.Symbol // Variable declaration to its func literal
+	imports map[string]string                 // Variable name to the imported module ID
+}
+
+// Create a document and analyze its source.
+func newDocument(uri, text string) *document {
+	d := &document{uri: uri}
+	d.update(text)
+	return d
+}
+
+// Update the source of the document and analyze it.
+func (d *document) update(text string) {
+	d.lines = strings.Split(text, "\n")
+	d.diags = []Diagnostic{}
+	d.info, d.funcs, d.imports = nil, nil, nil
+	syms, scp, err := parse(d.uri, []byte(text))
+	if err != nil {
+		d.addDiags(err, SeverityError)
+		return
+	}
+	d.info = vet.Resolve(syms)
+	d.funcs = make(map[*parser.Symbol]*parser.Symbol)
+	d.imports = make(map[string]string)
+	inspect(syms, func(sym *parser.Symbol) {
+		if sym.Id != ":=" {
+			return
+		}
+		nm, ok1 := sym.First.(*parser.Symbol)
+		val, ok2 := sym.Second.(*parser.Symbol)
+		if !ok1 || !ok2 {
+			return
+		}
+		if val.Id == "func" {
+			d.funcs[nm] = val
+		} else if id, ok := importID(val); ok {
+			d.imports[nm.Val.(string)] = id
+		}
+	})
+	d.addDiags(vet.Run(syms, scp), SeverityWarning)
+}
+
+// Parse the source, converting the panics of the parser to errors.
+func parse(fn string, src []byte) (syms []*parser.Symbol, scp *parser.Scope, err error) {
+	defer func() {
+		if e := recover(); e != nil {
+			if el, ok := e.(scanner.ErrorList); ok {
+				err = el
+				return
+			}
+			err = fmt.Errorf("invalid source: %v", e)
+		}
+	}()
+	return parser.New().Parse(fn, src)
+}
+
+// Returns the module ID if the symbol is a call to import with a string literal.
+func importID(sym *parser.Symbol) (string, bool) {
+	fn, ok := sym.First.(*parser.Symbol)
+	if sym.Id != "(" || !ok || fn.Id != "import" {
+		return "", false
+	}
+	args, _ := sym.Second.([]*parser.Symbol)
+	if len(args) != 1 || args[0].Ar != parser.ArLiteral {
+		return "", false
+	}
+	lit, _ := args[0].Val.(string)
+	id, err := strconv.Unquote(lit)
+	return id, err == nil
+}
+
+// Add the errors as diagnostics.
+func (d *document) addDiags(err error, sev int) {
+	if err == nil {
+		return
+	}
+	el, ok := err.(scanner.ErrorList)
+	if !ok {
+		d.diags = append(d.diags, Diagnostic{Severity: sev, Source: "agora", Message: err.Error()})
+		return
+	}
+	for _, e := range el {
+		start := d.position(e.Pos.Line, e.Pos.Column)
+		end := start
+		end.Character += utf16Len(d.wordAt(e.Pos.Line, e.Pos.Column))
+		if end == start {
+			end.Character++
+		}
+		d.diags = append(d.diags, Diagnostic{
+			Range:    Range{start, end},
+			Severity: sev,
+			Source:   "agora",
+			Message:  e.Msg,
+		})
+	}
+}
+
+// Returns the text of the line, 1-based, or an empty string.
+func (d *document) line(l int) string {
+	if l < 1 || l > len(d.lines) {
+		return ""
+	}
+	return d.lines[l-1]
+}
+
+// Convert a 1-based line and byte column to a protocol position.
+func (d *document) position(l, col int) Position {
+	s := d.line(l)
+	if col < 1 {
+		col = 1
+	}
+	if col-1 > len(s) {
+		col = len(s) + 1
+	}
+	if l < 1 {
+		l = 1
+	}
+	return Position{Line: l - 1, Character: utf16Len(s[:col-1])}
+}
+
+// Convert a protocol position to a 1-based line and byte column.
+func (d *document) offset(pos Position) (int, int) {
+	s := d.line(pos.Line + 1)
+	n := 0
+	for i, r := range s {
+		if n >= pos.Character {
+			return pos.Line + 1, i + 1
+		}
+		n += len(utf16.Encode([]rune{r}))
+	}
+	return pos.Line + 1, len(s) + 1
+}
+
+// Returns the number of UTF-16 code units of the string.
+func utf16Len(s string) int {
+	n := 0
+	for _, r := range s {
+		if r >= 0x10000 {
+			n++
+		}
+		n++
+	}
+	return n
+}
+
+func isIdentRune(r rune) bool {
+	return r == '_' || r >= utf8.RuneSelf || ('a' <= r && r <= 'z') || ('A' <= r && r <= 'Z') || ('0' <= r && r <= '9')
+}
+
+// Returns the identifier that starts at the 1-based line and byte column.
+func (d *document) wordAt(l, col int) string {
+	s := d.line(l)
+	if col < 1 || col > len(s) {
+		return ""
+	}
+	s = s[col-1:]
+	end := strings.IndexFunc(s, func(r rune) bool { return !isIdentRune(r) })
+	if end < 0 {
+		end = len(s)
+	}
+	return s[:end]
+}
+
+// Returns the identifier that contains the 1-based line and byte column, and
+// its column.
+func (d *document) wordAround(l, col int) (string, int) {
+	s := d.line(l)
+	if col < 1 || col > len(s)+1 {
+		return "", 0
+	}
+	start := strings.LastIndexFunc(s[:col-1], func(r rune) bool { return !isIdentRune(r) }) + 1
+	return d.wordAt(l, start+1), start + 1
+}
+
+// Returns the range of the name that starts at the 1-based line and column.
+func (d *document) nameRange(l, col int, nm string) Range {
+	start := d.position(l, col)
+	return Range{start, Position{start.Line, start.Character + utf16Len(nm)}}
+}
+
+// Returns the line and column of the name of the declared object. A function
+// statement is declared at the func keyword, its name follows it.
+func (d *document) declPos(o *vet.Object) (int, int) {
+	pos := o.Decl.Pos()
+	if o.Kind == vet.ObjFunc {
+		if s := d.line(pos.Line); pos.Column-1 < len(s) {
+			if i := strings.Index(s[pos.Column-1:], o.Name); i >= 0 {
+				return pos.Line, pos.Column + i
+			}
+		}
+	}
+	return pos.Line, pos.Column
+}
+
+// Returns the object declared or referenced at the 1-based line and column.
+func (d *document) objectAt(l, col int) (*vet.Object, Range) {
+	if d.info == nil {
+		return nil, Range{}
+	}
+	in := func(sl, sc int, nm string) bool {
+		return sl == l && col >= sc && col <= sc+len(nm)
+	}
+	for _, r := range d.info.Refs {
+		pos := r.Sym.Pos()
+		if nm, _ := r.Sym.Val.(string); r.Obj != nil && in(pos.Line, pos.Column, nm) {
+			return r.Obj, d.nameRange(pos.Line, pos.Column, nm)
+		}
+	}
+	for _, o := range d.info.Objects {
+		if dl, dc := d.declPos(o); in(dl, dc, o.Name) {
+			return o, d.nameRange(dl, dc, o.Name)
+		}
+	}
+	return nil, Range{}
+}
+
+// Returns the function symbol of the object, if it is a function.
+func (d *document) funcOf(o *vet.Object) *parser.Symbol {
+	if o.Kind == vet.ObjFunc {
+		return o.Decl
+	}
+	return d.funcs[o.Decl]
+}
+
+// Returns the signature of the function symbol, named nm.
+func signature(nm string, fn *parser.Symbol) string {
+	var params []string
+	if ps, ok := fn.First.([]*parser.Symbol); ok {
+		for _, p := range ps {
+			params = append(params, p.Val.(string))
+		}
+	}
+	return fmt.Sprintf("func %s(%s)", nm, strings.Join(params, ", "))
+}
+
+// Returns the name of the function symbol.
+func funcName(fn *parser.Symbol) string {
+	if fn == nil {
+		return "the top-level function"
+	}
+	if fn.Name != "" {
+		return "func " + fn.Name
+	}
+	return "a func literal"
+}
+
+// Returns the document symbols declared in the function, nil for the top-level.
+func (d *document) symbols(fn *parser.Symbol) []*DocumentSymbol {
+	var res []*DocumentSymbol
+	if d.info == nil {
+		return res
+	}
+	for _, o := range d.info.Objects {
+		if o.Func != fn {
+			continue
+		}
+		l, c := d.declPos(o)
+		ds := &DocumentSymbol{
+			Name:   o.Name,
+			Kind:   SymbolVariable,
+			Range:  d.nameRange(l, c, o.Name),
+			Detail: "var",
+		}
+		if o.Kind == vet.ObjParam {
+			ds.Detail = "parameter"
+		}
+		if f := d.funcOf(o); f != nil {
+			ds.Kind = SymbolFunction
+			ds.Detail = signature(o.Name, f)
+			ds.Children = d.symbols(f)
+		}
+		ds.SelectionRange = ds.Range
+		res = append(res, ds)
+	}
+	return res
+}
+
+// Returns the completion items for the declared names, the builtins and the
+// keywords.
+func (d *document) completeNames() []CompletionItem {
+	seen := make(map[string]bool)
+	var res []CompletionItem
+	if d.info != nil {
+		for _, o := range d.info.Objects {
+			if seen[o.Name] {
+				continue
+			}
+			seen[o.Name] = true
+			it := CompletionItem{Label: o.Name, Kind: CompletionVariable}
+			if f := d.funcOf(o); f != nil {
+				it.Kind, it.Detail = CompletionFunction, signature(o.Name, f)
+			} else if id, ok := d.imports[o.Name]; ok {
+				it.Kind, it.Detail = CompletionModule, fmt.Sprintf("import(%q)", id)
+			}
+			res = append(res, it)
+		}
+	}
+	for nm, sig := range builtins {
+		res = append(res, CompletionItem{Label: nm, Kind: CompletionFunction, Detail: sig})
+	}
+	for _, kw := range keywords {
+		res = append(res, CompletionItem{Label: kw, Kind: CompletionKeyword})
+	}
+	sort.SliceStable(res, func(i, j int) bool { return res[i].Label < res[j].Label })
+	return res
+}
+
+// Call fn for each symbol of the tree, in depth-first order.
+func inspect(v interface{}, fn func(*parser.Symbol)) {
+	switch v := v.(type) {
+	case *parser.Symbol:
+		if v == nil {
+			return
+		}
+		fn(v)
+		inspect(v.First, fn)
+		inspect(v.Second, fn)
+		inspect(v.Third, fn)
+	case []*parser.Symbol:
+		for _, s := range v {
+			inspect(s, fn)
+		}
+	case []interface{}:
+		for _, c := range v {
+			inspect(c, fn)
+		}
+	}
+}
@@ -0,0 +1,351 @@
+// Package lsp implements a Language Server Protocol server for agora source
+// code, to provide editors with diagnostics, navigation and completion.
+//
+// The server communicates over a reader and a writer, typically the standard
+// input and output of the process started by the editor. It supports the full
+// text synchronization of documents, and provides:
+//
+// - diagnostics, the parse errors and the problems reported by compiler/vet,
+//   published when a document is opened or changed;
+// - go-to-definition for the variables, parameters and functions;
+// - hover information, including the parameters of functions;
+// - document symbols, the declarations of each function;
+// - completion of the declared names, builtins, keywords and the members of
+//   the standard library modules.
+package lsp
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/textproto"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/saward/agora/compiler/vet"
+	"github.com/saward/agora/runtime"
+	"github.com/saward/agora/runtime/stdlib"
+)
+
+// A Server is a language server for agora. It is not safe for concurrent use.
+type Server struct {
+	// The native modules whose members are proposed for completion, by module ID.
+	// NewServer sets it to the modules of the standard library.
+	Modules map[string]runtime.NativeModule
+
+	w       io.Writer
+	docs    map[string]*document
+	members map[string][]CompletionItem
+}
+
+// NewServer returns a new language server that knows about the standard library.
+func NewServer() *Server {
+	s := &Server{
+		Modules: make(map[string]runtime.NativeModule),
+		docs:    make(map[string]*document),
+	}
+	for _, m := range stdlib.Modules() {
+		s.Modules[m.ID()] = m
+	}
+	return s
+}
+
+// Serve reads the requests from r and writes the responses and notifications
+// to w, until the exit notification is received or r returns io.EOF.
+func (s *Server) Serve(r io.Reader, w io.Writer) error {
+	s.w = w
+	br := bufio.NewReader(r)
+	for {
+		req, err := readRequest(br)
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if req.Method == "exit" {
+			return nil
+		}
+		if err := s.handle(req); err != nil {
+			return err
+		}
+	}
+}
+
+// Read a request, framed by its headers.
+func readRequest(br *bufio.Reader) (*request, error) {
+	hdr, err := textproto.NewReader(br).ReadMIMEHeader()
+	if err != nil {
+		return nil, err
+	}
+	n, err := strconv.Atoi(hdr.Get("Content-Length"))
+	if err != nil {
+		return nil, fmt.Errorf("lsp: invalid Content-Length header: %s", err)
+	}
+	b := make([]byte, n)
+	if _, err := io.ReadFull(br, b); err != nil {
+		return nil, err
+	}
+	req := new(request)
+	if err := json.Unmarshal(b, req); err != nil {
+		// Cannot respond to a request without an ID
+		req.Method = ""
+	}
+	return req, nil
+}
+
+// Write a message, framed by its headers.
+func (s *Server) write(v interface{}) error {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	if _, err := fmt.Fprintf(s.w, "Content-Length: %d\r\n\r\n", len(b)); err != nil {
+		return err
+	}
+	_, err = s.w.Write(b)
+	return err
+}
+
+// Send the response to a request. Notifications have no response.
+func (s *Server) reply(req *request, res interface{}, rerr *respError) error {
+	if req.ID == nil {
+		return nil
+	}
+	resp := &response{JSONRPC: "2.0", ID: req.ID, Error: rerr}
+	if rerr == nil {
+		b, err := json.Marshal(res)
+		if err != nil {
+			return err
+		}
+		resp.Result = b
+	}
+	return s.write(resp)
+}
+
+// Handle a request or notification.
+func (s *Server) handle(req *request) error {
+	var (
+		res  interface{}
+		rerr *respError
+		err  error
+	)
+	switch req.Method {
+	case "":
+		rerr = &respError{codeParseError, "invalid request"}
+	case "initialize":
+		res = map[string]interface{}{
+			"capabilities": map[string]interface{}{
+				"textDocumentSync":       1, // Full
+				"definitionProvider":     true,
+				"hoverProvider":          true,
+				"documentSymbolProvider": true,
+				"completionProvider": map[string]interface{}{
+					"triggerCharacters": []string{"."},
+				},
+			},
+			"serverInfo": map[string]string{"name": "agora"},
+		}
+	case "shutdown":
+	case "textDocument/didOpen":
+		var p didOpenParams
+		if err = json.Unmarshal(req.Params, &p); err == nil {
+			d := newDocument(p.TextDocument.URI, p.TextDocument.Text)
+			s.docs[d.uri] = d
+			return s.publish(d)
+		}
+	case "textDocument/didChange":
+		var p didChangeParams
+		if err = json.Unmarshal(req.Params, &p); err == nil {
+			d, ok := s.docs[p.TextDocument.URI]
+			if !ok || len(p.ContentChanges) == 0 {
+				return nil
+			}
+			d.update(p.ContentChanges[len(p.ContentChanges)-1].Text)
+			return s.publish(d)
+		}
+	case "textDocument/didClose":
+		var p didCloseParams
+		if err = json.Unmarshal(req.Params, &p); err == nil {
+			delete(s.docs, p.TextDocument.URI)
+			return s.write(&notification{"2.0", "textDocument/publishDiagnostics",
+				&publishDiagnosticsParams{p.TextDocument.URI, []Diagnostic{}}})
+		}
+	case "textDocument/definition":
+		var p textDocumentPositionParams
+		if err = json.Unmarshal(req.Params, &p); err == nil {
+			res = s.definition(p)
+		}
+	case "textDocument/hover":
+		var p textDocumentPositionParams
+		if err = json.Unmarshal(req.Params, &p); err == nil {
+			res = s.hover(p)
+		}
+	case "textDocument/documentSymbol":
+		var p documentSymbolParams
+		if err = json.Unmarshal(req.Params, &p); err == nil {
+			res = []*DocumentSymbol{}
+			if d, ok := s.docs[p.TextDocument.URI]; ok {
+				res = d.symbols(nil)
+			}
+		}
+	case "textDocument/completion":
+		var p textDocumentPositionParams
+		if err = json.Unmarshal(req.Params, &p); err == nil {
+			res = s.completion(p)
+		}
+	default:
+		rerr = &respError{codeMethodNotFound, "method not supported: " + req.Method}
+	}
+	if err != nil {
+		rerr = &respError{codeInvalidParams, err.Error()}
+	}
+	return s.reply(req, res, rerr)
+}
+
+// Publish the diagnostics of the document.
+func (s *Server) publish(d *document) error {
+	return s.write(&notification{"2.0", "textDocument/publishDiagnostics",
+		&publishDiagnosticsParams{d.uri, d.diags}})
+}
+
+// Returns the location of the declaration at the position, or nil.
+func (s *Server) definition(p textDocumentPositionParams) *Location {
+	d, ok := s.docs[p.TextDocument.URI]
+	if !ok {
+		return nil
+	}
+	o, _ := d.objectAt(d.offset(p.Position))
+	if o == nil {
+		return nil
+	}
+	l, c := d.declPos(o)
+	return &Location{d.uri, d.nameRange(l, c, o.Name)}
+}
+
+// Returns the hover information at the position, or nil.
+func (s *Server) hover(p textDocumentPositionParams) *Hover {
+	d, ok := s.docs[p.TextDocument.URI]
+	if !ok {
+		return nil
+	}
+	l, c := d.offset(p.Position)
+	var txt string
+	o, rng := d.objectAt(l, c)
+	switch {
+	case o == nil:
+		// A builtin or the member of a module
+		nm, col := d.wordAround(l, c)
+		if nm == "" {
+			return nil
+		}
+		rng = d.nameRange(l, col, nm)
+		if sig, ok := builtins[nm]; ok {
+			txt = sig + " // builtin"
+			break
+		}
+		line := d.line(l)[:col-1]
+		m := memberRe.FindStringSubmatch(line)
+		if m == nil {
+			return nil
+		}
+		id, ok := d.imports[m[1]]
+		if !ok {
+			return nil
+		}
+		for _, it := range s.moduleMembers(id) {
+			if it.Label == nm {
+				txt = it.Detail
+			}
+		}
+		if txt == "" {
+			return nil
+		}
+	case d.funcOf(o) != nil:
+		txt = signature(o.Name, d.funcOf(o))
+	case o.Kind == vet.ObjParam:
+		txt = fmt.Sprintf("%s // parameter of %s", o.Name, funcName(o.Func))
+	default:
+		txt = o.Name + " := ..."
+		if id, ok := d.imports[o.Name]; ok {
+			txt = fmt.Sprintf("%s := import(%q)", o.Name, id)
+		}
+	}
+	return &Hover{
+		Contents: markupContent{"markdown", "```agora\n" + txt + "\n```"},
+		Range:    &rng,
+	}
+}
+
+var (
+	// The member selection of an identifier, before the cursor
+	memberRe = regexp.MustCompile(`([A-Za-z_][A-Za-z_0-9]*)\.[A-Za-z_0-9]*$`)
+	// The module ID argument of an import, before the cursor
+	importRe = regexp.MustCompile(`import\(\s*["` + "`" + `][^"` + "`" + `]*$`)
+)
+
+// Returns the completion items at the position.
+func (s *Server) completion(p textDocumentPositionParams) []CompletionItem {
+	d, ok := s.docs[p.TextDocument.URI]
+	if !ok {
+		return []CompletionItem{}
+	}
+	l, c := d.offset(p.Position)
+	line := d.line(l)[:c-1]
+	if importRe.MatchString(line) {
+		res := []CompletionItem{}
+		for id := range s.Modules {
+			res = append(res, CompletionItem{Label: id, Kind: CompletionModule})
+		}
+		sort.Slice(res, func(i, j int) bool { return res[i].Label < res[j].Label })
+		return res
+	}
+	if m := memberRe.FindStringSubmatch(line); m != nil {
+		if id, ok := d.imports[m[1]]; ok {
+			return s.moduleMembers(id)
+		}
+		// The fields of objects are not known
+		return []CompletionItem{}
+	}
+	return d.completeNames()
+}
+
+// Returns the completion items for the members of the native module, sorted by
+// name. The module is executed in its own execution context to get its members.
+func (s *Server) moduleMembers(id string) []CompletionItem {
+	if items, ok := s.members[id]; ok {
+		return items
+	}
+	items := []CompletionItem{}
+	if m, ok := s.Modules[id]; ok {
+		ctx := context.Background()
+		ktx := runtime.NewKtx(nil, nil)
+		ktx.RegisterNativeModule(m)
+		if v, err := m.Run(ctx); err == nil {
+			if ob, ok := v.(runtime.Object); ok {
+				keys := ob.Keys(ctx).(runtime.Object)
+				for i, n := int64(0), keys.Len(ctx).Int(ctx); i < n; i++ {
+					k := keys.Get(runtime.Number(i))
+					nm := k.String(ctx)
+					it := CompletionItem{Label: nm, Kind: CompletionVariable, Detail: runtime.Type(ob.Get(k))}
+					if _, ok := ob.Get(k).(runtime.Func); ok {
+						it.Kind, it.Detail = CompletionFunction, "func "+id+"."+nm
+					}
+					if !strings.HasPrefix(nm, "__") {
+						items = append(items, it)
+					}
+				}
+			}
+		}
+		sort.Slice(items, func(i, j int) bool { return items[i].Label < items[j].Label })
+	}
+	if s.members == nil {
+		s.members = make(map[string][]CompletionItem)
+	}
+	s.members[id] = items
+	return items
+}
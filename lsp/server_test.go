@@ -0,0 +1,171 @@
+package lsp
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+const (
+	testURI = "file:///test.agora"
+	testSrc = `fmt := import("fmt")
+func add(a, b) {
+	return a + b
+}
+x := add(1, 2)
+fmt.Println(x)
+`
+)
+
+// Build the input stream of the messages.
+func messages(msgs ...string) *bytes.Buffer {
+	var buf bytes.Buffer
+	for _, m := range msgs {
+		fmt.Fprintf(&buf, "Content-Length: %d\r\n\r\n%s", len(m), m)
+	}
+	return &buf
+}
+
+// Read the messages written by the server, indexed by request ID, and the
+// notifications in order.
+func responses(t *testing.T, out *bytes.Buffer) (map[int]json.RawMessage, []map[string]interface{}) {
+	resps := make(map[int]json.RawMessage)
+	var notifs []map[string]interface{}
+	for _, raw := range splitMessages(t, out.String()) {
+		var m struct {
+			ID     *int             `json:"id"`
+			Method string           `json:"method"`
+			Result json.RawMessage  `json:"result"`
+			Params json.RawMessage  `json:"params"`
+			Error  *json.RawMessage `json:"error"`
+		}
+		if err := json.Unmarshal([]byte(raw), &m); err != nil {
+			t.Fatal(err)
+		}
+		if m.ID != nil {
+			if m.Error != nil {
+				resps[*m.ID] = *m.Error
+			} else {
+				resps[*m.ID] = m.Result
+			}
+			continue
+		}
+		var p map[string]interface{}
+		json.Unmarshal(m.Params, &p)
+		notifs = append(notifs, p)
+	}
+	return resps, notifs
+}
+
+func splitMessages(t *testing.T, s string) []string {
+	var res []string
+	for s != "" {
+		var n int
+		if _, err := fmt.Sscanf(s, "Content-Length: %d\r\n\r\n", &n); err != nil {
+			t.Fatal(err)
+		}
+		i := strings.Index(s, "\r\n\r\n") + 4
+		res = append(res, s[i:i+n])
+		s = s[i+n:]
+	}
+	return res
+}
+
+func posParams(id int, method string, line, char int) string {
+	return fmt.Sprintf(`{"jsonrpc":"2.0","id":%d,"method":%q,"params":{"textDocument":{"uri":%q},"position":{"line":%d,"character":%d}}}`,
+		id, method, testURI, line, char)
+}
+
+func TestServer(t *testing.T) {
+	src, _ := json.Marshal(testSrc)
+	in := messages(
+		`{"jsonrpc":"2.0","id":1,"method":"initialize","params":{}}`,
+		`{"jsonrpc":"2.0","method":"initialized","params":{}}`,
+		fmt.Sprintf(`{"jsonrpc":"2.0","method":"textDocument/didOpen","params":{"textDocument":{"uri":%q,"text":%s}}}`, testURI, src),
+		posParams(2, "textDocument/definition", 4, 6),
+		posParams(3, "textDocument/hover", 4, 6),
+		fmt.Sprintf(`{"jsonrpc":"2.0","id":4,"method":"textDocument/documentSymbol","params":{"textDocument":{"uri":%q}}}`, testURI),
+		posParams(5, "textDocument/completion", 5, 4),
+		posParams(6, "textDocument/completion", 5, 0),
+		posParams(7, "textDocument/hover", 5, 6),
+		fmt.Sprintf(`{"jsonrpc":"2.0","method":"textDocument/didChange","params":{"textDocument":{"uri":%q},"contentChanges":[{"text":"x := y"}]}}`, testURI),
+		posParams(8, "textDocument/definition", 4, 6),
+		`{"jsonrpc":"2.0","id":9,"method":"unknown/method"}`,
+		`{"jsonrpc":"2.0","id":10,"method":"shutdown"}`,
+		`{"jsonrpc":"2.0","method":"exit"}`,
+	)
+	var out bytes.Buffer
+	if err := NewServer().Serve(in, &out); err != nil {
+		t.Fatal(err)
+	}
+	resps, notifs := responses(t, &out)
+
+	// Definition of add
+	var loc Location
+	json.Unmarshal(resps[2], &loc)
+	if exp := (Range{Position{1, 5}, Position{1, 8}}); loc.Range != exp {
+		t.Errorf("definition: expected %v, got %v", exp, loc.Range)
+	}
+	// Hover of add
+	var h Hover
+	json.Unmarshal(resps[3], &h)
+	if !strings.Contains(h.Contents.Value, "func add(a, b)") {
+		t.Errorf("hover: expected the signature of add, got %q", h.Contents.Value)
+	}
+	// Document symbols
+	var syms []*DocumentSymbol
+	json.Unmarshal(resps[4], &syms)
+	var nms []string
+	for _, s := range syms {
+		nms = append(nms, s.Name)
+		for _, c := range s.Children {
+			nms = append(nms, s.Name+"."+c.Name)
+		}
+	}
+	if got, exp := strings.Join(nms, " "), "fmt add add.a add.b x"; got != exp {
+		t.Errorf("symbols: expected %s, got %s", exp, got)
+	}
+	// Completion of the fmt module members
+	if !hasLabel(resps[5], "Println") {
+		t.Errorf("completion: expected Println, got %s", resps[5])
+	}
+	if !hasLabel(resps[6], "len") || !hasLabel(resps[6], "add") {
+		t.Errorf("completion: expected len and add, got %s", resps[6])
+	}
+	// Hover of a stdlib function
+	h = Hover{}
+	json.Unmarshal(resps[7], &h)
+	if !strings.Contains(h.Contents.Value, "fmt.Println") {
+		t.Errorf("hover: expected fmt.Println, got %q", h.Contents.Value)
+	}
+	// No navigation in invalid source
+	if string(resps[8]) != "null" {
+		t.Errorf("definition: expected null for invalid source, got %s", resps[8])
+	}
+	if !strings.Contains(string(resps[9]), "-32601") {
+		t.Errorf("expected a method not found error, got %s", resps[9])
+	}
+	// Diagnostics: none for the first version, an error for the invalid one
+	if len(notifs) != 2 {
+		t.Fatalf("expected 2 notifications, got %d", len(notifs))
+	}
+	if diags := notifs[0]["diagnostics"].([]interface{}); len(diags) != 0 {
+		t.Errorf("expected no diagnostics, got %v", diags)
+	}
+	if diags := notifs[1]["diagnostics"].([]interface{}); len(diags) != 1 {
+		t.Errorf("expected 1 diagnostic, got %v", diags)
+	}
+}
+
+func hasLabel(raw json.RawMessage, lbl string) bool {
+	var items []CompletionItem
+	json.Unmarshal(raw, &items)
+	for _, it := range items {
+		if it.Label == lbl {
+			return true
+		}
+	}
+	return false
+}
@@ -39,7 +39,12 @@ func (b *builtinMod) SetKtx(c *Kontext) {
 
 func (b *builtinMod) _import(ctx context.Context, args ...Val) Val {
 	ExpectAtLeastNArgs(1, args)
-	m, err := b.ktx.Load(args[0].String(ctx))
+	id, from := args[0].String(ctx), b.ktx.importer()
+	if rr, ok := b.ktx.Resolver.(RelativeResolver); ok {
+		id = rr.Qualify(from, id)
+	}
+	b.ktx.addImporter(from, id)
+	m, err := b.ktx.Load(id)
 	if err != nil {
 		panic(err)
 	}
@@ -0,0 +1,43 @@
+package runtime
+
+import (
+	"sync"
+
+	"github.com/saward/agora/bytecode"
+)
+
+// A BytecodeCache holds the bytecode of the compiled agora modules, so that
+// the execution contexts that share it, such as the clones of a context,
+// compile each module only once. It is safe for concurrent use, and the zero
+// value is an empty cache.
+//
+// The bytecode of a module is dropped from the cache when the module is
+// invalidated in any of the execution contexts that share it.
+type BytecodeCache struct {
+	mu    sync.Mutex
+	files map[string]*bytecode.File
+}
+
+// Returns the bytecode of the module id, or nil if it is not cached.
+func (b *BytecodeCache) get(id string) *bytecode.File {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.files[id]
+}
+
+// Store the bytecode f of the module id.
+func (b *BytecodeCache) put(id string, f *bytecode.File) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.files == nil {
+		b.files = make(map[string]*bytecode.File)
+	}
+	b.files[id] = f
+}
+
+// Forget drops the bytecode of the module id from the cache.
+func (b *BytecodeCache) Forget(id string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	delete(b.files, id)
+}
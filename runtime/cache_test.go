@@ -0,0 +1,56 @@
+package runtime
+
+import (
+	"context"
+	"io"
+	"testing"
+	"testing/fstest"
+
+	"github.com/saward/agora/bytecode"
+	"github.com/saward/agora/compiler"
+)
+
+// A compiler that counts the compiled modules.
+type countCompiler struct {
+	n map[string]int
+}
+
+func (c *countCompiler) Compile(id string, r io.Reader) (*bytecode.File, error) {
+	c.n[id]++
+	return new(compiler.Compiler).Compile(id, r)
+}
+
+func TestBytecodeCache(t *testing.T) {
+	ctx := context.Background()
+	fsys := fstest.MapFS{
+		"a.agora": {Data: []byte(`return import("b") + 1`)},
+		"b.agora": {Data: []byte(`return 1`)},
+	}
+	comp := &countCompiler{n: make(map[string]int)}
+	ktx := NewKtx(NewFSResolver(fsys), comp)
+	ktx.Cache = new(BytecodeCache)
+	run := func(k *Kontext) {
+		m, err := k.Load("a")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if v, err := m.Run(ctx); err != nil || v.Int(ctx) != 2 {
+			t.Errorf("expected 2, got %v (%v)", v, err)
+		}
+	}
+	run(ktx)
+	// The clones share the cache, the modules are compiled once
+	run(ktx.Clone())
+	run(ktx.Clone())
+	if comp.n["a"] != 1 || comp.n["b"] != 1 {
+		t.Errorf("expected each module to be compiled once, got %v", comp.n)
+	}
+	// An invalidated module is dropped from the cache
+	k := ktx.Clone()
+	k.Invalidate("b")
+	run(k)
+	run(ktx.Clone())
+	if comp.n["a"] != 1 || comp.n["b"] != 2 {
+		t.Errorf("expected b to be compiled again once, got %v", comp.n)
+	}
+}
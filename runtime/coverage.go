@@ -0,0 +1,91 @@
+package runtime
+
+import (
+	"sort"
+	"sync"
+)
+
+// A Coverage records the number of times the source lines of the agora modules
+// are executed. It is enabled by setting the Coverage field of the execution
+// context before the modules are loaded. A line is counted each time the
+// execution enters it, so a loop on a single line counts one per iteration.
+//
+// The lines are known from the positions recorded by the compiler in the
+// bytecode.Fn.Lines field, so modules loaded from compiled bytecode or assembly
+// source have no coverage information.
+//
+// A Coverage is safe for concurrent use, so that it can be shared by distinct
+// execution contexts.
+type Coverage struct {
+	mu   sync.Mutex
+	mods map[string]map[int]int64
+}
+
+// NewCoverage returns a new, empty Coverage.
+func NewCoverage() *Coverage {
+	return &Coverage{
+		mods: make(map[string]map[int]int64),
+	}
+}
+
+// Register the lines of the module, so that the lines never executed are
+// reported with a count of 0.
+func (c *Coverage) addModule(m *agoraModule) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	lines, ok := c.mods[m.id]
+	for _, fn := range m.fns {
+		for _, l := range fn.lines {
+			if l <= 0 {
+				continue
+			}
+			if !ok {
+				lines = make(map[int]int64)
+				c.mods[m.id] = lines
+				ok = true
+			}
+			if _, seen := lines[int(l)]; !seen {
+				lines[int(l)] = 0
+			}
+		}
+	}
+}
+
+// Increment the count of the line of the module.
+func (c *Coverage) hit(id string, line int64) {
+	c.mu.Lock()
+	if lines, ok := c.mods[id]; ok {
+		lines[int(line)]++
+	}
+	c.mu.Unlock()
+}
+
+// Modules returns the identifiers of the modules that have coverage
+// information, sorted in lexical order.
+func (c *Coverage) Modules() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	ids := make([]string, 0, len(c.mods))
+	for id := range c.mods {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// Lines returns the execution counts of the lines of the module identified by
+// id, indexed by line number. Only the lines that hold executable code are
+// present. It returns nil if the module has no coverage information.
+func (c *Coverage) Lines(id string) map[int]int64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	lines, ok := c.mods[id]
+	if !ok {
+		return nil
+	}
+	cp := make(map[int]int64, len(lines))
+	for l, n := range lines {
+		cp[l] = n
+	}
+	return cp
+}
@@ -0,0 +1,77 @@
+package runtime
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"testing/fstest"
+
+	"github.com/saward/agora/compiler"
+)
+
+func TestCoverage(t *testing.T) {
+	src := `a := 0
+func f(n) {
+	if n > 10 {
+		return "big"
+	}
+	a += n
+	return "small"
+}
+for i := 0; i < 3; i++ {
+	f(i)
+}
+return a
+`
+	ktx := NewKtx(NewFSResolver(fstest.MapFS{"main.agora": {Data: []byte(src)}}), new(compiler.Compiler))
+	ktx.Coverage = NewCoverage()
+	m, err := ktx.Load("main")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.Run(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if ids := ktx.Coverage.Modules(); !reflect.DeepEqual(ids, []string{"main"}) {
+		t.Fatalf("expected modules [main], got %v", ids)
+	}
+	lines := ktx.Coverage.Lines("main")
+	exp := map[int]int64{
+		1:  1,
+		3:  3,
+		4:  0,
+		6:  3,
+		7:  3,
+		10: 3,
+		12: 1,
+	}
+	for l, n := range exp {
+		if got, ok := lines[l]; !ok || got != n {
+			t.Errorf("line %d: expected count %d, got %d (present: %t)", l, n, got, ok)
+		}
+	}
+	for _, l := range []int{5, 8, 11} {
+		if _, ok := lines[l]; ok {
+			t.Errorf("line %d: expected no executable code", l)
+		}
+	}
+	if ktx.Coverage.Lines("nope") != nil {
+		t.Errorf("expected no lines for an unknown module")
+	}
+}
+
+func TestCoverageSingleLineLoop(t *testing.T) {
+	src := "a := 0\nfor i := 0; i < 4; i++ { a++; }\nreturn a\n"
+	ktx := NewKtx(NewFSResolver(fstest.MapFS{"main.agora": {Data: []byte(src)}}), new(compiler.Compiler))
+	ktx.Coverage = NewCoverage()
+	m, err := ktx.Load("main")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.Run(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if n := ktx.Coverage.Lines("main")[2]; n != 5 {
+		t.Errorf("expected the loop line to be entered 5 times, got %d", n)
+	}
+}
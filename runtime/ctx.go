@@ -1,6 +1,8 @@
 package runtime
 
 import (
+	"bufio"
+	"bytes"
 	"fmt"
 	"io"
 	"os"
@@ -13,6 +15,8 @@ type (
 	ModuleNotFoundError string
 	// Error raised when a cyclic dependency is detected
 	CyclicDependencyError string
+	// Error raised when no compiler is registered for a kind of source code
+	NoCompilerError string
 )
 
 // Error interface implementation.
@@ -35,11 +39,30 @@ func NewCyclicDependencyError(id string) CyclicDependencyError {
 	return CyclicDependencyError(fmt.Sprintf("cyclic dependency: %s already being loaded", id))
 }
 
+// Error interface implementation.
+func (e NoCompilerError) Error() string {
+	return string(e)
+}
+
+// Create a new NoCompilerError.
+func NewNoCompilerError(id, kind string) NoCompilerError {
+	return NoCompilerError(fmt.Sprintf("no compiler for module %s of kind %q", id, kind))
+}
+
 // The Compiler interface defines the required behaviour for a Compiler.
 type Compiler interface {
 	Compile(string, io.Reader) (*bytecode.File, error)
 }
 
+// A header-sniffed compiler registration.
+type hdrCompiler struct {
+	hdr []byte
+	c   Compiler
+}
+
+// The length of the bytecode signature, used to sniff compiled modules.
+const bytecodeSigLen = 4
+
 // A frame represents a currently executing function. A native function has no
 // VM.
 type frame struct {
@@ -61,8 +84,15 @@ type Kontext struct {
 	Arithmetic Arithmetic     // The arithmetic processor
 	Comparer   Comparer       // The comparison processor
 	Resolver   ModuleResolver // The module loading resolver (match a module to a string literal)
-	Compiler   Compiler       // The source code compiler
+	Compiler   Compiler       // The default source code compiler
 	Debug      bool           // Debug mode outputs helpful messages
+	TrackDeps  bool           // Track imports so that invalidating a module also invalidates its importers
+	Coverage   *Coverage      // Records the executed lines of the modules loaded after it is set
+	Cache      *BytecodeCache // If set, the compiled modules are shared with the contexts that use the same cache
+
+	// Compilers registry
+	kindComps map[string]Compiler
+	hdrComps  []hdrCompiler
 
 	// Call stack
 	frames []*frame
@@ -71,6 +101,7 @@ type Kontext struct {
 	// Modules management
 	loadingMods map[string]bool // Modules currently being loaded
 	loadedMods  map[string]Module
+	importers   map[string]map[string]bool // Module ID to the IDs of the modules that imported it
 	builtin     Object
 }
 
@@ -85,8 +116,10 @@ func NewKtx(resolver ModuleResolver, comp Compiler) *Kontext {
 		Comparer:    defaultComparer{},
 		Resolver:    resolver,
 		Compiler:    comp,
+		kindComps:   make(map[string]Compiler),
 		loadingMods: make(map[string]bool),
 		loadedMods:  make(map[string]Module),
+		importers:   make(map[string]map[string]bool),
 	}
 	// Automatically add the built-in functions
 	b := new(builtinMod)
@@ -106,13 +139,19 @@ func NewKtx(resolver ModuleResolver, comp Compiler) *Kontext {
 // following:
 //
 // * If id is empty string, return error.
+// * If no module is executing and the resolver is a WatchingResolver, invalidate
+//   the modules whose source changed.
 // * If module is cached (ktx.loadedMods), return the Module, done.
-// * If module is not cached, call ModuleResolver.Resolve(id string) (io.Reader, error)
+// * If module is not cached, call ModuleResolver.Resolve(id string) (io.Reader, error),
+//   or KindResolver.ResolveKind(id string) (io.Reader, string, error) if the resolver
+//   supports it.
 // * If Resolve returns an error, return nil, error, done.
 // * If file is already bytecode, just load it into memory using a decoder
 // * If decoder returns an error, return nil, error, done.
-// * Otherwise (if not bytecode) call Compiler.Compile(id string, r io.Reader) (*bytecode.File, error)
-// * If Compile returns an error, return nil, error, done.
+// * Otherwise (if not bytecode) find the compiler registered for the header of
+//   the source, or for its kind, or use the default Compiler, and call
+//   Compiler.Compile(id string, r io.Reader) (*bytecode.File, error)
+// * If no compiler is available or Compile returns an error, return nil, error, done.
 // * Create module from *bytecode.File
 // * Cache module and return, do NOT execute the module.
 //
@@ -120,12 +159,51 @@ func (c *Kontext) Load(id string) (Module, error) {
 	if id == "" {
 		return nil, NewModuleNotFoundError(id)
 	}
+	// If not called from a running module, drop the modules that changed
+	if wr, ok := c.Resolver.(WatchingResolver); ok && c.frmsp == 0 {
+		for _, chg := range wr.Changed() {
+			c.Invalidate(chg)
+		}
+	}
 	// If already loaded, return from cache
 	if m, ok := c.loadedMods[id]; ok {
 		return m, nil
 	}
-	// Else, resolve the matching file from the module id
-	r, err := c.Resolver.Resolve(id)
+	f, err := c.compile(id)
+	if err != nil {
+		return nil, err
+	}
+	return c.LoadBytecode(id, f), nil
+}
+
+// Compile resolves the module identified by id and returns its bytecode,
+// compiled as by Load, without loading it. The bytecode can be loaded in
+// any number of execution contexts with LoadBytecode.
+func (c *Kontext) Compile(id string) (*bytecode.File, error) {
+	if id == "" {
+		return nil, NewModuleNotFoundError(id)
+	}
+	return c.compile(id)
+}
+
+// Resolve the module identified by id, and compile it to bytecode, or get
+// its bytecode from the Cache.
+func (c *Kontext) compile(id string) (*bytecode.File, error) {
+	if c.Cache != nil {
+		if f := c.Cache.get(id); f != nil {
+			return f, nil
+		}
+	}
+	var (
+		r    io.Reader
+		kind string
+		err  error
+	)
+	if kr, ok := c.Resolver.(KindResolver); ok {
+		r, kind, err = kr.ResolveKind(id)
+	} else {
+		r, err = c.Resolver.Resolve(id)
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -134,22 +212,139 @@ func (c *Kontext) Load(id string) (Module, error) {
 			rc.Close()
 		}
 	}()
-	// If already bytecode, just decode
+	// Buffer the source so that the header can be sniffed
+	br := bufio.NewReader(r)
 	var f *bytecode.File
-	if rs, ok := r.(io.ReadSeeker); ok && bytecode.IsBytecode(rs) {
-		dec := bytecode.NewDecoder(r)
+	if hdr, _ := br.Peek(bytecodeSigLen); bytecode.IsBytecode(bytes.NewReader(hdr)) {
+		// If already bytecode, just decode
+		dec := bytecode.NewDecoder(br)
 		f, err = dec.Decode()
 	} else {
 		// Compile to bytecode
-		f, err = c.Compiler.Compile(id, r)
+		comp := c.compilerFor(br, kind)
+		if comp == nil {
+			return nil, NewNoCompilerError(id, kind)
+		}
+		f, err = comp.Compile(id, br)
 	}
-	if err != nil {
-		return nil, err
+	if err == nil && c.Cache != nil {
+		c.Cache.put(id, f)
 	}
+	return f, err
+}
+
+// LoadBytecode loads the module compiled to the bytecode f, as returned by
+// Compile, and caches it as the module identified by id, replacing any
+// other module with the same ID. As with Load, the module is not executed.
+func (c *Kontext) LoadBytecode(id string, f *bytecode.File) Module {
 	mod := newAgoraModule(f, c)
-	// cache and return
 	c.loadedMods[id] = mod
-	return mod, nil
+	return mod
+}
+
+// Clone returns a new execution context with the same configuration as this
+// one: the standard streams, the arithmetic and comparison processors, the
+// resolver, the compilers, the bytecode Cache and the Debug and TrackDeps
+// flags. It shares no other state with this context (the Cache is safe for
+// concurrent use) and no agora module is loaded in it. The native
+// modules registered in this context that implement CloningModule are
+// registered in the clone as new instances, the others are not available in
+// the clone. The Coverage is not copied.
+func (c *Kontext) Clone() *Kontext {
+	k := NewKtx(c.Resolver, c.Compiler)
+	k.Stdout, k.Stdin, k.Stderr = c.Stdout, c.Stdin, c.Stderr
+	k.Arithmetic = c.Arithmetic
+	k.Comparer = c.Comparer
+	k.Debug = c.Debug
+	k.TrackDeps = c.TrackDeps
+	k.Cache = c.Cache
+	for kind, comp := range c.kindComps {
+		k.kindComps[kind] = comp
+	}
+	k.hdrComps = append(k.hdrComps, c.hdrComps...)
+	for _, m := range c.loadedMods {
+		if cm, ok := m.(CloningModule); ok {
+			k.RegisterNativeModule(cm.Clone())
+		}
+	}
+	return k
+}
+
+// RegisterCompiler registers the compiler to use for source code of the specified
+// kind, as reported by a KindResolver (usually the file extension, such as ".agora").
+// If comp is nil, the registration for this kind is removed.
+func (c *Kontext) RegisterCompiler(kind string, comp Compiler) {
+	if comp == nil {
+		delete(c.kindComps, kind)
+		return
+	}
+	c.kindComps[kind] = comp
+}
+
+// RegisterCompilerHeader registers the compiler to use for source code that
+// starts with the specified header, regardless of its kind. Headers are sniffed
+// in the order they were registered, and take precedence over the kinds.
+func (c *Kontext) RegisterCompilerHeader(hdr []byte, comp Compiler) {
+	c.hdrComps = append(c.hdrComps, hdrCompiler{hdr, comp})
+}
+
+// Get the compiler to use for the source code, based on its header and kind.
+// Defaults to the Compiler field.
+func (c *Kontext) compilerFor(br *bufio.Reader, kind string) Compiler {
+	for _, hc := range c.hdrComps {
+		if b, _ := br.Peek(len(hc.hdr)); bytes.Equal(b, hc.hdr) {
+			return hc.c
+		}
+	}
+	if comp, ok := c.kindComps[kind]; ok {
+		return comp
+	}
+	return c.Compiler
+}
+
+// Invalidate removes the agora module identified by id from the cache, so
+// that it is resolved, compiled and executed again on the next Load or import.
+// If TrackDeps is set, the modules that imported it are invalidated too,
+// recursively. Native modules are never invalidated. It returns the IDs of
+// the invalidated modules.
+func (c *Kontext) Invalidate(id string) []string {
+	if cr, ok := c.Resolver.(CachingResolver); ok {
+		cr.Forget(id)
+	}
+	if c.Cache != nil {
+		c.Cache.Forget(id)
+	}
+	var ids []string
+	if _, ok := c.loadedMods[id].(*agoraModule); ok {
+		delete(c.loadedMods, id)
+		ids = append(ids, id)
+	}
+	imps := c.importers[id]
+	delete(c.importers, id)
+	for imp := range imps {
+		ids = append(ids, c.Invalidate(imp)...)
+	}
+	return ids
+}
+
+// Reload invalidates the module identified by id, and loads it again. The
+// returned module must be run to execute the new version of the code.
+func (c *Kontext) Reload(id string) (Module, error) {
+	c.Invalidate(id)
+	return c.Load(id)
+}
+
+// Record that the module from imported the module id.
+func (c *Kontext) addImporter(from, id string) {
+	if !c.TrackDeps || from == "" {
+		return
+	}
+	imps, ok := c.importers[id]
+	if !ok {
+		imps = make(map[string]bool)
+		c.importers[id] = imps
+	}
+	imps[from] = true
 }
 
 // RegisterNativeModule adds the provided native module to the list of loaded and cached
@@ -202,6 +397,18 @@ func (c *Kontext) IsRunning(f Func) bool {
 	return false
 }
 
+// Get the identifier of the agora module that is currently executing, which is
+// the module that calls `import`. It returns an empty string if no agora function
+// is on the frame stack.
+func (c *Kontext) importer() string {
+	for i := c.frmsp - 1; i >= 0; i-- {
+		if fvm := c.frames[i].fvm; fvm != nil {
+			return fvm.proto.mod.ID()
+		}
+	}
+	return ""
+}
+
 // Get the variable identified by name, looking up the lexical scope stack and ultimately the
 // built-ins.
 func (c *Kontext) getVar(nm string, fvm *agoraFuncVM) (Val, bool) {
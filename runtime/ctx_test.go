@@ -0,0 +1,228 @@
+package runtime
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/saward/agora/bytecode"
+	"github.com/saward/agora/compiler"
+)
+
+const asmSrc = `[f]
+a
+1
+0
+0
+0
+0
+[k]
+sa
+[l]
+[i]
+PUSH K 0
+RET _ 0
+`
+
+// A DSL compiler that turns its source into a module returning the source
+// as a string.
+type dslCompiler struct{}
+
+func (d dslCompiler) Compile(id string, r io.Reader) (*bytecode.File, error) {
+	b, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	s := strings.TrimPrefix(string(b), "#!dsl\n")
+	return new(compiler.Compiler).Compile(id, strings.NewReader("return `"+s+"`"))
+}
+
+// A native module that returns its configuration, and that can be cloned.
+type cfgMod struct {
+	ktx *Kontext
+	cfg string
+}
+
+func (m *cfgMod) ID() string                               { return "cfg" }
+func (m *cfgMod) Run(context.Context, ...Val) (Val, error) { return String(m.cfg), nil }
+func (m *cfgMod) SetKtx(c *Kontext)                        { m.ktx = c }
+func (m *cfgMod) Clone() NativeModule                      { return &cfgMod{cfg: m.cfg} }
+
+// A native module that cannot be cloned.
+type plainMod struct{}
+
+func (m plainMod) ID() string                               { return "plain" }
+func (m plainMod) Run(context.Context, ...Val) (Val, error) { return Nil, nil }
+func (m plainMod) SetKtx(c *Kontext)                        {}
+
+func TestCompilerRegistry(t *testing.T) {
+	ctx := context.Background()
+	fsys := fstest.MapFS{
+		"main.agora": {Data: []byte(`return import("a") + import("b.txt") + import("c.dsl")`)},
+		"a.agoraa":   {Data: []byte(asmSrc)},
+		"b.txt":      {Data: []byte("#!dsl\nb")},
+		"c.dsl":      {Data: []byte("c")},
+	}
+	ktx := NewKtx(NewFSResolver(fsys), nil)
+	ktx.RegisterCompiler(".agora", new(compiler.Compiler))
+	ktx.RegisterCompiler(".agoraa", new(compiler.Asm))
+	ktx.RegisterCompiler(".dsl", dslCompiler{})
+	ktx.RegisterCompilerHeader([]byte("#!dsl\n"), dslCompiler{})
+
+	m, err := ktx.Load("main")
+	if err != nil {
+		t.Fatal(err)
+	}
+	v, err := m.Run(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exp := "abc"; v.String(ctx) != exp {
+		t.Errorf("expected '%s', got '%s'", exp, v.String(ctx))
+	}
+}
+
+func TestNoCompiler(t *testing.T) {
+	fsys := fstest.MapFS{
+		"a.dsl": {Data: []byte("a")},
+	}
+	ktx := NewKtx(NewFSResolver(fsys), nil)
+	ktx.RegisterCompiler(".agora", new(compiler.Compiler))
+	_, err := ktx.Load("a.dsl")
+	if _, ok := err.(NoCompilerError); !ok {
+		t.Errorf("expected a NoCompilerError, got %v", err)
+	}
+}
+
+func TestClone(t *testing.T) {
+	ctx := context.Background()
+	fsys := fstest.MapFS{
+		"a.dsl": {Data: []byte("a")},
+		"b.agora": {Data: []byte(`x := import("a.dsl")
+return x + args[0]`)},
+	}
+	ktx := NewKtx(NewFSResolver(fsys), new(compiler.Compiler))
+	ktx.RegisterCompiler(".dsl", dslCompiler{})
+	ktx.TrackDeps = true
+	cm := &cfgMod{cfg: "x"}
+	ktx.RegisterNativeModule(cm)
+	ktx.RegisterNativeModule(plainMod{})
+	if _, err := ktx.Load("b"); err != nil {
+		t.Fatal(err)
+	}
+
+	k := ktx.Clone()
+	if !k.TrackDeps || k.Resolver != ktx.Resolver || k.Compiler != ktx.Compiler || k.Stdout != ktx.Stdout {
+		t.Errorf("expected the configuration to be copied")
+	}
+	// Only the native modules that can be cloned are registered
+	if len(k.loadedMods) != 1 {
+		t.Errorf("expected 1 loaded module, got %d", len(k.loadedMods))
+	}
+	if m, ok := k.loadedMods["cfg"].(*cfgMod); !ok || m == cm || m.cfg != "x" || m.ktx != k {
+		t.Errorf("expected a new instance of the cfg module bound to the clone, got %#v", k.loadedMods["cfg"])
+	}
+	m, err := k.Load("b")
+	if err != nil {
+		t.Fatal(err)
+	}
+	v, err := m.Run(ctx, String("1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exp := "a1"; v.String(ctx) != exp {
+		t.Errorf("expected '%s', got '%s'", exp, v.String(ctx))
+	}
+	// The registries are distinct
+	k.RegisterCompiler(".dsl", nil)
+	if _, ok := ktx.kindComps[".dsl"]; !ok {
+		t.Errorf("expected the original registry to be unchanged")
+	}
+}
+
+func TestCompile(t *testing.T) {
+	ctx := context.Background()
+	fsys := fstest.MapFS{
+		"a.agora": {Data: []byte(`return args[0] + 1`)},
+	}
+	ktx := NewKtx(NewFSResolver(fsys), new(compiler.Compiler))
+	f, err := ktx.Compile("a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := ktx.loadedMods["a"]; ok {
+		t.Errorf("expected the compiled module not to be loaded")
+	}
+	// The bytecode can be loaded in distinct execution contexts
+	for i, k := range []*Kontext{ktx, ktx.Clone()} {
+		m := k.LoadBytecode("a", f)
+		v, err := m.Run(ctx, Number(i))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if v.Int(ctx) != int64(i+1) {
+			t.Errorf("[%d] - expected %d, got %v", i, i+1, v)
+		}
+		if lm, _ := k.Load("a"); lm != m {
+			t.Errorf("[%d] - expected the module to be cached", i)
+		}
+	}
+	if _, err := ktx.Compile(""); err == nil {
+		t.Errorf("expected an error for an empty id")
+	}
+}
+
+// A native module that keeps the context it is run with.
+type ctxMod struct {
+	ctx context.Context
+}
+
+func (m *ctxMod) ID() string { return "ctx" }
+func (m *ctxMod) Run(ctx context.Context, _ ...Val) (Val, error) {
+	m.ctx = ctx
+	return Nil, nil
+}
+func (m *ctxMod) SetKtx(c *Kontext) {}
+
+func TestRunCancel(t *testing.T) {
+	fsys := fstest.MapFS{
+		"a.agora": {Data: []byte(`return import("b")`)},
+		"b.agora": {Data: []byte(`import("ctx")
+return 1`)},
+	}
+	ktx := NewKtx(NewFSResolver(fsys), new(compiler.Compiler))
+	cm := new(ctxMod)
+	ktx.RegisterNativeModule(cm)
+	m, err := ktx.Load("a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.Run(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	// The context of the outermost run is cancelled when it returns
+	if cm.ctx == nil || cm.ctx.Err() != context.Canceled {
+		t.Errorf("expected the context of the run to be cancelled, got %v", cm.ctx)
+	}
+}
+
+func TestCancel(t *testing.T) {
+	fsys := fstest.MapFS{
+		"loop.agora": {Data: []byte(`for {
+}`)},
+	}
+	ktx := NewKtx(NewFSResolver(fsys), new(compiler.Compiler))
+	m, err := ktx.Load("loop")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err = m.Run(ctx); err != context.DeadlineExceeded {
+		t.Errorf("expected %v, got %v", context.DeadlineExceeded, err)
+	}
+}
@@ -0,0 +1,164 @@
+package runtime
+
+import (
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// The name of the environment variable that holds the list of search roots
+// used by AgoraPath.
+const AgoraPathEnv = "AGORAPATH"
+
+// A RelativeResolver is a ModuleResolver that can qualify a module identifier
+// relative to the identifier of the module that imports it. The qualified
+// identifier is the one used to cache the module, so that the same relative
+// import from two different modules may resolve to two distinct modules.
+type RelativeResolver interface {
+	ModuleResolver
+	Qualify(from, id string) string
+}
+
+// An FSResolver is a ModuleResolver that finds the source code of modules
+// in an fs.FS, such as an embed.FS, an fstest.MapFS, a zip file or the
+// result of os.DirFS.
+//
+// Identifiers starting with "./" or "../" are relative to the directory
+// of the importing module, and identifiers starting with "/" are relative
+// to the root of the file system. Other identifiers are looked up in each
+// of the search roots, in order. If the identifier has no extension, the
+// same extensions as for the FileResolver are tried, in the same order.
+type FSResolver struct {
+	FS    fs.FS
+	Roots []string // Ordered search roots, slash-separated paths in FS
+
+	mu       sync.Mutex
+	resolved map[string]string // Module identifier to path in FS
+}
+
+// NewFSResolver returns a resolver that looks for modules in fsys, using
+// the provided search roots. If no root is provided, the root of fsys is
+// used.
+func NewFSResolver(fsys fs.FS, roots ...string) *FSResolver {
+	if len(roots) == 0 {
+		roots = []string{"."}
+	}
+	return &FSResolver{
+		FS:    fsys,
+		Roots: roots,
+	}
+}
+
+// AgoraPath returns the list of search roots defined by the AGORAPATH
+// environment variable, converted to slash-separated paths. Like the PATH
+// environment variable, roots are separated by the OS-specific path list
+// separator.
+func AgoraPath() []string {
+	var roots []string
+	for _, s := range filepath.SplitList(os.Getenv(AgoraPathEnv)) {
+		if s != "" {
+			roots = append(roots, filepath.ToSlash(s))
+		}
+	}
+	return roots
+}
+
+// Returns true if the identifier is relative to the importing module.
+func isRelativeID(id string) bool {
+	return id == "." || id == ".." || strings.HasPrefix(id, "./") || strings.HasPrefix(id, "../")
+}
+
+// Qualify returns the identifier of the module id when imported by the module
+// from. Relative identifiers are joined to the directory where from was found,
+// and returned as an identifier relative to the root of the file system. Other
+// identifiers are returned unchanged.
+func (r *FSResolver) Qualify(from, id string) string {
+	if !isRelativeID(id) || from == "" {
+		return id
+	}
+	r.mu.Lock()
+	nm, ok := r.resolved[from]
+	r.mu.Unlock()
+	if !ok {
+		nm = strings.TrimPrefix(from, "/")
+	}
+	return "/" + path.Join(path.Dir(nm), id)
+}
+
+// Forget removes the path where the module id was found, so that it is
+// searched again the next time it is resolved.
+func (r *FSResolver) Forget(id string) {
+	r.mu.Lock()
+	delete(r.resolved, id)
+	r.mu.Unlock()
+}
+
+// Resolve finds the module identified by id in the file system. If the module
+// cannot be found, it returns a ModuleNotFoundError listing all the paths that
+// were tried.
+func (r *FSResolver) Resolve(id string) (io.Reader, error) {
+	rd, _, err := r.ResolveKind(id)
+	return rd, err
+}
+
+// ResolveKind is like Resolve, but also returns the extension of the file
+// that was found as the kind of the source code.
+func (r *FSResolver) ResolveKind(id string) (io.Reader, string, error) {
+	var bases []string
+	if isRelativeID(id) || strings.HasPrefix(id, "/") {
+		// Relative to the root of the file system, ignore search roots
+		bases = []string{path.Clean(strings.TrimPrefix(id, "/"))}
+	} else {
+		for _, root := range r.Roots {
+			bases = append(bases, path.Join(root, id))
+		}
+	}
+	var tried []string
+	for _, base := range bases {
+		nms := []string{base}
+		if path.Ext(base) == "" {
+			nms = nms[:0]
+			for _, ext := range extensions {
+				nms = append(nms, base+ext)
+			}
+		}
+		for _, nm := range nms {
+			tried = append(tried, nm)
+			if !fs.ValidPath(nm) {
+				continue
+			}
+			f, err := r.FS.Open(nm)
+			if err != nil {
+				if os.IsNotExist(err) {
+					continue
+				}
+				return nil, "", err
+			}
+			if fi, err := f.Stat(); err == nil && fi.IsDir() {
+				f.Close()
+				continue
+			}
+			r.mu.Lock()
+			if r.resolved == nil {
+				r.resolved = make(map[string]string)
+			}
+			r.resolved[id] = nm
+			r.mu.Unlock()
+			return f, path.Ext(nm), nil
+		}
+	}
+	return nil, "", newModuleNotFoundErrorTried(id, tried)
+}
+
+// Create a new ModuleNotFoundError that lists the paths that were tried.
+func newModuleNotFoundErrorTried(id string, tried []string) ModuleNotFoundError {
+	if len(tried) == 0 {
+		return NewModuleNotFoundError(id)
+	}
+	return ModuleNotFoundError(fmt.Sprintf("module not found: %s (tried %s)", id, strings.Join(tried, ", ")))
+}
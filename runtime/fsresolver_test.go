@@ -0,0 +1,154 @@
+package runtime
+
+import (
+	"context"
+	"io/ioutil"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/saward/agora/compiler"
+)
+
+func TestFSResolverResolve(t *testing.T) {
+	fsys := fstest.MapFS{
+		"lib/a.agora":     {Data: []byte("lib/a")},
+		"lib/b.agoraa":    {Data: []byte("lib/b")},
+		"vendor/a.agora":  {Data: []byte("vendor/a")},
+		"vendor/c.agora":  {Data: []byte("vendor/c")},
+		"app/main.agora":  {Data: []byte("app/main")},
+		"app/sub/d.agora": {Data: []byte("app/sub/d")},
+	}
+	r := NewFSResolver(fsys, "lib", "vendor")
+
+	cases := []struct {
+		id  string
+		exp string
+		err bool
+	}{
+		0: {id: "a", exp: "lib/a"},
+		1: {id: "b", exp: "lib/b"},
+		2: {id: "c", exp: "vendor/c"},
+		3: {id: "c.agora", exp: "vendor/c"},
+		4: {id: "./app/main", exp: "app/main"},
+		5: {id: "/app/sub/d", exp: "app/sub/d"},
+		6: {id: "main", err: true},
+		7: {id: "../a", err: true},
+	}
+	for i, c := range cases {
+		rd, err := r.Resolve(c.id)
+		if c.err {
+			if _, ok := err.(ModuleNotFoundError); !ok {
+				t.Errorf("[%d] - expected a ModuleNotFoundError, got %v", i, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("[%d] - expected no error, got %s", i, err)
+			continue
+		}
+		b, err := ioutil.ReadAll(rd)
+		if err != nil {
+			panic(err)
+		}
+		if string(b) != c.exp {
+			t.Errorf("[%d] - expected '%s', got '%s'", i, c.exp, b)
+		}
+	}
+}
+
+func TestFSResolverTried(t *testing.T) {
+	r := NewFSResolver(fstest.MapFS{}, "lib", "vendor")
+	_, err := r.Resolve("x")
+	if err == nil {
+		t.Fatal("expected an error, got none")
+	}
+	for _, exp := range []string{"lib/x.agorac", "lib/x.agora", "vendor/x.agoraa", "vendor/x.agora"} {
+		if !strings.Contains(err.Error(), exp) {
+			t.Errorf("expected error to list '%s', got '%s'", exp, err)
+		}
+	}
+}
+
+func TestFSResolverQualify(t *testing.T) {
+	r := NewFSResolver(fstest.MapFS{
+		"lib/x/main.agora": {},
+	}, "lib")
+	if _, err := r.Resolve("x/main"); err != nil {
+		panic(err)
+	}
+	cases := []struct {
+		from, id, exp string
+	}{
+		0: {"", "./a", "./a"},
+		1: {"app/main", "./a", "/app/a"},
+		2: {"app/main", "../lib/a", "/lib/a"},
+		3: {"app/sub/main", "a", "a"},
+		4: {"main", "./a", "/a"},
+		5: {"/app/sub/main", "../a", "/app/a"},
+		6: {"x/main", "./a", "/lib/x/a"},
+	}
+	for i, c := range cases {
+		if got := r.Qualify(c.from, c.id); got != c.exp {
+			t.Errorf("[%d] - expected '%s', got '%s'", i, c.exp, got)
+		}
+	}
+}
+
+func TestFSResolverImport(t *testing.T) {
+	ctx := context.Background()
+	fsys := fstest.MapFS{
+		"app/main.agora":  {Data: []byte(`a := import("./sub/a"); return a + import("b")`)},
+		"app/sub/a.agora": {Data: []byte(`return import("./c")`)},
+		"app/sub/c.agora": {Data: []byte(`return "c"`)},
+		"lib/b.agora":     {Data: []byte(`return "b"`)},
+	}
+	ktx := NewKtx(NewFSResolver(fsys, ".", "lib"), new(compiler.Compiler))
+	m, err := ktx.Load("app/main")
+	if err != nil {
+		t.Fatal(err)
+	}
+	v, err := m.Run(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exp := "cb"; v.String(ctx) != exp {
+		t.Errorf("expected '%s', got '%s'", exp, v.String(ctx))
+	}
+}
+
+func TestFSResolverForget(t *testing.T) {
+	ctx := context.Background()
+	fsys := fstest.MapFS{
+		"lib/a.agora": {Data: []byte(`return "lib"`)},
+	}
+	res := NewFSResolver(fsys, ".", "lib")
+	ktx := NewKtx(res, new(compiler.Compiler))
+	run := func() string {
+		m, err := ktx.Load("a")
+		if err != nil {
+			t.Fatal(err)
+		}
+		v, err := m.Run(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return v.String(ctx)
+	}
+	if v := run(); v != "lib" {
+		t.Errorf("expected 'lib', got '%s'", v)
+	}
+	if _, ok := res.resolved["a"]; !ok {
+		t.Errorf("expected the path of a to be recorded")
+	}
+
+	// A module added to an earlier root is found after invalidation
+	fsys["a.agora"] = &fstest.MapFile{Data: []byte(`return "root"`)}
+	ktx.Invalidate("a")
+	if _, ok := res.resolved["a"]; ok {
+		t.Errorf("expected the path of a to be forgotten")
+	}
+	if v := run(); v != "root" {
+		t.Errorf("expected 'root', got '%s'", v)
+	}
+}
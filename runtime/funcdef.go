@@ -27,6 +27,7 @@ type agoraFuncDef struct {
 	kTable  []Val
 	lTable  []string
 	code    []bytecode.Instr
+	lines   []int64 // The source line of each instruction, if known
 }
 
 func newAgoraFuncDef(mod *agoraModule, c *Kontext) *agoraFuncDef {
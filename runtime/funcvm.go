@@ -8,6 +8,7 @@ import (
 	"math"
 	"sort"
 	"strings"
+	"unicode/utf8"
 
 	"github.com/saward/agora/bytecode"
 	"github.com/mna/gocoro"
@@ -240,12 +241,11 @@ func (vm *agoraFuncVM) pushRange(ctx context.Context, args ...Val) {
 				panic(gocoro.ErrEndOfCoro)
 			}
 			if sep == "" {
-				cnt := int64(len(src))
-				if max >= 0 && max < cnt {
-					cnt = max
-				}
-				for i := int64(0); i < cnt; i++ {
-					y.Yield(String(src[i]))
+				// Loop over the runes, an invalid UTF-8 byte being yielded as is
+				for i, cnt := 0, int64(0); i < len(src) && (max < 0 || cnt < max); cnt++ {
+					_, n := utf8.DecodeRuneInString(src[i:])
+					y.Yield(String(src[i : i+n]))
+					i += n
 				}
 			} else {
 				cnt := int64(0)
@@ -267,6 +267,20 @@ func (vm *agoraFuncVM) pushRange(ctx context.Context, args ...Val) {
 
 	case "object":
 		ob := args[0].(Object)
+		if _, ok := ob.Get(String("__next")).(Func); ok {
+			// An iterator object, yield the values returned by __next until nil
+			coro = gocoro.New(func(y gocoro.Yielder, _ ...interface{}) interface{} {
+				for {
+					v, _ := ob.callMetaMethod(ctx, "__next")
+					if v == Nil {
+						break
+					}
+					y.Yield(v)
+				}
+				panic(gocoro.ErrEndOfCoro)
+			})
+			break
+		}
 		coro = gocoro.New(func(y gocoro.Yielder, args ...interface{}) interface{} {
 			ks := ob.Keys(ctx).(Object)
 			for i := int64(0); i < ks.Len(ctx).Int(ctx); i++ {
@@ -334,6 +348,27 @@ func (f *agoraFuncVM) run(ctx context.Context, args ...Val) Val {
 	arith := f.proto.ktx.Arithmetic
 	cmp := f.proto.ktx.Comparer
 
+	// Record the lines entered by the execution if coverage is enabled
+	cov := f.proto.ktx.Coverage
+	if f.proto.lines == nil {
+		cov = nil
+	}
+	var line, lastPC int64 = 0, -1
+
+	// The execution stops when the context is cancelled, which is checked on
+	// each call and backward jump so that loops and recursions are covered.
+	done := ctx.Done()
+	checkDone := func() {
+		if done != nil {
+			select {
+			case <-done:
+				panic(ctx.Err())
+			default:
+			}
+		}
+	}
+	checkDone()
+
 	// If the program counter is 0, this is an initial run, not a resume as
 	// a coroutine.
 	if f.pc == 0 {
@@ -363,6 +398,15 @@ func (f *agoraFuncVM) run(ctx context.Context, args ...Val) Val {
 	for {
 		// Get the instruction to process
 		i := f.proto.code[f.pc]
+		if cov != nil {
+			// A line is entered when the line changes, or when a jump goes back
+			// on the same line (e.g. a loop on a single line).
+			if l := f.proto.lines[f.pc]; l > 0 && (l != line || int64(f.pc) <= lastPC) {
+				cov.hit(f.proto.mod.id, l)
+				line = l
+			}
+			lastPC = int64(f.pc)
+		}
 		// Decode the instruction
 		op, flg, ix := i.Opcode(), i.Flag(), i.Index()
 		// Increment the PC, if a jump requires a different PC delta, it will set it explicitly
@@ -452,6 +496,7 @@ func (f *agoraFuncVM) run(ctx context.Context, args ...Val) Val {
 				f.pc += int(ix)
 			} else {
 				f.pc -= (int(ix) + 1) // +1 because pc is already on next instr
+				checkDone()
 			}
 
 		case bytecode.OP_NEW:
@@ -36,6 +36,14 @@ type NativeModule interface {
 	SetKtx(*Kontext)
 }
 
+// A CloningModule is a NativeModule that can return a new instance of itself,
+// with the same configuration, to be registered in a clone of its execution
+// context.
+type CloningModule interface {
+	NativeModule
+	Clone() NativeModule
+}
+
 // An agora module holds its ID, its function table, and the value it returned.
 type agoraModule struct {
 	id  string
@@ -81,6 +89,12 @@ func newAgoraModule(f *bytecode.File, c *Kontext) *agoraModule {
 		for j, ins := range fn.Is {
 			af.code[j] = ins
 		}
+		if len(fn.Lines) == len(fn.Is) {
+			af.lines = fn.Lines
+		}
+	}
+	if c.Coverage != nil {
+		c.Coverage.addModule(m)
 	}
 	return m
 }
@@ -94,6 +108,13 @@ func (m *agoraModule) Run(ctx context.Context, args ...Val) (v Val, err error) {
 	// Do not re-run a module if it has already been imported. Use the cached value.
 	if m.v == nil {
 		fn := m.fns[0]
+		if fn.ktx.frmsp == 0 {
+			// Outermost run in this context: what its execution started with
+			// the context, such as goroutines, is cancelled when it returns.
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithCancel(ctx)
+			defer cancel()
+		}
 		fn.ktx.pushModule(m.ID())
 		defer fn.ktx.popModule(m.ID())
 		fv := newAgoraFuncVal(fn, nil)
@@ -128,6 +149,22 @@ type ModuleResolver interface {
 	Resolve(string) (io.Reader, error)
 }
 
+// A KindResolver is a ModuleResolver that also reports the kind of source code
+// it returns, so that the execution context can pick the matching compiler. The
+// kind is usually the file extension (e.g. ".agora" or ".agoraa").
+type KindResolver interface {
+	ModuleResolver
+	ResolveKind(string) (io.Reader, string, error)
+}
+
+// A CachingResolver is a ModuleResolver that remembers information about the
+// modules it resolves. The execution context calls Forget when a module is
+// invalidated, so that it is resolved again on the next load.
+type CachingResolver interface {
+	ModuleResolver
+	Forget(string)
+}
+
 // A FileResolver is a ModuleResolver that turns the module identifier into
 // a file path to find the matching source code.
 type FileResolver struct{}
@@ -144,18 +181,21 @@ var (
 // 1- .agorac (compiled bytecode)
 // 2- .agoraa (agora assembly code)
 // 3- .agora  (agora source code)
-//
-// TODO : This doesn't work, the Ktx has a single compiler, that may
-// compile assembly or source, but not both. The Resolver should look
-// for compiled bytecode or the same source code as the initial Ktx.Load.
 func (f FileResolver) Resolve(id string) (io.Reader, error) {
+	r, _, err := f.ResolveKind(id)
+	return r, err
+}
+
+// ResolveKind is like Resolve, but also returns the extension of the file
+// that was found as the kind of the source code.
+func (f FileResolver) ResolveKind(id string) (io.Reader, string, error) {
 	var nm string
 	if filepath.IsAbs(id) {
 		nm = id
 	} else {
 		pwd, err := os.Getwd()
 		if err != nil {
-			return nil, err
+			return nil, "", err
 		}
 		nm = filepath.Join(pwd, id)
 	}
@@ -163,7 +203,7 @@ func (f FileResolver) Resolve(id string) (io.Reader, error) {
 		for _, ext := range extensions {
 			if _, err := os.Stat(nm + ext); err != nil {
 				if !os.IsNotExist(err) {
-					return nil, err
+					return nil, "", err
 				}
 			} else {
 				nm += ext
@@ -171,5 +211,9 @@ func (f FileResolver) Resolve(id string) (io.Reader, error) {
 			}
 		}
 	}
-	return os.Open(nm)
+	r, err := os.Open(nm)
+	if err != nil {
+		return nil, "", err
+	}
+	return r, filepath.Ext(nm), nil
 }
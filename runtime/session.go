@@ -0,0 +1,72 @@
+package runtime
+
+import (
+	"context"
+	"sort"
+
+	"github.com/saward/agora/bytecode"
+)
+
+// A Session executes successive bytecode files in the same execution context,
+// and keeps the top-level variables they define, so that each file can use the
+// variables defined by the previous ones. This is what an interactive interpreter
+// requires. The files must be compiled with the names returned by Vars defined
+// in their scope.
+type Session struct {
+	ktx  *Kontext
+	vars map[string]Val
+}
+
+// NewSession returns a new session that executes in the provided execution context.
+func NewSession(ktx *Kontext) *Session {
+	return &Session{
+		ktx:  ktx,
+		vars: make(map[string]Val),
+	}
+}
+
+// Vars returns the names of the variables defined in the session, sorted in
+// lexical order.
+func (s *Session) Vars() []string {
+	nms := make([]string, 0, len(s.vars))
+	for k := range s.vars {
+		nms = append(nms, k)
+	}
+	sort.Strings(nms)
+	return nms
+}
+
+// Get returns the value of the variable identified by nm, or Nil if it is
+// not defined.
+func (s *Session) Get(nm string) Val {
+	if v, ok := s.vars[nm]; ok {
+		return v
+	}
+	return Nil
+}
+
+// Run executes the top-level function of the bytecode file and returns its
+// return value, or an error. If it succeeds, the local variables of the
+// top-level function are added to the session.
+func (s *Session) Run(ctx context.Context, f *bytecode.File, args ...Val) (v Val, err error) {
+	defer PanicToError(&err)
+	m := newAgoraModule(f, s.ktx)
+	if len(m.fns) == 0 {
+		return Nil, NewEmptyModuleError(m.ID())
+	}
+	// The session's variables are the environment of the top-level function,
+	// as if it was a closure.
+	fv := newAgoraFuncVal(m.fns[0], nil)
+	fv.env = &env{
+		s.vars,
+		nil,
+	}
+	vm := newFuncVM(fv)
+	s.ktx.pushFn(fv, vm)
+	defer s.ktx.popFn()
+	v = vm.run(ctx, args...)
+	for k, lv := range vm.vars {
+		s.vars[k] = lv
+	}
+	return v, nil
+}
@@ -0,0 +1,52 @@
+package runtime
+
+import (
+	"context"
+	"testing"
+
+	"github.com/saward/agora/compiler/emitter"
+	"github.com/saward/agora/compiler/parser"
+)
+
+func TestSession(t *testing.T) {
+	ctx := context.Background()
+	ktx := NewKtx(nil, nil)
+	ses := NewSession(ktx)
+
+	cases := []struct {
+		src string
+		exp Val
+		err bool
+	}{
+		0: {src: `a := 1`, exp: Nil},
+		1: {src: `return a + 1`, exp: Number(2)},
+		2: {src: `a = a + 10`, exp: Nil},
+		3: {src: `return a`, exp: Number(11)},
+		4: {src: `a := "x"; b := func() { return a + "y"; }`, exp: Nil},
+		5: {src: `return b()`, exp: String("xy")},
+		6: {src: `return c`, err: true},
+	}
+	for i, c := range cases {
+		p := parser.New()
+		p.Predeclared = ses.Vars()
+		syms, scp, err := p.Parse("test", []byte(c.src))
+		if err != nil {
+			if !c.err {
+				t.Errorf("[%d] - expected no parse error, got %s", i, err)
+			}
+			continue
+		}
+		f, err := new(emitter.Emitter).Emit("test", syms, scp)
+		if err != nil {
+			panic(err)
+		}
+		v, err := ses.Run(ctx, f)
+		if (err != nil) != c.err {
+			t.Errorf("[%d] - expected error %v, got %v", i, c.err, err)
+			continue
+		}
+		if !c.err && v != c.exp {
+			t.Errorf("[%d] - expected %v, got %v", i, c.exp, v)
+		}
+	}
+}
@@ -0,0 +1,360 @@
+package stdlib
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"math"
+
+	"github.com/saward/agora/runtime"
+)
+
+// The bytes module, as documented in
+// https://github.com/saward/agora/wiki/Standard-library
+type BytesMod struct {
+	ktx *runtime.Kontext
+	ob  runtime.Object
+}
+
+func (b *BytesMod) ID() string {
+	return "bytes"
+}
+
+func (b *BytesMod) Run(_ context.Context, _ ...runtime.Val) (v runtime.Val, err error) {
+	defer runtime.PanicToError(&err)
+	if b.ob == nil {
+		// Prepare the object
+		b.ob = runtime.NewObject()
+		b.ob.Set(runtime.String("LittleEndian"), runtime.String("le"))
+		b.ob.Set(runtime.String("BigEndian"), runtime.String("be"))
+		b.ob.Set(runtime.String("New"), runtime.NewNativeFunc(b.ktx, "bytes.New", b.bytes_New))
+		b.ob.Set(runtime.String("Equal"), runtime.NewNativeFunc(b.ktx, "bytes.Equal", b.bytes_Equal))
+	}
+	return b.ob, nil
+}
+
+func (b *BytesMod) SetKtx(c *runtime.Kontext) {
+	b.ktx = c
+}
+
+func (b *BytesMod) Clone() runtime.NativeModule {
+	return new(BytesMod)
+}
+
+// Args:
+// 0 - The initial content (optional), a number of zero bytes, a string, a
+// buffer or an array-like object of byte values
+// Returns:
+// The new buffer, see the documentation for its methods.
+func (b *BytesMod) bytes_New(ctx context.Context, args ...runtime.Val) runtime.Val {
+	if len(args) == 0 {
+		return newBuffer(b.ktx, nil)
+	}
+	if n, ok := args[0].(runtime.Number); ok {
+		return newBuffer(b.ktx, make([]byte, int(n.Int(ctx))))
+	}
+	return newBuffer(b.ktx, appendBytes(ctx, nil, args[0]))
+}
+
+// Args:
+// 0 - The first buffer or string
+// 1 - The second buffer or string
+// Returns:
+// True if both hold the same bytes.
+func (b *BytesMod) bytes_Equal(ctx context.Context, args ...runtime.Val) runtime.Val {
+	runtime.ExpectAtLeastNArgs(2, args)
+	return runtime.Bool(bytes.Equal(appendBytes(ctx, nil, args[0]), appendBytes(ctx, nil, args[1])))
+}
+
+// A buffer is a mutable array of bytes. Its bytes are accessed by indexing
+// it with a number, and its methods and other fields are stored in the
+// embedded object.
+type buffer struct {
+	runtime.Object
+	b []byte
+}
+
+func newBuffer(ktx *runtime.Kontext, b []byte) *buffer {
+	ob := &buffer{
+		runtime.NewObject(),
+		b,
+	}
+	ob.Set(runtime.String("__string"), runtime.NewNativeFunc(ktx, "bytes.Buffer.__string", func(_ context.Context, args ...runtime.Val) runtime.Val {
+		return runtime.String(ob.b)
+	}))
+	ob.Set(runtime.String("__cmp"), runtime.NewNativeFunc(ktx, "bytes.Buffer.__cmp", ob.cmp))
+	ob.Set(runtime.String("Append"), runtime.NewNativeFunc(ktx, "bytes.Buffer.Append", ob.append))
+	ob.Set(runtime.String("Slice"), runtime.NewNativeFunc(ktx, "bytes.Buffer.Slice", func(ctx context.Context, args ...runtime.Val) runtime.Val {
+		start, end := ob.bounds(ctx, args)
+		return newBuffer(ktx, append([]byte(nil), ob.b[start:end]...))
+	}))
+	ob.Set(runtime.String("String"), runtime.NewNativeFunc(ktx, "bytes.Buffer.String", func(ctx context.Context, args ...runtime.Val) runtime.Val {
+		start, end := ob.bounds(ctx, args)
+		return runtime.String(ob.b[start:end])
+	}))
+	ob.Set(runtime.String("ReadInt"), runtime.NewNativeFunc(ktx, "bytes.Buffer.ReadInt", ob.readInt))
+	ob.Set(runtime.String("ReadUint"), runtime.NewNativeFunc(ktx, "bytes.Buffer.ReadUint", ob.readUint))
+	ob.Set(runtime.String("WriteInt"), runtime.NewNativeFunc(ktx, "bytes.Buffer.WriteInt", ob.writeInt))
+	ob.Set(runtime.String("AppendInt"), runtime.NewNativeFunc(ktx, "bytes.Buffer.AppendInt", ob.appendInt))
+	return ob
+}
+
+// Returns the byte index of the key, which must be in the range of the
+// buffer, or in the range extended by one if ext is true.
+func (b *buffer) index(k runtime.Number, ext bool) int {
+	f := float64(k)
+	max := len(b.b)
+	if ext {
+		max++
+	}
+	if f != math.Trunc(f) || f < 0 || f >= float64(max) {
+		panic(fmt.Sprintf("bytes: index %v out of range [0:%d]", f, len(b.b)))
+	}
+	return int(f)
+}
+
+// Get returns the byte at the index if the key is a number, or the field of
+// the buffer otherwise.
+func (b *buffer) Get(k runtime.Val) runtime.Val {
+	if n, ok := k.(runtime.Number); ok {
+		return runtime.Number(b.b[b.index(n, false)])
+	}
+	return b.Object.Get(k)
+}
+
+// Set sets the byte at the index if the key is a number, or the field of the
+// buffer otherwise. Setting the byte just past the end appends it.
+func (b *buffer) Set(k, v runtime.Val) {
+	if n, ok := k.(runtime.Number); ok {
+		i := b.index(n, true)
+		c := toByte(v)
+		if i == len(b.b) {
+			b.b = append(b.b, c)
+		} else {
+			b.b[i] = c
+		}
+		return
+	}
+	b.Object.Set(k, v)
+}
+
+// Len returns the number of bytes in the buffer.
+func (b *buffer) Len(ctx context.Context) runtime.Val {
+	return runtime.Number(len(b.b))
+}
+
+// Keys returns the indices of the bytes, so that ranging over a buffer
+// yields its bytes.
+func (b *buffer) Keys(ctx context.Context) runtime.Val {
+	ob := runtime.NewObject()
+	for i := range b.b {
+		ob.Set(runtime.Number(i), runtime.Number(i))
+	}
+	return ob
+}
+
+// Native returns the bytes of the buffer.
+func (b *buffer) Native(ctx context.Context) interface{} {
+	return b.b
+}
+
+// Returns the byte value of v, which must be a number between 0 and 255.
+func toByte(v runtime.Val) byte {
+	n, ok := v.(runtime.Number)
+	if !ok {
+		panic(runtime.NewTypeError(runtime.Type(v), "", "bytes.Buffer byte"))
+	}
+	if f := float64(n); f != math.Trunc(f) || f < 0 || f > 255 {
+		panic(fmt.Sprintf("bytes: invalid byte value %v", f))
+	}
+	return byte(n)
+}
+
+// Appends the bytes of v to b and returns the resulting slice. The value
+// is a byte number, a string, a buffer or an array-like object of byte
+// numbers.
+func appendBytes(ctx context.Context, b []byte, v runtime.Val) []byte {
+	switch v := v.(type) {
+	case runtime.Number:
+		return append(b, toByte(v))
+	case runtime.String:
+		return append(b, v...)
+	case *buffer:
+		return append(b, v.b...)
+	case runtime.Object:
+		n := int(v.Len(ctx).Int(ctx))
+		for i := 0; i < n; i++ {
+			b = append(b, toByte(v.Get(runtime.Number(i))))
+		}
+		return b
+	}
+	panic(runtime.NewTypeError(runtime.Type(v), "", "bytes"))
+}
+
+// Returns the bytes of v, the bytes of the buffer or of the string
+// conversion of any other value.
+func toBytes(ctx context.Context, v runtime.Val) []byte {
+	if b, ok := v.(*buffer); ok {
+		return b.b
+	}
+	return []byte(v.String(ctx))
+}
+
+// Returns the start and end indices provided as optional arguments, that
+// default to the start and end of the buffer.
+func (b *buffer) bounds(ctx context.Context, args []runtime.Val) (int, int) {
+	start, end := 0, len(b.b)
+	if len(args) > 0 {
+		start = b.index(runtime.Number(args[0].Float(ctx)), true)
+	}
+	if len(args) > 1 {
+		end = b.index(runtime.Number(args[1].Float(ctx)), true)
+	}
+	if start > end {
+		panic(fmt.Sprintf("bytes: invalid slice indices %d > %d", start, end))
+	}
+	return start, end
+}
+
+// Returns the byte order specified by the optional argument at index i,
+// little-endian by default.
+func byteOrder(ctx context.Context, args []runtime.Val, i int) binary.ByteOrder {
+	if len(args) <= i {
+		return binary.LittleEndian
+	}
+	switch o := args[i].String(ctx); o {
+	case "le":
+		return binary.LittleEndian
+	case "be":
+		return binary.BigEndian
+	default:
+		panic(fmt.Sprintf("bytes: invalid byte order %q", o))
+	}
+}
+
+// Returns the integer size in bytes, which must be 1, 2, 4 or 8.
+func intSize(ctx context.Context, v runtime.Val) int {
+	n := int(v.Int(ctx))
+	switch n {
+	case 1, 2, 4, 8:
+		return n
+	}
+	panic(fmt.Sprintf("bytes: invalid integer size %d", n))
+}
+
+// Returns the unsigned integer of size bytes at offset off, and the size.
+func (b *buffer) uintAt(ctx context.Context, args []runtime.Val) (uint64, int) {
+	runtime.ExpectAtLeastNArgs(2, args)
+	off := int(args[0].Int(ctx))
+	size := intSize(ctx, args[1])
+	if off < 0 || off+size > len(b.b) {
+		panic(fmt.Sprintf("bytes: cannot read %d bytes at offset %d of %d", size, off, len(b.b)))
+	}
+	p, o := b.b[off:off+size], byteOrder(ctx, args, 2)
+	switch size {
+	case 1:
+		return uint64(p[0]), size
+	case 2:
+		return uint64(o.Uint16(p)), size
+	case 4:
+		return uint64(o.Uint32(p)), size
+	}
+	return o.Uint64(p), size
+}
+
+// Stores the integer v in p, truncated to len(p) bytes.
+func putUint(p []byte, o binary.ByteOrder, v uint64) {
+	switch len(p) {
+	case 1:
+		p[0] = byte(v)
+	case 2:
+		o.PutUint16(p, uint16(v))
+	case 4:
+		o.PutUint32(p, uint32(v))
+	default:
+		o.PutUint64(p, v)
+	}
+}
+
+func (b *buffer) cmp(ctx context.Context, args ...runtime.Val) runtime.Val {
+	runtime.ExpectAtLeastNArgs(2, args)
+	ob, ok := args[0].(*buffer)
+	if !ok {
+		// Buffers have no ordering with other values
+		return runtime.Number(-1)
+	}
+	if args[1].Bool(ctx) {
+		return runtime.Number(bytes.Compare(b.b, ob.b))
+	}
+	return runtime.Number(bytes.Compare(ob.b, b.b))
+}
+
+// Args:
+// 0..n - The values to append, byte numbers, strings, buffers or array-like
+// objects of byte numbers
+// Returns:
+// The buffer.
+func (b *buffer) append(ctx context.Context, args ...runtime.Val) runtime.Val {
+	for _, v := range args {
+		b.b = appendBytes(ctx, b.b, v)
+	}
+	return b
+}
+
+// Args:
+// 0 - The offset of the integer
+// 1 - The size of the integer, in bytes: 1, 2, 4 or 8
+// 2 - The byte order, "le" or "be" (optional, defaults to "le")
+// Returns:
+// The signed integer.
+func (b *buffer) readInt(ctx context.Context, args ...runtime.Val) runtime.Val {
+	u, size := b.uintAt(ctx, args)
+	// Sign-extend the value
+	shift := uint(64 - 8*size)
+	return runtime.Number(int64(u<<shift) >> shift)
+}
+
+// Args:
+// 0 - The offset of the integer
+// 1 - The size of the integer, in bytes: 1, 2, 4 or 8
+// 2 - The byte order, "le" or "be" (optional, defaults to "le")
+// Returns:
+// The unsigned integer.
+func (b *buffer) readUint(ctx context.Context, args ...runtime.Val) runtime.Val {
+	u, _ := b.uintAt(ctx, args)
+	return runtime.Number(u)
+}
+
+// Args:
+// 0 - The offset of the integer
+// 1 - The size of the integer, in bytes: 1, 2, 4 or 8
+// 2 - The integer, signed or unsigned
+// 3 - The byte order, "le" or "be" (optional, defaults to "le")
+// Returns:
+// The buffer.
+func (b *buffer) writeInt(ctx context.Context, args ...runtime.Val) runtime.Val {
+	runtime.ExpectAtLeastNArgs(3, args)
+	off := int(args[0].Int(ctx))
+	size := intSize(ctx, args[1])
+	if off < 0 || off+size > len(b.b) {
+		panic(fmt.Sprintf("bytes: cannot write %d bytes at offset %d of %d", size, off, len(b.b)))
+	}
+	putUint(b.b[off:off+size], byteOrder(ctx, args, 3), uint64(args[2].Int(ctx)))
+	return b
+}
+
+// Args:
+// 0 - The size of the integer, in bytes: 1, 2, 4 or 8
+// 1 - The integer, signed or unsigned
+// 2 - The byte order, "le" or "be" (optional, defaults to "le")
+// Returns:
+// The buffer.
+func (b *buffer) appendInt(ctx context.Context, args ...runtime.Val) runtime.Val {
+	runtime.ExpectAtLeastNArgs(2, args)
+	size := intSize(ctx, args[0])
+	p := make([]byte, size)
+	putUint(p, byteOrder(ctx, args, 2), uint64(args[1].Int(ctx)))
+	b.b = append(b.b, p...)
+	return b
+}
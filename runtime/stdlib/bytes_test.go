@@ -0,0 +1,181 @@
+package stdlib
+
+import (
+	"context"
+	"testing"
+
+	"github.com/saward/agora/runtime"
+)
+
+func TestBytesNew(t *testing.T) {
+	ctx := context.Background()
+	ktx := runtime.NewKtx(nil, nil)
+	bm := new(BytesMod)
+	bm.SetKtx(ktx)
+
+	arr := runtime.NewObject()
+	arr.Set(runtime.Number(0), runtime.Number(1))
+	arr.Set(runtime.Number(1), runtime.Number(255))
+	cases := []struct {
+		src []runtime.Val
+		exp string
+	}{
+		0: {exp: ""},
+		1: {src: []runtime.Val{runtime.Number(3)}, exp: "\x00\x00\x00"},
+		2: {src: []runtime.Val{runtime.String("abc")}, exp: "abc"},
+		3: {src: []runtime.Val{arr}, exp: "\x01\xff"},
+		4: {src: []runtime.Val{newBuffer(ktx, []byte("xy"))}, exp: "xy"},
+	}
+	for i, c := range cases {
+		b := bm.bytes_New(ctx, c.src...).(*buffer)
+		if string(b.b) != c.exp {
+			t.Errorf("[%d] - expected %q, got %q", i, c.exp, b.b)
+		}
+		if b.String(ctx) != c.exp {
+			t.Errorf("[%d] - expected string conversion %q, got %q", i, c.exp, b.String(ctx))
+		}
+	}
+
+	arr.Set(runtime.Number(2), runtime.Number(256))
+	if err := assertErr(func() { bm.bytes_New(ctx, arr) }); err == nil {
+		t.Errorf("expected error for an invalid byte value")
+	}
+	if err := assertErr(func() { bm.bytes_New(ctx, runtime.Bool(true)) }); err == nil {
+		t.Errorf("expected error for a bool")
+	}
+
+	if !bm.bytes_Equal(ctx, newBuffer(ktx, []byte("ab")), runtime.String("ab")).Bool(ctx) {
+		t.Errorf("expected buffer and string to be equal")
+	}
+	if bm.bytes_Equal(ctx, newBuffer(ktx, []byte("ab")), newBuffer(ktx, []byte("abc"))).Bool(ctx) {
+		t.Errorf("expected buffers not to be equal")
+	}
+}
+
+func TestBytesBuffer(t *testing.T) {
+	ctx := context.Background()
+	ktx := runtime.NewKtx(nil, nil)
+	b := newBuffer(ktx, []byte("abc"))
+
+	// Indexing
+	if v := b.Get(runtime.Number(1)); v != runtime.Number('b') {
+		t.Errorf("expected index 1 to be %d, got %v", 'b', v)
+	}
+	b.Set(runtime.Number(0), runtime.Number('A'))
+	b.Set(runtime.Number(3), runtime.Number('d'))
+	if string(b.b) != "Abcd" {
+		t.Errorf("expected Abcd, got %q", b.b)
+	}
+	if n := b.Len(ctx).Int(ctx); n != 4 {
+		t.Errorf("expected length 4, got %d", n)
+	}
+	keys := b.Keys(ctx).(runtime.Object)
+	if n := keys.Len(ctx).Int(ctx); n != 4 {
+		t.Errorf("expected 4 keys, got %d", n)
+	}
+	for i, idx := range []float64{-1, 4, 1.5} {
+		if err := assertErr(func() { b.Get(runtime.Number(idx)) }); err == nil {
+			t.Errorf("[%d] - expected error for index %v", i, idx)
+		}
+	}
+	if err := assertErr(func() { b.Set(runtime.Number(0), runtime.String("a")) }); err == nil {
+		t.Errorf("expected error for a string byte")
+	}
+
+	// Fields are still available
+	b.Set(runtime.String("Tag"), runtime.String("x"))
+	if v := b.Get(runtime.String("Tag")); v != runtime.String("x") {
+		t.Errorf("expected field Tag to be x, got %v", v)
+	}
+
+	// Slicing
+	s := b.Get(runtime.String("Slice")).(runtime.Func).Call(ctx, nil, runtime.Number(1), runtime.Number(3)).(*buffer)
+	if string(s.b) != "bc" {
+		t.Errorf("expected slice bc, got %q", s.b)
+	}
+	s.Set(runtime.Number(0), runtime.Number('B'))
+	if string(b.b) != "Abcd" {
+		t.Errorf("expected slice to be a copy, got %q", b.b)
+	}
+	str := b.Get(runtime.String("String")).(runtime.Func).Call(ctx, nil, runtime.Number(2))
+	if str != runtime.String("cd") {
+		t.Errorf("expected string cd, got %v", str)
+	}
+	if err := assertErr(func() {
+		b.Get(runtime.String("Slice")).(runtime.Func).Call(ctx, nil, runtime.Number(3), runtime.Number(1))
+	}); err == nil {
+		t.Errorf("expected error for inverted slice indices")
+	}
+
+	// Append
+	b.append(ctx, runtime.Number(0), runtime.String("ef"), newBuffer(ktx, []byte("g")))
+	if string(b.b) != "Abcd\x00efg" {
+		t.Errorf("expected Abcd\\x00efg, got %q", b.b)
+	}
+
+	// Comparison
+	cmp := ktx.Comparer
+	if cmp.Cmp(ctx, newBuffer(ktx, []byte("a")), newBuffer(ktx, []byte("b"))) != -1 {
+		t.Errorf("expected a < b")
+	}
+	if cmp.Cmp(ctx, newBuffer(ktx, []byte("ab")), newBuffer(ktx, []byte("ab"))) != 0 {
+		t.Errorf("expected ab == ab")
+	}
+}
+
+func TestBytesInts(t *testing.T) {
+	ctx := context.Background()
+	ktx := runtime.NewKtx(nil, nil)
+	b := newBuffer(ktx, nil)
+
+	b.appendInt(ctx, runtime.Number(2), runtime.Number(0x0102))
+	b.appendInt(ctx, runtime.Number(4), runtime.Number(-2), runtime.String("be"))
+	b.appendInt(ctx, runtime.Number(1), runtime.Number(200))
+	if exp := "\x02\x01\xff\xff\xff\xfe\xc8"; string(b.b) != exp {
+		t.Errorf("expected %q, got %q", exp, b.b)
+	}
+
+	cases := []struct {
+		args []runtime.Val
+		uns  bool
+		exp  float64
+	}{
+		0: {args: []runtime.Val{runtime.Number(0), runtime.Number(2)}, exp: 0x0102},
+		1: {args: []runtime.Val{runtime.Number(0), runtime.Number(2), runtime.String("be")}, exp: 0x0201},
+		2: {args: []runtime.Val{runtime.Number(2), runtime.Number(4), runtime.String("be")}, exp: -2},
+		3: {args: []runtime.Val{runtime.Number(2), runtime.Number(4), runtime.String("be")}, uns: true, exp: 0xfffffffe},
+		4: {args: []runtime.Val{runtime.Number(6), runtime.Number(1)}, exp: -56},
+		5: {args: []runtime.Val{runtime.Number(6), runtime.Number(1)}, uns: true, exp: 200},
+	}
+	for i, c := range cases {
+		var v runtime.Val
+		if c.uns {
+			v = b.readUint(ctx, c.args...)
+		} else {
+			v = b.readInt(ctx, c.args...)
+		}
+		if v != runtime.Number(c.exp) {
+			t.Errorf("[%d] - expected %v, got %v", i, c.exp, v)
+		}
+	}
+
+	b.writeInt(ctx, runtime.Number(0), runtime.Number(2), runtime.Number(0xabcd), runtime.String("be"))
+	if v := b.readUint(ctx, runtime.Number(0), runtime.Number(2), runtime.String("be")); v != runtime.Number(0xabcd) {
+		t.Errorf("expected %d, got %v", 0xabcd, v)
+	}
+
+	errs := [][]runtime.Val{
+		{runtime.Number(6), runtime.Number(2)},
+		{runtime.Number(0), runtime.Number(3)},
+		{runtime.Number(0), runtime.Number(2), runtime.String("xe")},
+		{runtime.Number(-1), runtime.Number(1)},
+	}
+	for i, args := range errs {
+		if err := assertErr(func() { b.readInt(ctx, args...) }); err == nil {
+			t.Errorf("[%d] - expected error", i)
+		}
+	}
+	if err := assertErr(func() { b.writeInt(ctx, runtime.Number(4), runtime.Number(4), runtime.Number(1)) }); err == nil {
+		t.Errorf("expected error for write past the end")
+	}
+}
@@ -0,0 +1,128 @@
+package stdlib
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/md5"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/subtle"
+	"encoding/hex"
+	"hash"
+
+	"github.com/saward/agora/runtime"
+)
+
+// The crypto module, as documented in
+// https://github.com/saward/agora/wiki/Standard-library
+type CryptoMod struct {
+	ktx *runtime.Kontext
+	ob  runtime.Object
+}
+
+// The hash functions, by name.
+var hashes = map[string]func() hash.Hash{
+	"md5":    md5.New,
+	"sha1":   sha1.New,
+	"sha256": sha256.New,
+	"sha512": sha512.New,
+}
+
+func (c *CryptoMod) ID() string {
+	return "crypto"
+}
+
+func (c *CryptoMod) Run(_ context.Context, _ ...runtime.Val) (v runtime.Val, err error) {
+	defer runtime.PanicToError(&err)
+	if c.ob == nil {
+		// Prepare the object
+		c.ob = runtime.NewObject()
+		c.ob.Set(runtime.String("MD5"), runtime.NewNativeFunc(c.ktx, "crypto.MD5", c.hashFunc("md5")))
+		c.ob.Set(runtime.String("SHA1"), runtime.NewNativeFunc(c.ktx, "crypto.SHA1", c.hashFunc("sha1")))
+		c.ob.Set(runtime.String("SHA256"), runtime.NewNativeFunc(c.ktx, "crypto.SHA256", c.hashFunc("sha256")))
+		c.ob.Set(runtime.String("SHA512"), runtime.NewNativeFunc(c.ktx, "crypto.SHA512", c.hashFunc("sha512")))
+		c.ob.Set(runtime.String("HMAC"), runtime.NewNativeFunc(c.ktx, "crypto.HMAC", c.crypto_HMAC))
+		c.ob.Set(runtime.String("RandomBytes"), runtime.NewNativeFunc(c.ktx, "crypto.RandomBytes", c.crypto_RandomBytes))
+		c.ob.Set(runtime.String("ConstantTimeCompare"), runtime.NewNativeFunc(c.ktx, "crypto.ConstantTimeCompare", c.crypto_ConstantTimeCompare))
+	}
+	return c.ob, nil
+}
+
+func (c *CryptoMod) SetKtx(ktx *runtime.Kontext) {
+	c.ktx = ktx
+}
+
+func (c *CryptoMod) Clone() runtime.NativeModule {
+	return new(CryptoMod)
+}
+
+// Returns the digest as a hex string, or as a buffer if the optional
+// argument at index i is true.
+func (c *CryptoMod) digest(ctx context.Context, sum []byte, args []runtime.Val, i int) runtime.Val {
+	if len(args) > i && args[i].Bool(ctx) {
+		return newBuffer(c.ktx, sum)
+	}
+	return runtime.String(hex.EncodeToString(sum))
+}
+
+// Returns the native func that computes the digest of the named hash
+// function.
+//
+// Args:
+// 0 - The string or buffer to hash
+// 1 - True to return the raw digest in a buffer (optional)
+// Returns:
+// The digest, as a hex string by default.
+func (c *CryptoMod) hashFunc(nm string) func(context.Context, ...runtime.Val) runtime.Val {
+	return func(ctx context.Context, args ...runtime.Val) runtime.Val {
+		runtime.ExpectAtLeastNArgs(1, args)
+		h := hashes[nm]()
+		h.Write(toBytes(ctx, args[0]))
+		return c.digest(ctx, h.Sum(nil), args, 1)
+	}
+}
+
+// Args:
+// 0 - The name of the hash function, "md5", "sha1", "sha256" or "sha512"
+// 1 - The key, a string or buffer
+// 2 - The message, a string or buffer
+// 3 - True to return the raw MAC in a buffer (optional)
+// Returns:
+// The MAC, as a hex string by default.
+func (c *CryptoMod) crypto_HMAC(ctx context.Context, args ...runtime.Val) runtime.Val {
+	runtime.ExpectAtLeastNArgs(3, args)
+	nm := args[0].String(ctx)
+	fn, ok := hashes[nm]
+	if !ok {
+		panic("crypto: unknown hash function " + nm)
+	}
+	h := hmac.New(fn, toBytes(ctx, args[1]))
+	h.Write(toBytes(ctx, args[2]))
+	return c.digest(ctx, h.Sum(nil), args, 3)
+}
+
+// Args:
+// 0 - The number of bytes
+// Returns:
+// A buffer of cryptographically secure random bytes.
+func (c *CryptoMod) crypto_RandomBytes(ctx context.Context, args ...runtime.Val) runtime.Val {
+	runtime.ExpectAtLeastNArgs(1, args)
+	b := make([]byte, int(args[0].Int(ctx)))
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return newBuffer(c.ktx, b)
+}
+
+// Args:
+// 0 - The first string or buffer
+// 1 - The second string or buffer
+// Returns:
+// True if both hold the same bytes. The time taken depends only on the
+// length of the values, not on their content.
+func (c *CryptoMod) crypto_ConstantTimeCompare(ctx context.Context, args ...runtime.Val) runtime.Val {
+	runtime.ExpectAtLeastNArgs(2, args)
+	return runtime.Bool(subtle.ConstantTimeCompare(toBytes(ctx, args[0]), toBytes(ctx, args[1])) == 1)
+}
@@ -0,0 +1,86 @@
+package stdlib
+
+import (
+	"context"
+	"testing"
+
+	"github.com/saward/agora/runtime"
+)
+
+func TestCryptoHashes(t *testing.T) {
+	ctx := context.Background()
+	ktx := runtime.NewKtx(nil, nil)
+	cm := new(CryptoMod)
+	cm.SetKtx(ktx)
+
+	cases := []struct {
+		nm  string
+		exp string
+	}{
+		0: {"md5", "900150983cd24fb0d6963f7d28e17f72"},
+		1: {"sha1", "a9993e364706816aba3e25717850c26c9cd0d89d"},
+		2: {"sha256", "ba7816bf8f01cfea414140de5dae2223b00361a396177a9cb410ff61f20015ad"},
+		3: {"sha512", "ddaf35a193617abacc417349ae20413112e6fa4e89a97ea20a9eeee64b55d39a2192992a274fc1a836ba3c23a3feebbd454d4423643ce80e2a9ac94fa54ca49f"},
+	}
+	for i, c := range cases {
+		fn := cm.hashFunc(c.nm)
+		if res := fn(ctx, runtime.String("abc")); res.String(ctx) != c.exp {
+			t.Errorf("[%d] - expected %s, got %s", i, c.exp, res.String(ctx))
+		}
+		if res := fn(ctx, newBuffer(ktx, []byte("abc"))); res.String(ctx) != c.exp {
+			t.Errorf("[%d] - expected %s for a buffer, got %s", i, c.exp, res.String(ctx))
+		}
+		raw, ok := fn(ctx, runtime.String("abc"), runtime.Bool(true)).(*buffer)
+		if !ok || len(raw.b) != len(c.exp)/2 {
+			t.Errorf("[%d] - expected raw digest buffer of %d bytes, got %v", i, len(c.exp)/2, raw)
+		}
+	}
+}
+
+func TestCryptoHMAC(t *testing.T) {
+	ctx := context.Background()
+	ktx := runtime.NewKtx(nil, nil)
+	cm := new(CryptoMod)
+	cm.SetKtx(ktx)
+
+	msg := runtime.String("The quick brown fox jumps over the lazy dog")
+	exp := "f7bc83f430538424b13298e6aa6fb143ef4d59a14946175997479dbc2d1a3cd8"
+	res := cm.crypto_HMAC(ctx, runtime.String("sha256"), runtime.String("key"), msg)
+	if res.String(ctx) != exp {
+		t.Errorf("expected %s, got %s", exp, res.String(ctx))
+	}
+	exp = "80070713463e7749b90c2dc24911e275"
+	res = cm.crypto_HMAC(ctx, runtime.String("md5"), runtime.String("key"), msg)
+	if res.String(ctx) != exp {
+		t.Errorf("expected %s, got %s", exp, res.String(ctx))
+	}
+	raw := cm.crypto_HMAC(ctx, runtime.String("sha1"), runtime.String("key"), msg, runtime.Bool(true))
+	if b, ok := raw.(*buffer); !ok || len(b.b) != 20 {
+		t.Errorf("expected raw MAC buffer of 20 bytes, got %v", raw)
+	}
+	if err := assertErr(func() { cm.crypto_HMAC(ctx, runtime.String("sha3"), runtime.String("key"), msg) }); err == nil {
+		t.Errorf("expected error for an unknown hash function")
+	}
+}
+
+func TestCryptoRandomCompare(t *testing.T) {
+	ctx := context.Background()
+	ktx := runtime.NewKtx(nil, nil)
+	cm := new(CryptoMod)
+	cm.SetKtx(ktx)
+
+	b1 := cm.crypto_RandomBytes(ctx, runtime.Number(16)).(*buffer)
+	b2 := cm.crypto_RandomBytes(ctx, runtime.Number(16)).(*buffer)
+	if len(b1.b) != 16 || len(b2.b) != 16 {
+		t.Errorf("expected 16 random bytes, got %d and %d", len(b1.b), len(b2.b))
+	}
+	if cm.crypto_ConstantTimeCompare(ctx, b1, b2).Bool(ctx) {
+		t.Errorf("expected random buffers to differ")
+	}
+	if !cm.crypto_ConstantTimeCompare(ctx, b1, runtime.String(b1.b)).Bool(ctx) {
+		t.Errorf("expected buffer and string of the same bytes to be equal")
+	}
+	if cm.crypto_ConstantTimeCompare(ctx, runtime.String("ab"), runtime.String("abc")).Bool(ctx) {
+		t.Errorf("expected strings of different lengths to differ")
+	}
+}
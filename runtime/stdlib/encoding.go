@@ -0,0 +1,226 @@
+package stdlib
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/csv"
+	"encoding/hex"
+	"io"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/saward/agora/runtime"
+)
+
+// The encoding module, as documented in
+// https://github.com/saward/agora/wiki/Standard-library
+type EncodingMod struct {
+	ktx *runtime.Kontext
+	ob  runtime.Object
+}
+
+func (e *EncodingMod) ID() string {
+	return "encoding"
+}
+
+func (e *EncodingMod) Run(_ context.Context, _ ...runtime.Val) (v runtime.Val, err error) {
+	defer runtime.PanicToError(&err)
+	if e.ob == nil {
+		// Prepare the object
+		e.ob = runtime.NewObject()
+		e.ob.Set(runtime.String("Base64Encode"), runtime.NewNativeFunc(e.ktx, "encoding.Base64Encode", e.encoding_Base64Encode))
+		e.ob.Set(runtime.String("Base64Decode"), runtime.NewNativeFunc(e.ktx, "encoding.Base64Decode", e.encoding_Base64Decode))
+		e.ob.Set(runtime.String("HexEncode"), runtime.NewNativeFunc(e.ktx, "encoding.HexEncode", e.encoding_HexEncode))
+		e.ob.Set(runtime.String("HexDecode"), runtime.NewNativeFunc(e.ktx, "encoding.HexDecode", e.encoding_HexDecode))
+		e.ob.Set(runtime.String("CSVReader"), runtime.NewNativeFunc(e.ktx, "encoding.CSVReader", e.encoding_CSVReader))
+		e.ob.Set(runtime.String("CSVWriter"), runtime.NewNativeFunc(e.ktx, "encoding.CSVWriter", e.encoding_CSVWriter))
+	}
+	return e.ob, nil
+}
+
+func (e *EncodingMod) SetKtx(c *runtime.Kontext) {
+	e.ktx = c
+}
+
+func (e *EncodingMod) Clone() runtime.NativeModule {
+	return new(EncodingMod)
+}
+
+// Returns the base64 encoding to use, the URL-safe one if the optional
+// argument at index i is true.
+func base64Encoding(ctx context.Context, args []runtime.Val, i int) *base64.Encoding {
+	if len(args) > i && args[i].Bool(ctx) {
+		return base64.URLEncoding
+	}
+	return base64.StdEncoding
+}
+
+// Args:
+// 0 - The string or buffer to encode
+// 1 - True to use the URL-safe alphabet (optional)
+// Returns:
+// The base64 encoding of the value.
+func (e *EncodingMod) encoding_Base64Encode(ctx context.Context, args ...runtime.Val) runtime.Val {
+	runtime.ExpectAtLeastNArgs(1, args)
+	return runtime.String(base64Encoding(ctx, args, 1).EncodeToString(appendBytes(ctx, nil, args[0])))
+}
+
+// Args:
+// 0 - The base64 string to decode
+// 1 - True to use the URL-safe alphabet (optional)
+// Returns:
+// The decoded string.
+func (e *EncodingMod) encoding_Base64Decode(ctx context.Context, args ...runtime.Val) runtime.Val {
+	runtime.ExpectAtLeastNArgs(1, args)
+	b, err := base64Encoding(ctx, args, 1).DecodeString(args[0].String(ctx))
+	if err != nil {
+		panic(err)
+	}
+	return runtime.String(b)
+}
+
+// Args:
+// 0 - The string or buffer to encode
+// Returns:
+// The hexadecimal encoding of the value, in lower case.
+func (e *EncodingMod) encoding_HexEncode(ctx context.Context, args ...runtime.Val) runtime.Val {
+	runtime.ExpectAtLeastNArgs(1, args)
+	return runtime.String(hex.EncodeToString(appendBytes(ctx, nil, args[0])))
+}
+
+// Args:
+// 0 - The hexadecimal string to decode
+// Returns:
+// The decoded string.
+func (e *EncodingMod) encoding_HexDecode(ctx context.Context, args ...runtime.Val) runtime.Val {
+	runtime.ExpectAtLeastNArgs(1, args)
+	b, err := hex.DecodeString(args[0].String(ctx))
+	if err != nil {
+		panic(err)
+	}
+	return runtime.String(b)
+}
+
+// Returns the field separator provided as optional argument at index i,
+// defaulting to a comma.
+func csvComma(ctx context.Context, args []runtime.Val, i int) rune {
+	if len(args) <= i {
+		return ','
+	}
+	s := args[i].String(ctx)
+	r, n := utf8.DecodeRuneInString(s)
+	if n == 0 || n != len(s) {
+		panic("encoding: invalid CSV separator " + s)
+	}
+	return r
+}
+
+// Args:
+// 0 - The source, an os file or a string
+// 1 - The field separator (optional, defaults to ",")
+// Returns:
+// The CSV reader, see the documentation for its methods.
+func (e *EncodingMod) encoding_CSVReader(ctx context.Context, args ...runtime.Val) runtime.Val {
+	runtime.ExpectAtLeastNArgs(1, args)
+	var r io.Reader
+	if f, ok := args[0].(*file); ok {
+		r = f.reader()
+	} else {
+		r = strings.NewReader(args[0].String(ctx))
+	}
+	cr := csv.NewReader(r)
+	cr.Comma = csvComma(ctx, args, 1)
+	cr.FieldsPerRecord = -1
+
+	ob := runtime.NewObject()
+	read := func(_ context.Context, args ...runtime.Val) runtime.Val {
+		row, err := cr.Read()
+		if err == io.EOF {
+			return runtime.Nil
+		}
+		if err != nil {
+			panic(err)
+		}
+		return newRow(row)
+	}
+	ob.Set(runtime.String("__next"), runtime.NewNativeFunc(e.ktx, "encoding.CSVReader.__next", read))
+	ob.Set(runtime.String("Read"), runtime.NewNativeFunc(e.ktx, "encoding.CSVReader.Read", read))
+	ob.Set(runtime.String("ReadAll"), runtime.NewNativeFunc(e.ktx, "encoding.CSVReader.ReadAll", func(_ context.Context, args ...runtime.Val) runtime.Val {
+		rows, err := cr.ReadAll()
+		if err != nil {
+			panic(err)
+		}
+		res := runtime.NewObject()
+		for i, row := range rows {
+			res.Set(runtime.Number(i), newRow(row))
+		}
+		return res
+	}))
+	return ob
+}
+
+// Returns the array-like object holding the fields of the CSV row.
+func newRow(row []string) runtime.Object {
+	ob := runtime.NewObject()
+	for i, fld := range row {
+		ob.Set(runtime.Number(i), runtime.String(fld))
+	}
+	return ob
+}
+
+// Args:
+// 0 - The destination, an os file
+// 1 - The field separator (optional, defaults to ",")
+// Returns:
+// The CSV writer, see the documentation for its methods.
+func (e *EncodingMod) encoding_CSVWriter(ctx context.Context, args ...runtime.Val) runtime.Val {
+	runtime.ExpectAtLeastNArgs(1, args)
+	f, ok := args[0].(*file)
+	if !ok {
+		panic(runtime.NewTypeError(runtime.Type(args[0]), "", "encoding.CSVWriter"))
+	}
+	cw := csv.NewWriter(f.f)
+	cw.Comma = csvComma(ctx, args, 1)
+
+	write := func(ctx context.Context, rows ...runtime.Val) runtime.Val {
+		f.unread()
+		for _, row := range rows {
+			ob, ok := row.(runtime.Object)
+			if !ok {
+				panic(runtime.NewTypeError(runtime.Type(row), "", "encoding.CSVWriter row"))
+			}
+			flds := make([]string, int(ob.Len(ctx).Int(ctx)))
+			for i := range flds {
+				if v := ob.Get(runtime.Number(i)); v != runtime.Nil {
+					flds[i] = v.String(ctx)
+				}
+			}
+			if err := cw.Write(flds); err != nil {
+				panic(err)
+			}
+		}
+		cw.Flush()
+		if err := cw.Error(); err != nil {
+			panic(err)
+		}
+		return runtime.Nil
+	}
+	ob := runtime.NewObject()
+	ob.Set(runtime.String("Write"), runtime.NewNativeFunc(e.ktx, "encoding.CSVWriter.Write", func(ctx context.Context, args ...runtime.Val) runtime.Val {
+		runtime.ExpectAtLeastNArgs(1, args)
+		return write(ctx, args[0])
+	}))
+	ob.Set(runtime.String("WriteAll"), runtime.NewNativeFunc(e.ktx, "encoding.CSVWriter.WriteAll", func(ctx context.Context, args ...runtime.Val) runtime.Val {
+		runtime.ExpectAtLeastNArgs(1, args)
+		rows, ok := args[0].(runtime.Object)
+		if !ok {
+			panic(runtime.NewTypeError(runtime.Type(args[0]), "", "encoding.CSVWriter.WriteAll"))
+		}
+		vals := make([]runtime.Val, int(rows.Len(ctx).Int(ctx)))
+		for i := range vals {
+			vals[i] = rows.Get(runtime.Number(i))
+		}
+		return write(ctx, vals...)
+	}))
+	return ob
+}
@@ -0,0 +1,124 @@
+package stdlib
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/saward/agora/runtime"
+)
+
+func TestEncodingBase64Hex(t *testing.T) {
+	ctx := context.Background()
+	ktx := runtime.NewKtx(nil, nil)
+	em := new(EncodingMod)
+	em.SetKtx(ktx)
+
+	cases := []struct {
+		fn   func(context.Context, ...runtime.Val) runtime.Val
+		args []runtime.Val
+		exp  string
+	}{
+		0: {fn: em.encoding_Base64Encode, args: []runtime.Val{runtime.String("hello?>")}, exp: "aGVsbG8/Pg=="},
+		1: {fn: em.encoding_Base64Encode, args: []runtime.Val{runtime.String("hello?>"), runtime.Bool(true)}, exp: "aGVsbG8_Pg=="},
+		2: {fn: em.encoding_Base64Encode, args: []runtime.Val{newBuffer(ktx, []byte{0, 255})}, exp: "AP8="},
+		3: {fn: em.encoding_Base64Decode, args: []runtime.Val{runtime.String("aGVsbG8/Pg==")}, exp: "hello?>"},
+		4: {fn: em.encoding_Base64Decode, args: []runtime.Val{runtime.String("aGVsbG8_Pg=="), runtime.Bool(true)}, exp: "hello?>"},
+		5: {fn: em.encoding_HexEncode, args: []runtime.Val{runtime.String("hi\n")}, exp: "68690a"},
+		6: {fn: em.encoding_HexEncode, args: []runtime.Val{newBuffer(ktx, []byte{0, 255})}, exp: "00ff"},
+		7: {fn: em.encoding_HexDecode, args: []runtime.Val{runtime.String("68690A")}, exp: "hi\n"},
+	}
+	for i, c := range cases {
+		if res := c.fn(ctx, c.args...); res.String(ctx) != c.exp {
+			t.Errorf("[%d] - expected %q, got %q", i, c.exp, res.String(ctx))
+		}
+	}
+
+	if err := assertErr(func() { em.encoding_Base64Decode(ctx, runtime.String("a!")) }); err == nil {
+		t.Errorf("expected error for invalid base64")
+	}
+	if err := assertErr(func() { em.encoding_HexDecode(ctx, runtime.String("abc")) }); err == nil {
+		t.Errorf("expected error for invalid hex")
+	}
+}
+
+func TestEncodingCSV(t *testing.T) {
+	ctx := context.Background()
+	ktx := runtime.NewKtx(nil, nil)
+	em := new(EncodingMod)
+	em.SetKtx(ktx)
+	om := new(OsMod)
+	om.SetKtx(ktx)
+
+	f, err := ioutil.TempFile("", "agora-csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	of := om.newFile(f)
+
+	// Write
+	w := em.encoding_CSVWriter(ctx, of, runtime.String(";")).(runtime.Object)
+	w.Get(runtime.String("Write")).(runtime.Func).Call(ctx, nil, newRow([]string{"a", "b;c"}))
+	rows := runtime.NewObject()
+	rows.Set(runtime.Number(0), newRow([]string{"1", "say \"hi\""}))
+	row := runtime.NewObject()
+	row.Set(runtime.Number(0), runtime.Number(2))
+	row.Set(runtime.Number(1), runtime.Bool(true))
+	rows.Set(runtime.Number(1), row)
+	w.Get(runtime.String("WriteAll")).(runtime.Func).Call(ctx, nil, rows)
+	b, err := ioutil.ReadFile(f.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exp := "a;\"b;c\"\n1;\"say \"\"hi\"\"\"\n2;true\n"; string(b) != exp {
+		t.Errorf("expected %q, got %q", exp, string(b))
+	}
+
+	// Read from the file, after a line read from the file itself
+	of.seek(ctx)
+	if l := of.readLine(ctx); l.String(ctx) != "a;\"b;c\"" {
+		t.Errorf("expected the first line, got %v", l)
+	}
+	r := em.encoding_CSVReader(ctx, of, runtime.String(";")).(runtime.Object)
+	read := r.Get(runtime.String("Read")).(runtime.Func)
+	exp := [][]string{{"1", "say \"hi\""}, {"2", "true"}}
+	for i, e := range exp {
+		v := read.Call(ctx, nil)
+		ob, ok := v.(runtime.Object)
+		if !ok {
+			t.Fatalf("[%d] - expected a row, got %v", i, v)
+		}
+		if n := ob.Len(ctx).Int(ctx); n != int64(len(e)) {
+			t.Errorf("[%d] - expected %d fields, got %d", i, len(e), n)
+		}
+		for j, fld := range e {
+			if got := ob.Get(runtime.Number(j)).String(ctx); got != fld {
+				t.Errorf("[%d] - expected field %d to be %q, got %q", i, j, fld, got)
+			}
+		}
+	}
+	if v := read.Call(ctx, nil); v != runtime.Nil {
+		t.Errorf("expected nil at the end, got %v", v)
+	}
+
+	// Read all from a string, with a variable number of fields
+	r = em.encoding_CSVReader(ctx, runtime.String("a,b\nc\n")).(runtime.Object)
+	all := r.Get(runtime.String("ReadAll")).(runtime.Func).Call(ctx, nil).(runtime.Object)
+	if n := all.Len(ctx).Int(ctx); n != 2 {
+		t.Errorf("expected 2 rows, got %d", n)
+	}
+
+	// Errors
+	r = em.encoding_CSVReader(ctx, runtime.String("a,\"b\n")).(runtime.Object)
+	if err := assertErr(func() { r.Get(runtime.String("Read")).(runtime.Func).Call(ctx, nil) }); err == nil {
+		t.Errorf("expected error for invalid CSV")
+	}
+	if err := assertErr(func() { em.encoding_CSVWriter(ctx, runtime.String("x")) }); err == nil {
+		t.Errorf("expected error for a string destination")
+	}
+	if err := assertErr(func() { em.encoding_CSVReader(ctx, runtime.String("x"), runtime.String(";;")) }); err == nil {
+		t.Errorf("expected error for an invalid separator")
+	}
+}
@@ -2,6 +2,8 @@ package stdlib
 
 import (
 	"context"
+	"errors"
+	"os"
 	"path/filepath"
 
 	"github.com/saward/agora/runtime"
@@ -10,8 +12,10 @@ import (
 // The filepath module, as documented in
 // https://github.com/saward/agora/wiki/Standard-library
 type FilepathMod struct {
-	ktx *runtime.Kontext
-	ob  runtime.Object
+	ktx     *runtime.Kontext
+	ob      runtime.Object
+	skipDir runtime.Object
+	skipAll runtime.Object
 }
 
 func (fp *FilepathMod) ID() string {
@@ -29,6 +33,16 @@ func (fp *FilepathMod) Run(_ context.Context, _ ...runtime.Val) (v runtime.Val,
 		fp.ob.Set(runtime.String("Ext"), runtime.NewNativeFunc(fp.ktx, "filepath.Ext", fp.filepath_Ext))
 		fp.ob.Set(runtime.String("IsAbs"), runtime.NewNativeFunc(fp.ktx, "filepath.IsAbs", fp.filepath_IsAbs))
 		fp.ob.Set(runtime.String("Join"), runtime.NewNativeFunc(fp.ktx, "filepath.Join", fp.filepath_Join))
+		fp.ob.Set(runtime.String("Clean"), runtime.NewNativeFunc(fp.ktx, "filepath.Clean", fp.filepath_Clean))
+		fp.ob.Set(runtime.String("Glob"), runtime.NewNativeFunc(fp.ktx, "filepath.Glob", fp.filepath_Glob))
+		fp.ob.Set(runtime.String("Match"), runtime.NewNativeFunc(fp.ktx, "filepath.Match", fp.filepath_Match))
+		fp.ob.Set(runtime.String("Rel"), runtime.NewNativeFunc(fp.ktx, "filepath.Rel", fp.filepath_Rel))
+		fp.ob.Set(runtime.String("Split"), runtime.NewNativeFunc(fp.ktx, "filepath.Split", fp.filepath_Split))
+		fp.ob.Set(runtime.String("Walk"), runtime.NewNativeFunc(fp.ktx, "filepath.Walk", fp.filepath_Walk))
+		fp.skipDir = fp.newSentinel("SkipDir")
+		fp.skipAll = fp.newSentinel("SkipAll")
+		fp.ob.Set(runtime.String("SkipDir"), fp.skipDir)
+		fp.ob.Set(runtime.String("SkipAll"), fp.skipAll)
 	}
 	return fp.ob, nil
 }
@@ -37,6 +51,10 @@ func (fp *FilepathMod) SetKtx(c *runtime.Kontext) {
 	fp.ktx = c
 }
 
+func (fp *FilepathMod) Clone() runtime.NativeModule {
+	return new(FilepathMod)
+}
+
 func (fp *FilepathMod) filepath_Abs(ctx context.Context, args ...runtime.Val) runtime.Val {
 	runtime.ExpectAtLeastNArgs(1, args)
 	s, e := filepath.Abs(args[0].String(ctx))
@@ -71,3 +89,115 @@ func (fp *FilepathMod) filepath_Join(ctx context.Context, args ...runtime.Val) r
 	s := toString(ctx, args)
 	return runtime.String(filepath.Join(s...))
 }
+
+// Returns the unique object returned by a Walk func to control the walk,
+// whose string conversion is nm.
+func (fp *FilepathMod) newSentinel(nm string) runtime.Object {
+	ob := runtime.NewObject()
+	ob.Set(runtime.String("__string"), runtime.NewNativeFunc(fp.ktx, "filepath."+nm+".__string", func(_ context.Context, args ...runtime.Val) runtime.Val {
+		return runtime.String(nm)
+	}))
+	return ob
+}
+
+func (fp *FilepathMod) filepath_Clean(ctx context.Context, args ...runtime.Val) runtime.Val {
+	runtime.ExpectAtLeastNArgs(1, args)
+	return runtime.String(filepath.Clean(args[0].String(ctx)))
+}
+
+// Args:
+// 0 - The pattern, as defined by Match
+// Returns:
+// The array-like object holding the paths of the files that match the
+// pattern, sorted.
+func (fp *FilepathMod) filepath_Glob(ctx context.Context, args ...runtime.Val) runtime.Val {
+	runtime.ExpectAtLeastNArgs(1, args)
+	ms, e := filepath.Glob(args[0].String(ctx))
+	if e != nil {
+		panic(e)
+	}
+	ob := runtime.NewObject()
+	for i, m := range ms {
+		ob.Set(runtime.Number(i), runtime.String(m))
+	}
+	return ob
+}
+
+// Args:
+// 0 - The shell pattern, e.g. "*.agora"
+// 1 - The name to match
+// Returns:
+// True if the whole name matches the pattern, it panics if the pattern is
+// malformed.
+func (fp *FilepathMod) filepath_Match(ctx context.Context, args ...runtime.Val) runtime.Val {
+	runtime.ExpectAtLeastNArgs(2, args)
+	ok, e := filepath.Match(args[0].String(ctx), args[1].String(ctx))
+	if e != nil {
+		panic(e)
+	}
+	return runtime.Bool(ok)
+}
+
+// Args:
+// 0 - The base path
+// 1 - The target path
+// Returns:
+// The path of the target relative to the base, it panics if it cannot be
+// made relative.
+func (fp *FilepathMod) filepath_Rel(ctx context.Context, args ...runtime.Val) runtime.Val {
+	runtime.ExpectAtLeastNArgs(2, args)
+	s, e := filepath.Rel(args[0].String(ctx), args[1].String(ctx))
+	if e != nil {
+		panic(e)
+	}
+	return runtime.String(s)
+}
+
+// Args:
+// 0 - The path
+// Returns:
+// An object with the Dir and File fields, the path being split after its
+// last separator.
+func (fp *FilepathMod) filepath_Split(ctx context.Context, args ...runtime.Val) runtime.Val {
+	runtime.ExpectAtLeastNArgs(1, args)
+	dir, file := filepath.Split(args[0].String(ctx))
+	ob := runtime.NewObject()
+	ob.Set(runtime.String("Dir"), runtime.String(dir))
+	ob.Set(runtime.String("File"), runtime.String(file))
+	return ob
+}
+
+// Args:
+// 0 - The root of the tree to walk
+// 1 - The func called with the path and the file info object of each file
+// and directory of the tree, in lexical order. It returns SkipDir to skip
+// the directory, or the remaining files of the directory if called for a
+// file, and SkipAll to stop the walk.
+// Returns:
+// Nil, it panics if a file cannot be read.
+func (fp *FilepathMod) filepath_Walk(ctx context.Context, args ...runtime.Val) runtime.Val {
+	runtime.ExpectAtLeastNArgs(2, args)
+	fn, ok := args[1].(runtime.Func)
+	if !ok {
+		panic(runtime.NewTypeError(runtime.Type(args[1]), "", "filepath.Walk"))
+	}
+	e := filepath.Walk(args[0].String(ctx), func(p string, fi os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		switch fn.Call(ctx, nil, runtime.String(p), createFileInfo(fp.ktx, p, fi)) {
+		case fp.skipDir:
+			return filepath.SkipDir
+		case fp.skipAll:
+			return errSkipAll
+		}
+		return nil
+	})
+	if e != nil && e != errSkipAll {
+		panic(e)
+	}
+	return runtime.Nil
+}
+
+// The error returned to stop the walk.
+var errSkipAll = errors.New("skip all")
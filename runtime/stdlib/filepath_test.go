@@ -2,7 +2,10 @@ package stdlib
 
 import (
 	"context"
+	"io/ioutil"
+	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/saward/agora/runtime"
@@ -78,3 +81,95 @@ func TestFilepathJoin(t *testing.T) {
 		t.Errorf("expected '%s', got '%s'", exp, ret.String(ctx))
 	}
 }
+
+func TestFilepathCleanSplitRelMatch(t *testing.T) {
+	ctx := context.Background()
+	ktx := runtime.NewKtx(nil, nil)
+	fm := new(FilepathMod)
+	fm.SetKtx(ktx)
+	if s := fm.filepath_Clean(ctx, runtime.String("a/./b/../c//")).String(ctx); s != "a/c" {
+		t.Errorf("expected 'a/c', got '%s'", s)
+	}
+	ob := fm.filepath_Split(ctx, runtime.String("a/b/c.txt")).(runtime.Object)
+	if d, f := ob.Get(runtime.String("Dir")).String(ctx), ob.Get(runtime.String("File")).String(ctx); d != "a/b/" || f != "c.txt" {
+		t.Errorf("expected 'a/b/' and 'c.txt', got '%s' and '%s'", d, f)
+	}
+	if s := fm.filepath_Rel(ctx, runtime.String("/a/b"), runtime.String("/a/c/d")).String(ctx); s != "../c/d" {
+		t.Errorf("expected '../c/d', got '%s'", s)
+	}
+	if err := assertErr(func() { fm.filepath_Rel(ctx, runtime.String("/a"), runtime.String("b")) }); err == nil {
+		t.Errorf("expected error for rel of a relative path to an absolute one")
+	}
+	if ok := fm.filepath_Match(ctx, runtime.String("*.agora"), runtime.String("x.agora")); ok != runtime.Bool(true) {
+		t.Errorf("expected match")
+	}
+	if ok := fm.filepath_Match(ctx, runtime.String("*.agora"), runtime.String("d/x.agora")); ok != runtime.Bool(false) {
+		t.Errorf("expected no match across separators")
+	}
+	if err := assertErr(func() { fm.filepath_Match(ctx, runtime.String("[x"), runtime.String("x")) }); err == nil {
+		t.Errorf("expected error for malformed pattern")
+	}
+}
+
+func TestFilepathGlobWalk(t *testing.T) {
+	ctx := context.Background()
+	ktx := runtime.NewKtx(nil, nil)
+	fm := new(FilepathMod)
+	fm.SetKtx(ktx)
+	if _, err := fm.Run(ctx); err != nil {
+		t.Fatal(err)
+	}
+	dir, err := ioutil.TempDir("", "agora-walk")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	for _, p := range []string{"a.txt", "b.agora", "skip/c.txt", "sub/d.agora", "sub/e.txt"} {
+		p = filepath.Join(dir, p)
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(p, []byte(p), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	ob := fm.filepath_Glob(ctx, runtime.String(filepath.Join(dir, "*", "*.agora"))).(runtime.Object)
+	if n := ob.Len(ctx).Int(ctx); n != 1 {
+		t.Fatalf("expected 1 match, got %d", n)
+	}
+	if s := ob.Get(runtime.Number(0)).String(ctx); s != filepath.Join(dir, "sub", "d.agora") {
+		t.Errorf("expected sub/d.agora, got %s", s)
+	}
+
+	walk := func(stop string) []string {
+		var got []string
+		fn := runtime.NewNativeFunc(ktx, "", func(ctx context.Context, args ...runtime.Val) runtime.Val {
+			rel, _ := filepath.Rel(dir, args[0].String(ctx))
+			info := args[1].(runtime.Object)
+			if info.Get(runtime.String("IsDir")).Bool(ctx) {
+				rel += "/"
+			}
+			got = append(got, rel)
+			switch rel {
+			case "skip/":
+				return fm.skipDir
+			case stop:
+				return fm.skipAll
+			}
+			return runtime.Nil
+		})
+		fm.filepath_Walk(ctx, runtime.String(dir), fn)
+		return got
+	}
+	exp := []string{"./", "a.txt", "b.agora", "skip/", "sub/", "sub/d.agora", "sub/e.txt"}
+	if got := walk(""); strings.Join(got, ",") != strings.Join(exp, ",") {
+		t.Errorf("expected %v, got %v", exp, got)
+	}
+	if got := walk("b.agora"); strings.Join(got, ",") != strings.Join(exp[:3], ",") {
+		t.Errorf("expected %v, got %v", exp[:3], got)
+	}
+	if err := assertErr(func() { fm.filepath_Walk(ctx, runtime.String(filepath.Join(dir, "none")), runtime.Nil) }); err == nil {
+		t.Errorf("expected error for a non-func")
+	}
+}
@@ -2,12 +2,35 @@ package stdlib
 
 import (
 	"bufio"
+	"bytes"
 	"context"
 	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"unicode/utf8"
 
 	"github.com/saward/agora/runtime"
 )
 
+// Error raised when the arguments of a formatting function do not match its
+// format string.
+type FormatError string
+
+// Error interface implementation.
+func (e FormatError) Error() string {
+	return string(e)
+}
+
+// Create a new FormatError raised by the function fn for the directive dir
+// of the format string.
+func NewFormatError(fn, dir, msg string) FormatError {
+	if dir == "" {
+		return FormatError(fmt.Sprintf("%s: %s", fn, msg))
+	}
+	return FormatError(fmt.Sprintf("%s: %s: %s", fn, dir, msg))
+}
+
 // The fmt module, as documented in
 // https://github.com/saward/agora/wiki/Standard-library
 type FmtMod struct {
@@ -26,6 +49,9 @@ func (f *FmtMod) Run(_ context.Context, _ ...runtime.Val) (v runtime.Val, err er
 		f.ob = runtime.NewObject()
 		f.ob.Set(runtime.String("Print"), runtime.NewNativeFunc(f.ktx, "fmt.Print", f.fmt_Print))
 		f.ob.Set(runtime.String("Println"), runtime.NewNativeFunc(f.ktx, "fmt.Println", f.fmt_Println))
+		f.ob.Set(runtime.String("Printf"), runtime.NewNativeFunc(f.ktx, "fmt.Printf", f.fmt_Printf))
+		f.ob.Set(runtime.String("Sprintf"), runtime.NewNativeFunc(f.ktx, "fmt.Sprintf", f.fmt_Sprintf))
+		f.ob.Set(runtime.String("Fprintf"), runtime.NewNativeFunc(f.ktx, "fmt.Fprintf", f.fmt_Fprintf))
 		f.ob.Set(runtime.String("Scanln"), runtime.NewNativeFunc(f.ktx, "fmt.Scanln", f.fmt_Scanln))
 		f.ob.Set(runtime.String("Scanint"), runtime.NewNativeFunc(f.ktx, "fmt.Scanint", f.fmt_Scanint))
 	}
@@ -36,6 +62,10 @@ func (f *FmtMod) SetKtx(c *runtime.Kontext) {
 	f.ktx = c
 }
 
+func (f *FmtMod) Clone() runtime.NativeModule {
+	return new(FmtMod)
+}
+
 func toStringIface(ctx context.Context, args []runtime.Val) []interface{} {
 	var ifs []interface{}
 
@@ -66,6 +96,162 @@ func (f *FmtMod) fmt_Println(ctx context.Context, args ...runtime.Val) runtime.V
 	return runtime.Number(n)
 }
 
+// Args:
+// 0 - The format string
+// 1..n - The values to format
+// Returns:
+// The number of bytes written to stdout
+func (f *FmtMod) fmt_Printf(ctx context.Context, args ...runtime.Val) runtime.Val {
+	runtime.ExpectAtLeastNArgs(1, args)
+	n, err := io.WriteString(f.ktx.Stdout, sprintf(ctx, "fmt.Printf", args[0].String(ctx), args[1:]))
+	if err != nil {
+		panic(err)
+	}
+	return runtime.Number(n)
+}
+
+// Args:
+// 0 - The format string
+// 1..n - The values to format
+// Returns:
+// The formatted string
+func (f *FmtMod) fmt_Sprintf(ctx context.Context, args ...runtime.Val) runtime.Val {
+	runtime.ExpectAtLeastNArgs(1, args)
+	return runtime.String(sprintf(ctx, "fmt.Sprintf", args[0].String(ctx), args[1:]))
+}
+
+// Args:
+// 0 - The destination, a file of the os module or an object with a Write
+// method
+// 1 - The format string
+// 2..n - The values to format
+// Returns:
+// The number of bytes written
+func (f *FmtMod) fmt_Fprintf(ctx context.Context, args ...runtime.Val) runtime.Val {
+	runtime.ExpectAtLeastNArgs(2, args)
+	s := sprintf(ctx, "fmt.Fprintf", args[1].String(ctx), args[2:])
+	switch w := args[0].(type) {
+	case *file:
+		return w.write(ctx, runtime.String(s))
+	case runtime.Object:
+		if fn, ok := w.Get(runtime.String("Write")).(runtime.Func); ok {
+			return fn.Call(ctx, w, runtime.String(s))
+		}
+	}
+	panic(NewFormatError("fmt.Fprintf", "", "cannot write to a value of type "+runtime.Type(args[0])))
+}
+
+// Returns the values formatted according to the format string, for the
+// function fn. The verbs and flags are those of Go's fmt package, with the
+// value converted according to the verb:
+//
+// %d, %b, %o, %c, %U: the Int conversion
+// %x, %X: the string itself for a string, the Int conversion otherwise
+// %e, %E, %f, %F, %g, %G: the Float conversion
+// %s, %v, %q: the String conversion, %q quoting it
+// %t: the Bool conversion
+// %T: the type of the value
+//
+// The width and precision may be * to take them from the arguments.
+func sprintf(ctx context.Context, fn, format string, args []runtime.Val) string {
+	var buf bytes.Buffer
+	argi := 0
+	next := func(dir string) runtime.Val {
+		if argi >= len(args) {
+			panic(NewFormatError(fn, dir, "missing argument"))
+		}
+		argi++
+		return args[argi-1]
+	}
+	for i := 0; i < len(format); {
+		if format[i] != '%' {
+			buf.WriteByte(format[i])
+			i++
+			continue
+		}
+		start := i
+		i++
+		for i < len(format) && strings.IndexByte("+-# 0", format[i]) >= 0 {
+			i++
+		}
+		spec := format[start:i]
+		// Width and precision
+		for _, prec := range []bool{false, true} {
+			if prec {
+				if i >= len(format) || format[i] != '.' {
+					break
+				}
+				spec += "."
+				i++
+			}
+			if i < len(format) && format[i] == '*' {
+				i++
+				spec += strconv.Itoa(int(toArg(ctx, fn, format[start:i], next(format[start:i]), 'd').(int64)))
+				continue
+			}
+			j := i
+			for i < len(format) && '0' <= format[i] && format[i] <= '9' {
+				i++
+			}
+			spec += format[j:i]
+		}
+		if i >= len(format) {
+			panic(NewFormatError(fn, format[start:], "missing verb"))
+		}
+		verb, sz := utf8.DecodeRuneInString(format[i:])
+		i += sz
+		dir := format[start:i]
+		if verb == '%' {
+			buf.WriteByte('%')
+			continue
+		}
+		arg := toArg(ctx, fn, dir, next(dir), verb)
+		if verb == 'T' {
+			verb = 's'
+		}
+		fmt.Fprintf(&buf, spec+string(verb), arg)
+	}
+	if argi < len(args) {
+		panic(NewFormatError(fn, "", fmt.Sprintf("too many arguments: %d for %d verbs", len(args), argi)))
+	}
+	return buf.String()
+}
+
+// Returns the Go value to format with the verb for the agora value v. The
+// failed conversions are raised as FormatErrors.
+func toArg(ctx context.Context, fn, dir string, v runtime.Val, verb rune) (arg interface{}) {
+	defer func() {
+		if e := recover(); e != nil {
+			if _, ok := e.(FormatError); ok {
+				panic(e)
+			}
+			desc := runtime.Type(v)
+			if s, ok := v.(runtime.String); ok {
+				desc += " " + strconv.Quote(string(s))
+			}
+			panic(NewFormatError(fn, dir, fmt.Sprintf("cannot format %s: %v", desc, e)))
+		}
+	}()
+	switch verb {
+	case 'd', 'b', 'o', 'c', 'U':
+		return v.Int(ctx)
+	case 'x', 'X':
+		if s, ok := v.(runtime.String); ok {
+			return string(s)
+		}
+		return v.Int(ctx)
+	case 'e', 'E', 'f', 'F', 'g', 'G':
+		return v.Float(ctx)
+	case 's', 'v', 'q':
+		return v.String(ctx)
+	case 't':
+		return v.Bool(ctx)
+	case 'T':
+		return runtime.Type(v)
+	}
+	panic(NewFormatError(fn, dir, "unknown verb"))
+}
+
 func (f *FmtMod) fmt_Scanln(ctx context.Context, args ...runtime.Val) runtime.Val {
 	var (
 		b, l []byte
@@ -3,6 +3,8 @@ package stdlib
 import (
 	"bytes"
 	"context"
+	"io/ioutil"
+	"os"
 	"strings"
 	"testing"
 
@@ -103,3 +105,125 @@ func TestFmtScanint(t *testing.T) {
 		t.Errorf("expected 12, got %d", ret.Int(ctx))
 	}
 }
+
+func TestFmtSprintf(t *testing.T) {
+	ctx := context.Background()
+	ktx := runtime.NewKtx(nil, nil)
+	fm := new(FmtMod)
+	fm.SetKtx(ktx)
+
+	ob := runtime.NewObject()
+	ob.Set(runtime.String("__string"), runtime.NewNativeFunc(ktx, "", func(_ context.Context, args ...runtime.Val) runtime.Val {
+		return runtime.String("ob")
+	}))
+	cases := []struct {
+		format string
+		args   []runtime.Val
+		exp    string
+		err    string
+	}{
+		0:  {format: "no verb", exp: "no verb"},
+		1:  {format: "%d%%", args: []runtime.Val{runtime.Number(42)}, exp: "42%"},
+		2:  {format: "%5d|%-5d|%05d", args: []runtime.Val{runtime.Number(1), runtime.Number(2), runtime.String("3")}, exp: "    1|2    |00003"},
+		3:  {format: "%.2f %8.3f %g", args: []runtime.Val{runtime.Number(3.14159), runtime.String("2.5"), runtime.Number(1e21)}, exp: "3.14    2.500 1e+21"},
+		4:  {format: "%s %v %v %v", args: []runtime.Val{runtime.String("a"), runtime.Number(1.5), runtime.Nil, ob}, exp: "a 1.5 nil ob"},
+		5:  {format: "%q", args: []runtime.Val{runtime.String("a\"b")}, exp: `"a\"b"`},
+		6:  {format: "%x %X %x", args: []runtime.Val{runtime.Number(255), runtime.Number(255), runtime.String("hi")}, exp: "ff FF 6869"},
+		7:  {format: "%t %t", args: []runtime.Val{runtime.Bool(true), runtime.Number(0)}, exp: "true false"},
+		8:  {format: "%T %T %T", args: []runtime.Val{runtime.Number(1), runtime.String(""), ob}, exp: "number string object"},
+		9:  {format: "%*d|%.*f", args: []runtime.Val{runtime.Number(4), runtime.Number(7), runtime.Number(1), runtime.Number(2.25)}, exp: "   7|2.2"},
+		10: {format: "%-6s|%.2s", args: []runtime.Val{runtime.String("ab"), runtime.String("xyz")}, exp: "ab    |xy"},
+		11: {format: "%c%b%o", args: []runtime.Val{runtime.Number(65), runtime.Number(5), runtime.Number(8)}, exp: "A10110"},
+		12: {format: "%d", args: []runtime.Val{runtime.String("abc")}, err: `fmt.Sprintf: %d: cannot format string "abc"`},
+		13: {format: "%d %d", args: []runtime.Val{runtime.Number(1)}, err: "fmt.Sprintf: %d: missing argument"},
+		14: {format: "%d", args: []runtime.Val{runtime.Number(1), runtime.Number(2)}, err: "fmt.Sprintf: too many arguments: 2 for 1 verbs"},
+		15: {format: "%z", args: []runtime.Val{runtime.Number(1)}, err: "fmt.Sprintf: %z: unknown verb"},
+		16: {format: "abc %-5", err: "fmt.Sprintf: %-5: missing verb"},
+		17: {format: "%f", args: []runtime.Val{ob}, err: "fmt.Sprintf: %f: cannot format object"},
+	}
+	for i, c := range cases {
+		var res runtime.Val
+		err := assertErr(func() {
+			res = fm.fmt_Sprintf(ctx, append([]runtime.Val{runtime.String(c.format)}, c.args...)...)
+		})
+		if c.err != "" {
+			if err == nil || !strings.HasPrefix(err.Error(), c.err) {
+				t.Errorf("[%d] - expected error %q, got %v", i, c.err, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("[%d] - expected no error, got %s", i, err)
+			continue
+		}
+		if res.String(ctx) != c.exp {
+			t.Errorf("[%d] - expected %q, got %q", i, c.exp, res.String(ctx))
+		}
+	}
+}
+
+func TestFmtPrintf(t *testing.T) {
+	ctx := context.Background()
+	ktx := runtime.NewKtx(nil, nil)
+	fm := new(FmtMod)
+	fm.SetKtx(ktx)
+	buf := bytes.NewBuffer(nil)
+	ktx.Stdout = buf
+
+	res := fm.fmt_Printf(ctx, runtime.String("%s=%03d\n"), runtime.String("x"), runtime.Number(7))
+	if exp := "x=007\n"; buf.String() != exp {
+		t.Errorf("expected %q, got %q", exp, buf.String())
+	}
+	if res.Int(ctx) != 6 {
+		t.Errorf("expected return value of %d, got %d", 6, res.Int(ctx))
+	}
+}
+
+func TestFmtFprintf(t *testing.T) {
+	ctx := context.Background()
+	ktx := runtime.NewKtx(nil, nil)
+	fm := new(FmtMod)
+	fm.SetKtx(ktx)
+	om := new(OsMod)
+	om.SetKtx(ktx)
+
+	// To an os file
+	f, err := ioutil.TempFile("", "agora-fprintf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	of := om.newFile(f)
+	fm.fmt_Fprintf(ctx, of, runtime.String("%d-%s"), runtime.Number(1), runtime.String("a"))
+	f.Close()
+	b, err := ioutil.ReadFile(f.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exp := "1-a"; string(b) != exp {
+		t.Errorf("expected %q, got %q", exp, string(b))
+	}
+
+	// To an object with a Write method
+	var got string
+	w := runtime.NewObject()
+	w.Set(runtime.String("Write"), runtime.NewNativeFunc(ktx, "", func(ctx context.Context, args ...runtime.Val) runtime.Val {
+		got += args[0].String(ctx)
+		return runtime.Number(len(args[0].String(ctx)))
+	}))
+	res := fm.fmt_Fprintf(ctx, w, runtime.String("%.1f"), runtime.Number(2))
+	if exp := "2.0"; got != exp {
+		t.Errorf("expected %q, got %q", exp, got)
+	}
+	if res.Int(ctx) != 3 {
+		t.Errorf("expected return value of %d, got %d", 3, res.Int(ctx))
+	}
+
+	// To an invalid destination
+	err = assertErr(func() {
+		fm.fmt_Fprintf(ctx, runtime.Number(1), runtime.String("x"))
+	})
+	if err == nil {
+		t.Errorf("expected error, got none")
+	}
+}
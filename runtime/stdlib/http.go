@@ -0,0 +1,287 @@
+package stdlib
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/saward/agora/runtime"
+)
+
+// The http module, as documented in
+// https://github.com/saward/agora/wiki/Standard-library
+type HTTPMod struct {
+	ktx *runtime.Kontext
+	ob  runtime.Object
+
+	// NewKtx returns the execution context in which a request is handled. If
+	// it is nil, the context is a clone of the module's context, with the
+	// same native modules (see runtime.Kontext.Clone), and with its standard
+	// streams and the bytecode of the imported modules shared by the
+	// requests.
+	NewKtx func() *runtime.Kontext
+}
+
+func (h *HTTPMod) ID() string {
+	return "http"
+}
+
+func (h *HTTPMod) Run(_ context.Context, _ ...runtime.Val) (v runtime.Val, err error) {
+	defer runtime.PanicToError(&err)
+	if h.ob == nil {
+		// Prepare the object
+		h.ob = runtime.NewObject()
+		h.ob.Set(runtime.String("Do"), runtime.NewNativeFunc(h.ktx, "http.Do", h.http_Do))
+		h.ob.Set(runtime.String("Get"), runtime.NewNativeFunc(h.ktx, "http.Get", h.http_Get))
+		h.ob.Set(runtime.String("Post"), runtime.NewNativeFunc(h.ktx, "http.Post", h.http_Post))
+		h.ob.Set(runtime.String("ListenAndServe"), runtime.NewNativeFunc(h.ktx, "http.ListenAndServe", h.http_ListenAndServe))
+	}
+	return h.ob, nil
+}
+
+func (h *HTTPMod) SetKtx(c *runtime.Kontext) {
+	h.ktx = c
+}
+
+func (h *HTTPMod) Clone() runtime.NativeModule {
+	return &HTTPMod{NewKtx: h.NewKtx}
+}
+
+// Returns the object holding the headers, the values of a header being
+// joined with a comma.
+func newHeaders(hdr http.Header) runtime.Object {
+	ob := runtime.NewObject()
+	for k, v := range hdr {
+		ob.Set(runtime.String(k), runtime.String(strings.Join(v, ", ")))
+	}
+	return ob
+}
+
+// Sets the headers of the object ob in hdr.
+func setHeaders(ctx context.Context, hdr http.Header, ob runtime.Val) {
+	hob, ok := ob.(runtime.Object)
+	if !ok {
+		return
+	}
+	keys := hob.Keys(ctx).(runtime.Object)
+	for i := int64(0); i < keys.Len(ctx).Int(ctx); i++ {
+		k := keys.Get(runtime.Number(i))
+		hdr.Set(k.String(ctx), hob.Get(k).String(ctx))
+	}
+}
+
+// Returns the field nm of the options object, or Nil.
+func option(opts runtime.Val, nm string) runtime.Val {
+	if ob, ok := opts.(runtime.Object); ok {
+		return ob.Get(runtime.String(nm))
+	}
+	return runtime.Nil
+}
+
+// Sends the request and returns the response object.
+func (h *HTTPMod) do(ctx context.Context, method, url string, opts runtime.Val) runtime.Val {
+	var body io.Reader
+	if b := option(opts, "Body"); b != runtime.Nil {
+		body = strings.NewReader(b.String(ctx))
+	}
+	req, err := http.NewRequest(method, url, body)
+	if err != nil {
+		panic(err)
+	}
+	req = req.WithContext(ctx)
+	setHeaders(ctx, req.Header, option(opts, "Headers"))
+	cli := &http.Client{}
+	if to := option(opts, "Timeout"); to != runtime.Nil {
+		cli.Timeout = time.Duration(to.Int(ctx)) * time.Millisecond
+	}
+	resp, err := cli.Do(req)
+	if err != nil {
+		panic(err)
+	}
+	defer resp.Body.Close()
+	b, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		panic(err)
+	}
+	ob := runtime.NewObject()
+	ob.Set(runtime.String("Status"), runtime.Number(resp.StatusCode))
+	ob.Set(runtime.String("Headers"), newHeaders(resp.Header))
+	ob.Set(runtime.String("Body"), runtime.String(b))
+	return ob
+}
+
+// Args:
+// 0 - The method
+// 1 - The URL
+// 2 - The options (optional), an object with the Headers, Body and Timeout
+// (in milliseconds) fields
+// Returns:
+// The response object, with the Status, Headers and Body fields.
+func (h *HTTPMod) http_Do(ctx context.Context, args ...runtime.Val) runtime.Val {
+	runtime.ExpectAtLeastNArgs(2, args)
+	var opts runtime.Val = runtime.Nil
+	if len(args) > 2 {
+		opts = args[2]
+	}
+	return h.do(ctx, strings.ToUpper(args[0].String(ctx)), args[1].String(ctx), opts)
+}
+
+// Args:
+// 0 - The URL
+// 1 - The options (optional), see Do
+// Returns:
+// The response object.
+func (h *HTTPMod) http_Get(ctx context.Context, args ...runtime.Val) runtime.Val {
+	runtime.ExpectAtLeastNArgs(1, args)
+	return h.http_Do(ctx, append([]runtime.Val{runtime.String("GET")}, args...)...)
+}
+
+// Args:
+// 0 - The URL
+// 1 - The body
+// 2 - The options (optional), see Do
+// Returns:
+// The response object.
+func (h *HTTPMod) http_Post(ctx context.Context, args ...runtime.Val) runtime.Val {
+	runtime.ExpectAtLeastNArgs(2, args)
+	opts := runtime.NewObject()
+	if len(args) > 2 {
+		if ob, ok := args[2].(runtime.Object); ok {
+			keys := ob.Keys(ctx).(runtime.Object)
+			for i := int64(0); i < keys.Len(ctx).Int(ctx); i++ {
+				k := keys.Get(runtime.Number(i))
+				opts.Set(k, ob.Get(k))
+			}
+		}
+	}
+	opts.Set(runtime.String("Body"), args[1])
+	return h.do(ctx, "POST", args[0].String(ctx), opts)
+}
+
+// Args:
+// 0 - The address to listen on, e.g. ":8080"
+// 1 - The ID of the handler module
+// Returns:
+// Nil when the execution context is cancelled, it panics if the server
+// fails.
+func (h *HTTPMod) http_ListenAndServe(ctx context.Context, args ...runtime.Val) runtime.Val {
+	runtime.ExpectAtLeastNArgs(2, args)
+	hdl, err := h.Handler(args[1].String(ctx))
+	if err != nil {
+		panic(err)
+	}
+	srv := &http.Server{
+		Addr:    args[0].String(ctx),
+		Handler: hdl,
+	}
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			srv.Shutdown(context.Background())
+		case <-done:
+		}
+	}()
+	if err := srv.ListenAndServe(); err != http.ErrServerClosed {
+		panic(err)
+	}
+	return runtime.Nil
+}
+
+// Handler returns the HTTP handler that handles each request by running
+// the agora module identified by id in a new execution context, as returned
+// by NewKtx. The module is compiled once, when Handler is called, and it
+// returns the compilation error, if any. The module is called with the
+// request object as argument, and returns the response, or a func that is
+// called with the request object and returns the response.
+func (h *HTTPMod) Handler(id string) (hdl http.Handler, err error) {
+	defer runtime.PanicToError(&err)
+	f, err := h.ktx.Compile(id)
+	if err != nil {
+		return nil, err
+	}
+	// The requests run in clones of this context, that is never run itself.
+	// They share the standard streams of the module's context, and the
+	// bytecode of the modules they import.
+	base := h.ktx.Clone()
+	lockStreams(base)
+	if base.Cache == nil {
+		base.Cache = new(runtime.BytecodeCache)
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		k := h.newKtx(base)
+		if err := h.serve(w, r, k.LoadBytecode(id, f)); err != nil {
+			fmt.Fprintf(base.Stderr, "http: %s %s: %s\n", r.Method, r.URL, err)
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		}
+	}), nil
+}
+
+// Returns the execution context in which to handle a request, a clone of
+// base if NewKtx is nil.
+func (h *HTTPMod) newKtx(base *runtime.Kontext) *runtime.Kontext {
+	if h.NewKtx != nil {
+		return h.NewKtx()
+	}
+	return base.Clone()
+}
+
+// Returns the request object of the request.
+func newRequest(r *http.Request) (runtime.Object, error) {
+	b, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		return nil, err
+	}
+	ob := runtime.NewObject()
+	ob.Set(runtime.String("Method"), runtime.String(r.Method))
+	ob.Set(runtime.String("URL"), runtime.String(r.URL.String()))
+	ob.Set(runtime.String("Path"), runtime.String(r.URL.Path))
+	q := runtime.NewObject()
+	for k, v := range r.URL.Query() {
+		q.Set(runtime.String(k), runtime.String(v[0]))
+	}
+	ob.Set(runtime.String("Query"), q)
+	ob.Set(runtime.String("Headers"), newHeaders(r.Header))
+	ob.Set(runtime.String("Body"), runtime.String(b))
+	ob.Set(runtime.String("RemoteAddr"), runtime.String(r.RemoteAddr))
+	return ob, nil
+}
+
+// Handles the request with the module m, and writes the response.
+func (h *HTTPMod) serve(w http.ResponseWriter, r *http.Request, m runtime.Module) (err error) {
+	defer runtime.PanicToError(&err)
+	ctx := r.Context()
+	req, err := newRequest(r)
+	if err != nil {
+		return err
+	}
+	res, err := m.Run(ctx, req)
+	if err != nil {
+		return err
+	}
+	if fn, ok := res.(runtime.Func); ok {
+		res = fn.Call(ctx, nil, req)
+	}
+
+	// The response is an object, or the body of a 200 response
+	status, body := http.StatusOK, res
+	if ob, ok := res.(runtime.Object); ok {
+		if _, isBuf := ob.(*buffer); !isBuf {
+			setHeaders(ctx, w.Header(), ob.Get(runtime.String("Headers")))
+			if s := ob.Get(runtime.String("Status")); s != runtime.Nil {
+				status = int(s.Int(ctx))
+			}
+			body = ob.Get(runtime.String("Body"))
+		}
+	}
+	w.WriteHeader(status)
+	if body != runtime.Nil {
+		_, err = w.Write(toBytes(ctx, body))
+	}
+	return err
+}
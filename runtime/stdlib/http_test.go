@@ -0,0 +1,297 @@
+package stdlib
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/saward/agora/bytecode"
+	"github.com/saward/agora/compiler"
+	"github.com/saward/agora/runtime"
+)
+
+func TestHTTPClient(t *testing.T) {
+	ctx := context.Background()
+	ktx := runtime.NewKtx(nil, nil)
+	hm := new(HTTPMod)
+	hm.SetKtx(ktx)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/slow" {
+			time.Sleep(200 * time.Millisecond)
+		}
+		b, _ := ioutil.ReadAll(r.Body)
+		w.Header().Set("X-Method", r.Method)
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(r.Header.Get("X-Test") + ":" + string(b)))
+	}))
+	defer srv.Close()
+
+	hdrs := runtime.NewObject()
+	hdrs.Set(runtime.String("X-Test"), runtime.String("yes"))
+	opts := runtime.NewObject()
+	opts.Set(runtime.String("Headers"), hdrs)
+	opts.Set(runtime.String("Body"), runtime.String("data"))
+
+	check := func(i int, res runtime.Val, method, body string) {
+		ob := res.(runtime.Object)
+		if st := ob.Get(runtime.String("Status")).Int(ctx); st != http.StatusCreated {
+			t.Errorf("[%d] - expected status %d, got %d", i, http.StatusCreated, st)
+		}
+		if m := ob.Get(runtime.String("Headers")).(runtime.Object).Get(runtime.String("X-Method")).String(ctx); m != method {
+			t.Errorf("[%d] - expected method %s, got %s", i, method, m)
+		}
+		if b := ob.Get(runtime.String("Body")).String(ctx); b != body {
+			t.Errorf("[%d] - expected body %q, got %q", i, body, b)
+		}
+	}
+	check(0, hm.http_Do(ctx, runtime.String("put"), runtime.String(srv.URL), opts), "PUT", "yes:data")
+	check(1, hm.http_Get(ctx, runtime.String(srv.URL)), "GET", ":")
+	check(2, hm.http_Post(ctx, runtime.String(srv.URL), runtime.String("x"), opts), "POST", "yes:x")
+
+	// Timeout
+	opts = runtime.NewObject()
+	opts.Set(runtime.String("Timeout"), runtime.Number(20))
+	if err := assertErr(func() { hm.http_Get(ctx, runtime.String(srv.URL+"/slow"), opts) }); err == nil {
+		t.Errorf("expected timeout error")
+	}
+}
+
+func TestHTTPHandler(t *testing.T) {
+	fsys := fstest.MapFS{
+		"echo.agora": {Data: []byte(`
+count := import("zero")
+return func(req) {
+	count++
+	hdrs := {}
+	hdrs["X-Count"] = count
+	return {
+		Status: 202,
+		Headers: hdrs,
+		Body: req.Method + " " + req.Path + " " + req.Query.q + " " + req.Headers["X-Test"] + " " + req.Body,
+	}
+}
+`)},
+		"zero.agora": {Data: []byte(`return 0`)},
+		"text.agora": {Data: []byte(`return "hello"`)},
+		"fail.agora": {Data: []byte(`return 1 + {}`)},
+		"bad.agora":  {Data: []byte(`return 1 +`)},
+	}
+	comp := &countCompiler{n: make(map[string]int)}
+	ktx := runtime.NewKtx(runtime.NewFSResolver(fsys), comp)
+	stderr := bytes.NewBuffer(nil)
+	ktx.Stderr = stderr
+	hm := new(HTTPMod)
+	hm.SetKtx(ktx)
+	handler := func(id string) http.Handler {
+		hdl, err := hm.Handler(id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return hdl
+	}
+
+	srv := httptest.NewServer(handler("echo"))
+	defer srv.Close()
+	// The module is compiled once, when the handler is created
+	fsys["echo.agora"] = &fstest.MapFile{Data: []byte(`return "changed"`)}
+	for i := 0; i < 2; i++ {
+		req, _ := http.NewRequest("POST", srv.URL+"/a/b?q=1", strings.NewReader("body"))
+		req.Header.Set("X-Test", "t")
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		b, _ := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		if resp.StatusCode != 202 {
+			t.Errorf("[%d] - expected status 202, got %d", i, resp.StatusCode)
+		}
+		// Each request runs in a new context, so the count is always 1
+		if c := resp.Header.Get("X-Count"); c != "1" {
+			t.Errorf("[%d] - expected count 1, got %s", i, c)
+		}
+		if exp := "POST /a/b 1 t body"; string(b) != exp {
+			t.Errorf("[%d] - expected %q, got %q", i, exp, b)
+		}
+	}
+
+	// The imported modules are compiled once too
+	if comp.n["echo"] != 1 || comp.n["zero"] != 1 {
+		t.Errorf("expected the modules to be compiled once, got %v", comp.n)
+	}
+
+	srv2 := httptest.NewServer(handler("text"))
+	defer srv2.Close()
+	resp, err := http.Get(srv2.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, _ := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode != 200 || string(b) != "hello" {
+		t.Errorf("expected 200 hello, got %d %s", resp.StatusCode, b)
+	}
+
+	srv3 := httptest.NewServer(handler("fail"))
+	defer srv3.Close()
+	resp, err = http.Get(srv3.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != 500 {
+		t.Errorf("expected status 500, got %d", resp.StatusCode)
+	}
+	if !strings.Contains(stderr.String(), "type error") {
+		t.Errorf("expected the error to be logged, got %q", stderr.String())
+	}
+
+	for _, id := range []string{"bad", "missing"} {
+		if _, err := hm.Handler(id); err == nil {
+			t.Errorf("expected a compilation error for %s", id)
+		}
+	}
+}
+
+func TestHTTPHandlerKtx(t *testing.T) {
+	fsys := fstest.MapFS{
+		"print.agora": {Data: []byte(`fmt := import("fmt")
+fmt.Println("req " + args[0].Query.n)
+return import("cfg")`)},
+		"sys.agora": {Data: []byte(`return import("os").Getwd()`)},
+	}
+	ktx := runtime.NewKtx(runtime.NewFSResolver(fsys), new(compiler.Compiler))
+	stdout := bytes.NewBuffer(nil)
+	ktx.Stdout = stdout
+	ktx.Stderr = stdout
+	hm := new(HTTPMod)
+	ktx.RegisterNativeModule(hm)
+	ktx.RegisterNativeModule(new(FmtMod))
+	ktx.RegisterNativeModule(&cfgMod{cfg: "x"})
+
+	// The stdlib modules can all be cloned
+	for _, m := range Modules() {
+		if _, ok := m.(runtime.CloningModule); !ok {
+			t.Errorf("expected %s to implement runtime.CloningModule", m.ID())
+		}
+	}
+
+	// The requests only have the native modules of the module's context,
+	// and share its standard output under a lock
+	hdl, err := hm.Handler("print")
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := httptest.NewServer(hdl)
+	defer srv.Close()
+	const n = 10
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			resp, err := http.Get(fmt.Sprintf("%s?n=%d", srv.URL, i))
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			b, _ := ioutil.ReadAll(resp.Body)
+			resp.Body.Close()
+			if string(b) != "x" {
+				t.Errorf("[%d] - expected the cfg module, got %d %s", i, resp.StatusCode, b)
+			}
+		}(i)
+	}
+	wg.Wait()
+	if lines := strings.Split(strings.TrimSpace(stdout.String()), "\n"); len(lines) != n {
+		t.Errorf("expected %d lines, got %q", n, stdout.String())
+	}
+
+	stdout.Reset()
+	hdl, err = hm.Handler("sys")
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv2 := httptest.NewServer(hdl)
+	defer srv2.Close()
+	resp, err := http.Get(srv2.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != 500 || !strings.Contains(stdout.String(), "module not found: os") {
+		t.Errorf("expected the os module not to be found, got %d %q", resp.StatusCode, stdout.String())
+	}
+}
+
+// A compiler that counts the compiled modules.
+type countCompiler struct {
+	mu sync.Mutex
+	n  map[string]int
+}
+
+func (c *countCompiler) Compile(id string, r io.Reader) (*bytecode.File, error) {
+	c.mu.Lock()
+	c.n[id]++
+	c.mu.Unlock()
+	return new(compiler.Compiler).Compile(id, r)
+}
+
+// A native module that returns its configuration.
+type cfgMod struct {
+	ktx *runtime.Kontext
+	cfg string
+}
+
+func (c *cfgMod) ID() string {
+	return "cfg"
+}
+
+func (c *cfgMod) Run(_ context.Context, _ ...runtime.Val) (runtime.Val, error) {
+	return runtime.String(c.cfg), nil
+}
+
+func (c *cfgMod) SetKtx(ktx *runtime.Kontext) {
+	c.ktx = ktx
+}
+
+func (c *cfgMod) Clone() runtime.NativeModule {
+	return &cfgMod{cfg: c.cfg}
+}
+
+func TestHTTPListenAndServe(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	fsys := fstest.MapFS{
+		"x.agora": {Data: []byte(`return "x"`)},
+	}
+	ktx := runtime.NewKtx(runtime.NewFSResolver(fsys), new(compiler.Compiler))
+	hm := new(HTTPMod)
+	hm.SetKtx(ktx)
+
+	done := make(chan error)
+	go func() {
+		done <- assertErr(func() {
+			hm.http_ListenAndServe(ctx, runtime.String("127.0.0.1:0"), runtime.String("x"))
+		})
+	}()
+	time.Sleep(50 * time.Millisecond)
+	cancel()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("expected no error, got %s", err)
+		}
+	case <-time.After(time.Second):
+		t.Errorf("expected the server to stop when the context is cancelled")
+	}
+}
@@ -0,0 +1,245 @@
+package stdlib
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/saward/agora/runtime"
+)
+
+// Error raised when the source of json.Unmarshal is not valid JSON.
+type JSONError struct {
+	Offset int64 // The offset of the error in the source, in bytes
+	Line   int   // The line of the error, starting at 1
+	Column int   // The column of the error, in bytes, starting at 1
+	Msg    string
+}
+
+// Error interface implementation.
+func (e JSONError) Error() string {
+	return fmt.Sprintf("json: %s at offset %d (line %d, column %d)", e.Msg, e.Offset, e.Line, e.Column)
+}
+
+// Create a new JSONError for the error detected at offset off of the source.
+func NewJSONError(src string, off int64, msg string) JSONError {
+	if off > int64(len(src)) {
+		off = int64(len(src))
+	}
+	before := src[:off]
+	return JSONError{
+		Offset: off,
+		Line:   strings.Count(before, "\n") + 1,
+		Column: len(before) - strings.LastIndex(before, "\n"),
+		Msg:    msg,
+	}
+}
+
+// The json module, as documented in
+// https://github.com/saward/agora/wiki/Standard-library
+type JSONMod struct {
+	ktx *runtime.Kontext
+	ob  runtime.Object
+}
+
+func (j *JSONMod) ID() string {
+	return "json"
+}
+
+func (j *JSONMod) Run(_ context.Context, _ ...runtime.Val) (v runtime.Val, err error) {
+	defer runtime.PanicToError(&err)
+	if j.ob == nil {
+		// Prepare the object
+		j.ob = runtime.NewObject()
+		j.ob.Set(runtime.String("Marshal"), runtime.NewNativeFunc(j.ktx, "json.Marshal", j.json_Marshal))
+		j.ob.Set(runtime.String("Unmarshal"), runtime.NewNativeFunc(j.ktx, "json.Unmarshal", j.json_Unmarshal))
+	}
+	return j.ob, nil
+}
+
+func (j *JSONMod) SetKtx(c *runtime.Kontext) {
+	j.ktx = c
+}
+
+func (j *JSONMod) Clone() runtime.NativeModule {
+	return new(JSONMod)
+}
+
+// Args:
+// 0 - The value to encode
+// 1 - The indentation, a string or a number of spaces (optional)
+// Returns:
+// The JSON encoding of the value.
+func (j *JSONMod) json_Marshal(ctx context.Context, args ...runtime.Val) runtime.Val {
+	runtime.ExpectAtLeastNArgs(1, args)
+	v := toJSON(ctx, args[0], make(map[runtime.Object]bool))
+	buf := bytes.NewBuffer(nil)
+	enc := json.NewEncoder(buf)
+	enc.SetEscapeHTML(false)
+	if len(args) > 1 {
+		ind := args[1].String(ctx)
+		if _, ok := args[1].(runtime.Number); ok {
+			ind = strings.Repeat(" ", int(args[1].Int(ctx)))
+		}
+		enc.SetIndent("", ind)
+	}
+	if err := enc.Encode(v); err != nil {
+		panic(err)
+	}
+	return runtime.String(strings.TrimSuffix(buf.String(), "\n"))
+}
+
+// Returns the Go value to encode as JSON for the agora value v. The seen
+// objects are tracked to detect cycles.
+func toJSON(ctx context.Context, v runtime.Val, seen map[runtime.Object]bool) interface{} {
+	switch v := v.(type) {
+	case nil:
+		return nil
+	case runtime.Number:
+		f := float64(v)
+		if math.IsNaN(f) || math.IsInf(f, 0) {
+			panic(fmt.Sprintf("json: unsupported number: %s", v.String(ctx)))
+		}
+		if f == math.Trunc(f) && math.Abs(f) < 1<<53 {
+			return int64(f)
+		}
+		return f
+	case runtime.String, runtime.Bool:
+		return v.Native(ctx)
+	case runtime.Func:
+		panic(runtime.NewTypeError(runtime.Type(v), "", "json.Marshal"))
+	case runtime.Object:
+		if seen[v] {
+			panic("json: cycle in the object to marshal")
+		}
+		seen[v] = true
+		defer delete(seen, v)
+		if mm, ok := v.Get(runtime.String("__json")).(runtime.Func); ok {
+			return toJSON(ctx, mm.Call(ctx, v), seen)
+		}
+		if _, ok := v.Get(runtime.String("__native")).(runtime.Func); ok {
+			return v.Native(ctx)
+		}
+		keys := v.Keys(ctx).(runtime.Object)
+		n := int(keys.Len(ctx).Int(ctx))
+		if isArray(ctx, keys, n) {
+			a := make([]interface{}, n)
+			for i := range a {
+				a[i] = toJSON(ctx, v.Get(runtime.Number(i)), seen)
+			}
+			return a
+		}
+		m := make(map[string]interface{}, n)
+		for i := 0; i < n; i++ {
+			k := keys.Get(runtime.Number(i))
+			val := v.Get(k)
+			if _, ok := val.(runtime.Func); ok {
+				// Methods are not part of the data
+				continue
+			}
+			m[k.String(ctx)] = toJSON(ctx, val, seen)
+		}
+		return m
+	}
+	if v == runtime.Nil {
+		return nil
+	}
+	// A custom value, encode its native representation
+	return v.Native(ctx)
+}
+
+// Returns true if the object is array-like, that is if its keys are the
+// numbers 0 to n - 1. An empty object is not an array.
+func isArray(ctx context.Context, keys runtime.Object, n int) bool {
+	if n == 0 {
+		return false
+	}
+	ixs := make([]int, 0, n)
+	for i := 0; i < n; i++ {
+		k, ok := keys.Get(runtime.Number(i)).(runtime.Number)
+		if !ok || float64(k) != math.Trunc(float64(k)) {
+			return false
+		}
+		ixs = append(ixs, int(k))
+	}
+	sort.Ints(ixs)
+	for i, ix := range ixs {
+		if i != ix {
+			return false
+		}
+	}
+	return true
+}
+
+// Args:
+// 0 - The JSON source
+// Returns:
+// The decoded value. JSON objects and arrays are decoded as objects, the
+// arrays being indexed from 0 to the number of elements - 1.
+func (j *JSONMod) json_Unmarshal(ctx context.Context, args ...runtime.Val) runtime.Val {
+	runtime.ExpectAtLeastNArgs(1, args)
+	src := args[0].String(ctx)
+	dec := json.NewDecoder(strings.NewReader(src))
+	dec.UseNumber()
+	var v interface{}
+	if err := dec.Decode(&v); err != nil {
+		panic(jsonError(src, dec, err))
+	}
+	// Only whitespace is allowed after the value
+	if off := dec.InputOffset(); strings.TrimSpace(src[off:]) != "" {
+		off += int64(len(src[off:]) - len(strings.TrimLeft(src[off:], " \t\r\n")))
+		panic(NewJSONError(src, off, "invalid data after top-level value"))
+	}
+	return fromJSON(v)
+}
+
+// Returns the JSONError for the decoding error err.
+func jsonError(src string, dec *json.Decoder, err error) error {
+	switch e := err.(type) {
+	case *json.SyntaxError:
+		// The offset is the number of bytes read, including the invalid one
+		return NewJSONError(src, e.Offset-1, e.Error())
+	case *json.UnmarshalTypeError:
+		return NewJSONError(src, e.Offset, e.Error())
+	}
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		return NewJSONError(src, int64(len(src)), "unexpected end of JSON input")
+	}
+	return NewJSONError(src, dec.InputOffset(), err.Error())
+}
+
+// Returns the agora value for the decoded JSON value v.
+func fromJSON(v interface{}) runtime.Val {
+	switch v := v.(type) {
+	case nil:
+		return runtime.Nil
+	case bool:
+		return runtime.Bool(v)
+	case string:
+		return runtime.String(v)
+	case json.Number:
+		f, err := v.Float64()
+		if err != nil {
+			panic(err)
+		}
+		return runtime.Number(f)
+	case []interface{}:
+		ob := runtime.NewObject()
+		for i, e := range v {
+			ob.Set(runtime.Number(i), fromJSON(e))
+		}
+		return ob
+	case map[string]interface{}:
+		ob := runtime.NewObject()
+		for k, e := range v {
+			ob.Set(runtime.String(k), fromJSON(e))
+		}
+		return ob
+	}
+	panic(fmt.Sprintf("json: unexpected decoded type %T", v))
+}
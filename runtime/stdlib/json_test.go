@@ -0,0 +1,124 @@
+package stdlib
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/saward/agora/runtime"
+)
+
+func TestJSONMarshal(t *testing.T) {
+	ctx := context.Background()
+	ktx := runtime.NewKtx(nil, nil)
+	jm := new(JSONMod)
+	jm.SetKtx(ktx)
+
+	arr := runtime.NewObject()
+	arr.Set(runtime.Number(1), runtime.String("b"))
+	arr.Set(runtime.Number(0), runtime.Number(1.5))
+	ob := runtime.NewObject()
+	ob.Set(runtime.String("s"), runtime.String(`say "<hi>"`))
+	ob.Set(runtime.String("a"), arr)
+	ob.Set(runtime.String("t"), runtime.Bool(true))
+	ob.Set(runtime.String("n"), runtime.Number(1000000))
+	ob.Set(runtime.String("m"), runtime.NewNativeFunc(ktx, "m", func(context.Context, ...runtime.Val) runtime.Val {
+		return runtime.Nil
+	}))
+	holes := runtime.NewObject()
+	holes.Set(runtime.Number(0), runtime.Number(1))
+	holes.Set(runtime.Number(2), runtime.Number(3))
+	custom := runtime.NewObject()
+	custom.Set(runtime.String("x"), runtime.Number(1))
+	custom.Set(runtime.String("__json"), runtime.NewNativeFunc(ktx, "__json", func(context.Context, ...runtime.Val) runtime.Val {
+		return runtime.String("custom")
+	}))
+	native := runtime.NewObject()
+	native.Set(runtime.String("__native"), runtime.NewNativeFunc(ktx, "__native", func(context.Context, ...runtime.Val) runtime.Val {
+		return runtime.Number(42)
+	}))
+
+	cases := []struct {
+		args []runtime.Val
+		exp  string
+	}{
+		0: {[]runtime.Val{runtime.Nil}, "null"},
+		1: {[]runtime.Val{runtime.Number(-3)}, "-3"},
+		2: {[]runtime.Val{runtime.Number(0.25)}, "0.25"},
+		3: {[]runtime.Val{ob}, `{"a":[1.5,"b"],"n":1000000,"s":"say \"<hi>\"","t":true}`},
+		4: {[]runtime.Val{arr, runtime.Number(2)}, "[\n  1.5,\n  \"b\"\n]"},
+		5: {[]runtime.Val{holes}, `{"0":1,"2":3}`},
+		6: {[]runtime.Val{runtime.NewObject(), runtime.String("\t")}, "{}"},
+		7: {[]runtime.Val{custom}, `"custom"`},
+		8: {[]runtime.Val{native}, "42"},
+	}
+	for i, c := range cases {
+		if got := jm.json_Marshal(ctx, c.args...).String(ctx); got != c.exp {
+			t.Errorf("[%d] - expected %q, got %q", i, c.exp, got)
+		}
+	}
+
+	cyclic := runtime.NewObject()
+	cyclic.Set(runtime.String("self"), cyclic)
+	for i, v := range []runtime.Val{ob.Get(runtime.String("m")), cyclic} {
+		if err := assertErr(func() { jm.json_Marshal(ctx, v) }); err == nil {
+			t.Errorf("[%d] - expected an error", i)
+		}
+	}
+}
+
+func TestJSONUnmarshal(t *testing.T) {
+	ctx := context.Background()
+	ktx := runtime.NewKtx(nil, nil)
+	jm := new(JSONMod)
+	jm.SetKtx(ktx)
+
+	v := jm.json_Unmarshal(ctx, runtime.String(`{"a": [1, "two", {"b": false}], "c": null, "d": 1e3}`))
+	ob := v.(runtime.Object)
+	a := ob.Get(runtime.String("a")).(runtime.Object)
+	if n := a.Len(ctx).Int(ctx); n != 3 {
+		t.Errorf("expected an array of 3 elements, got %d", n)
+	}
+	if s := a.Get(runtime.Number(1)); s != runtime.String("two") {
+		t.Errorf("expected two, got %v", s)
+	}
+	if b := a.Get(runtime.Number(2)).(runtime.Object).Get(runtime.String("b")); b != runtime.Bool(false) {
+		t.Errorf("expected false, got %v", b)
+	}
+	if c := ob.Get(runtime.String("c")); c != runtime.Nil {
+		t.Errorf("expected nil, got %v", c)
+	}
+	if d := ob.Get(runtime.String("d")); d != runtime.Number(1000) {
+		t.Errorf("expected 1000, got %v", d)
+	}
+	// Round trip
+	if s := jm.json_Marshal(ctx, v).String(ctx); s != `{"a":[1,"two",{"b":false}],"d":1000}` {
+		t.Errorf("unexpected round trip %s", s)
+	}
+
+	cases := []struct {
+		src          string
+		off          int64
+		line, column int
+	}{
+		0: {`{"a": 1,}`, 8, 1, 9},
+		1: {"[1,\n  2,\n  x]", 11, 3, 3},
+		2: {`{"a": `, 6, 1, 7},
+		3: {`{} {}`, 3, 1, 4},
+		4: {``, 0, 1, 1},
+	}
+	for i, c := range cases {
+		err := assertErr(func() { jm.json_Unmarshal(ctx, runtime.String(c.src)) })
+		je, ok := err.(JSONError)
+		if !ok {
+			t.Errorf("[%d] - expected a JSONError, got %v", i, err)
+			continue
+		}
+		if je.Offset != c.off || je.Line != c.line || je.Column != c.column {
+			t.Errorf("[%d] - expected offset %d (%d:%d), got %s", i, c.off, c.line, c.column, je)
+		}
+		if !strings.HasPrefix(je.Error(), "json: ") {
+			t.Errorf("[%d] - unexpected message %s", i, je)
+		}
+	}
+}
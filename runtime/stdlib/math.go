@@ -60,6 +60,10 @@ func (m *MathMod) SetKtx(ktx *runtime.Kontext) {
 	m.ktx = ktx
 }
 
+func (m *MathMod) Clone() runtime.NativeModule {
+	return new(MathMod)
+}
+
 func (m *MathMod) math_Abs(ctx context.Context, args ...runtime.Val) runtime.Val {
 	runtime.ExpectAtLeastNArgs(1, args)
 	return runtime.Number(math.Abs(args[0].Float(ctx)))
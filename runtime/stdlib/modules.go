@@ -0,0 +1,66 @@
+package stdlib
+
+import (
+	"io"
+	"sync"
+
+	"github.com/saward/agora/runtime"
+)
+
+// Modules returns new instances of all the modules of the standard library,
+// ready to be registered in an execution context.
+func Modules() []runtime.NativeModule {
+	return []runtime.NativeModule{
+		new(FmtMod),
+		new(FilepathMod),
+		new(StringsMod),
+		new(MathMod),
+		new(OsMod),
+		new(TimeMod),
+		new(TestingMod),
+		new(JSONMod),
+		new(RegexpMod),
+		new(SortMod),
+		new(BytesMod),
+		new(CryptoMod),
+		new(HTTPMod),
+		new(TaskMod),
+		new(EncodingMod),
+		new(UnicodeMod),
+		new(TemplateMod),
+	}
+}
+
+// A standard stream shared by concurrent execution contexts, that serializes
+// their accesses.
+type lockedStream struct {
+	mu *sync.Mutex
+	rw io.ReadWriter
+}
+
+func (l *lockedStream) Read(b []byte) (int, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.rw.Read(b)
+}
+
+func (l *lockedStream) Write(b []byte) (int, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.rw.Write(b)
+}
+
+// Wraps the standard streams of the execution context so that the accesses
+// of the concurrent contexts cloned from it are serialized, unless they are
+// already wrapped. The output and error share the same lock in case they are
+// the same writer, the input has its own so that a blocking read does not
+// block the writes.
+func lockStreams(ktx *runtime.Kontext) {
+	if _, ok := ktx.Stdout.(*lockedStream); ok {
+		return
+	}
+	mu := new(sync.Mutex)
+	ktx.Stdin = &lockedStream{new(sync.Mutex), ktx.Stdin}
+	ktx.Stdout = &lockedStream{mu, ktx.Stdout}
+	ktx.Stderr = &lockedStream{mu, ktx.Stderr}
+}
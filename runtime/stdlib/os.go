@@ -2,10 +2,16 @@ package stdlib
 
 import (
 	"bufio"
+	"bytes"
 	"context"
+	"errors"
+	"fmt"
+	"io"
 	"io/ioutil"
 	"os"
 	"os/exec"
+	"path/filepath"
+	"strings"
 
 	"github.com/saward/agora/runtime"
 )
@@ -19,19 +25,25 @@ type OsMod struct {
 
 type file struct {
 	runtime.Object
-	f *os.File
-	s *bufio.Scanner
+	ktx *runtime.Kontext
+	f   *os.File
+	// All the reads go through the same buffered reader, so that reading
+	// lines and bytes can be mixed.
+	r *bufio.Reader
 }
 
 func (o *OsMod) newFile(f *os.File) *file {
 	ob := runtime.NewObject()
 	of := &file{
 		ob,
+		o.ktx,
 		f,
 		nil,
 	}
 	ob.Set(runtime.String("Name"), runtime.String(f.Name()))
 	ob.Set(runtime.String("Close"), runtime.NewNativeFunc(o.ktx, "os.File.Close", of.closeFile))
+	ob.Set(runtime.String("Read"), runtime.NewNativeFunc(o.ktx, "os.File.Read", of.read))
+	ob.Set(runtime.String("ReadAll"), runtime.NewNativeFunc(o.ktx, "os.File.ReadAll", of.readAll))
 	ob.Set(runtime.String("ReadLine"), runtime.NewNativeFunc(o.ktx, "os.File.ReadLine", of.readLine))
 	ob.Set(runtime.String("Seek"), runtime.NewNativeFunc(o.ktx, "os.File.Seek", of.seek))
 	ob.Set(runtime.String("Write"), runtime.NewNativeFunc(o.ktx, "os.File.Write", of.write))
@@ -47,17 +59,63 @@ func (of *file) closeFile(ctx context.Context, args ...runtime.Val) runtime.Val
 	return runtime.Nil
 }
 
-func (of *file) readLine(ctx context.Context, args ...runtime.Val) runtime.Val {
-	if of.s == nil {
-		of.s = bufio.NewScanner(of.f)
+// Returns the buffered reader of the file.
+func (of *file) reader() *bufio.Reader {
+	if of.r == nil {
+		of.r = bufio.NewReader(of.f)
 	}
-	if of.s.Scan() {
-		return runtime.String(of.s.Text())
+	return of.r
+}
+
+// Moves the offset of the file back to the first byte that was buffered but
+// not read yet, and discards the buffer, so that the file can be written to.
+func (of *file) unread() {
+	if of.r == nil || of.r.Buffered() == 0 {
+		return
 	}
-	if e := of.s.Err(); e != nil {
+	if _, e := of.f.Seek(-int64(of.r.Buffered()), io.SeekCurrent); e != nil {
 		panic(e)
 	}
-	return runtime.Nil
+	of.r.Reset(of.f)
+}
+
+// Args:
+// 0 - The maximum number of bytes to read
+// Returns:
+// A buffer of the bytes read, or nil at the end of the file.
+func (of *file) read(ctx context.Context, args ...runtime.Val) runtime.Val {
+	runtime.ExpectAtLeastNArgs(1, args)
+	b := make([]byte, int(args[0].Int(ctx)))
+	n, e := of.reader().Read(b)
+	if e == io.EOF && n == 0 && len(b) > 0 {
+		return runtime.Nil
+	}
+	if e != nil && e != io.EOF {
+		panic(e)
+	}
+	return newBuffer(of.ktx, b[:n])
+}
+
+// Returns:
+// A buffer of the bytes read up to the end of the file.
+func (of *file) readAll(ctx context.Context, args ...runtime.Val) runtime.Val {
+	b, e := ioutil.ReadAll(of.reader())
+	if e != nil {
+		panic(e)
+	}
+	return newBuffer(of.ktx, b)
+}
+
+func (of *file) readLine(ctx context.Context, args ...runtime.Val) runtime.Val {
+	l, e := of.reader().ReadString('\n')
+	if e == io.EOF && l == "" {
+		return runtime.Nil
+	}
+	if e != nil && e != io.EOF {
+		panic(e)
+	}
+	l = strings.TrimSuffix(l, "\n")
+	return runtime.String(strings.TrimSuffix(l, "\r"))
 }
 
 func (of *file) seek(ctx context.Context, args ...runtime.Val) runtime.Val {
@@ -69,6 +127,13 @@ func (of *file) seek(ctx context.Context, args ...runtime.Val) runtime.Val {
 	if len(args) > 1 {
 		rel = int(args[1].Int(ctx))
 	}
+	if of.r != nil {
+		// The offset of the file is ahead of the buffered bytes
+		if rel == io.SeekCurrent {
+			off -= int64(of.r.Buffered())
+		}
+		of.r.Reset(of.f)
+	}
 	n, e := of.f.Seek(off, rel)
 	if e != nil {
 		panic(e)
@@ -77,6 +142,7 @@ func (of *file) seek(ctx context.Context, args ...runtime.Val) runtime.Val {
 }
 
 func (of *file) write(ctx context.Context, args ...runtime.Val) runtime.Val {
+	of.unread()
 	n := 0
 	for _, v := range args {
 		m, e := of.f.WriteString(v.String(ctx))
@@ -110,6 +176,7 @@ func (o *OsMod) Run(_ context.Context, _ ...runtime.Val) (v runtime.Val, err err
 		o.ob.Set(runtime.String("PathSeparator"), runtime.String(os.PathSeparator))
 		o.ob.Set(runtime.String("PathListSeparator"), runtime.String(os.PathListSeparator))
 		o.ob.Set(runtime.String("DevNull"), runtime.String(os.DevNull))
+		o.ob.Set(runtime.String("Command"), runtime.NewNativeFunc(o.ktx, "os.Command", o.os_Command))
 		o.ob.Set(runtime.String("Exec"), runtime.NewNativeFunc(o.ktx, "os.Exec", o.os_Exec))
 		o.ob.Set(runtime.String("Exit"), runtime.NewNativeFunc(o.ktx, "os.Exit", o.os_Exit))
 		o.ob.Set(runtime.String("Getenv"), runtime.NewNativeFunc(o.ktx, "os.Getenv", o.os_Getenv))
@@ -123,6 +190,8 @@ func (o *OsMod) Run(_ context.Context, _ ...runtime.Val) (v runtime.Val, err err
 		o.ob.Set(runtime.String("RemoveAll"), runtime.NewNativeFunc(o.ktx, "os.RemoveAll", o.os_RemoveAll))
 		o.ob.Set(runtime.String("Rename"), runtime.NewNativeFunc(o.ktx, "os.Rename", o.os_Rename))
 		o.ob.Set(runtime.String("ReadDir"), runtime.NewNativeFunc(o.ktx, "os.ReadDir", o.os_ReadDir))
+		o.ob.Set(runtime.String("Stat"), runtime.NewNativeFunc(o.ktx, "os.Stat", o.os_Stat))
+		o.ob.Set(runtime.String("Lstat"), runtime.NewNativeFunc(o.ktx, "os.Lstat", o.os_Lstat))
 	}
 	return o.ob, nil
 }
@@ -131,6 +200,10 @@ func (o *OsMod) SetKtx(ktx *runtime.Kontext) {
 	o.ktx = ktx
 }
 
+func (o *OsMod) Clone() runtime.NativeModule {
+	return new(OsMod)
+}
+
 func (o *OsMod) os_Exit(ctx context.Context, args ...runtime.Val) runtime.Val {
 	if len(args) == 0 {
 		os.Exit(0)
@@ -154,14 +227,139 @@ func (o *OsMod) os_Getwd(ctx context.Context, args ...runtime.Val) runtime.Val {
 
 func (o *OsMod) os_Exec(ctx context.Context, args ...runtime.Val) runtime.Val {
 	runtime.ExpectAtLeastNArgs(1, args)
-	c := exec.Command(args[0].String(ctx), toString(ctx, args[1:])...)
+	c := exec.CommandContext(ctx, args[0].String(ctx), toString(ctx, args[1:])...)
 	b, e := c.CombinedOutput()
 	if e != nil {
+		if ctx.Err() != nil {
+			panic(ctx.Err())
+		}
 		panic(e)
 	}
 	return runtime.String(b)
 }
 
+type command struct {
+	runtime.Object
+	ktx    *runtime.Kontext
+	name   string
+	args   []string
+	c      *exec.Cmd
+	ctx    context.Context
+	stdout bytes.Buffer
+	stderr bytes.Buffer
+}
+
+// Args:
+// 0 - The name of the program
+// 1..n - The arguments of the program
+// Returns:
+// The command object, see the documentation for its fields and methods.
+func (o *OsMod) os_Command(ctx context.Context, args ...runtime.Val) runtime.Val {
+	runtime.ExpectAtLeastNArgs(1, args)
+	ob := runtime.NewObject()
+	cmd := &command{
+		Object: ob,
+		ktx:    o.ktx,
+		name:   args[0].String(ctx),
+		args:   toString(ctx, args[1:]),
+	}
+	ob.Set(runtime.String("Run"), runtime.NewNativeFunc(o.ktx, "os.Command.Run", cmd.run))
+	ob.Set(runtime.String("Output"), runtime.NewNativeFunc(o.ktx, "os.Command.Output", cmd.output))
+	ob.Set(runtime.String("Start"), runtime.NewNativeFunc(o.ktx, "os.Command.Start", cmd.start))
+	ob.Set(runtime.String("Wait"), runtime.NewNativeFunc(o.ktx, "os.Command.Wait", cmd.wait))
+	ob.Set(runtime.String("Kill"), runtime.NewNativeFunc(o.ktx, "os.Command.Kill", cmd.kill))
+	return cmd
+}
+
+// Starts the process, configured by the Env, Dir and Stdin fields of the
+// command object. The process is killed if the execution context ctx is
+// cancelled.
+func (cmd *command) start(ctx context.Context, args ...runtime.Val) runtime.Val {
+	if cmd.c != nil {
+		panic("os: command already started")
+	}
+	c := exec.CommandContext(ctx, cmd.name, cmd.args...)
+	if d := cmd.Get(runtime.String("Dir")); d != runtime.Nil {
+		c.Dir = d.String(ctx)
+	}
+	if env, ok := cmd.Get(runtime.String("Env")).(runtime.Object); ok {
+		// Added to the environment of the current process, overriding
+		// existing variables
+		c.Env = os.Environ()
+		keys := env.Keys(ctx).(runtime.Object)
+		for i := int64(0); i < keys.Len(ctx).Int(ctx); i++ {
+			k := keys.Get(runtime.Number(i))
+			c.Env = append(c.Env, k.String(ctx)+"="+env.Get(k).String(ctx))
+		}
+	}
+	switch in := cmd.Get(runtime.String("Stdin")).(type) {
+	case *file:
+		c.Stdin = in.reader()
+	default:
+		if in != runtime.Nil {
+			c.Stdin = bytes.NewReader(toBytes(ctx, in))
+		}
+	}
+	c.Stdout = &cmd.stdout
+	c.Stderr = &cmd.stderr
+	if e := c.Start(); e != nil {
+		panic(e)
+	}
+	cmd.c, cmd.ctx = c, ctx
+	return runtime.Nil
+}
+
+// Returns:
+// The result object, with the Stdout, Stderr and ExitCode fields, when the
+// process exits. A non-zero exit code is not an error, but it panics if the
+// execution context of the process is cancelled.
+func (cmd *command) wait(ctx context.Context, args ...runtime.Val) runtime.Val {
+	if cmd.c == nil {
+		panic("os: command not started")
+	}
+	e := cmd.c.Wait()
+	if cmd.ctx.Err() != nil {
+		panic(cmd.ctx.Err())
+	}
+	var ee *exec.ExitError
+	if e != nil && !errors.As(e, &ee) {
+		panic(e)
+	}
+	ob := runtime.NewObject()
+	ob.Set(runtime.String("Stdout"), runtime.String(cmd.stdout.String()))
+	ob.Set(runtime.String("Stderr"), runtime.String(cmd.stderr.String()))
+	ob.Set(runtime.String("ExitCode"), runtime.Number(cmd.c.ProcessState.ExitCode()))
+	return ob
+}
+
+// Returns:
+// The result object, see wait.
+func (cmd *command) run(ctx context.Context, args ...runtime.Val) runtime.Val {
+	cmd.start(ctx)
+	return cmd.wait(ctx)
+}
+
+// Returns:
+// The standard output of the process, it panics with the standard error if
+// the exit code is not 0.
+func (cmd *command) output(ctx context.Context, args ...runtime.Val) runtime.Val {
+	res := cmd.run(ctx).(runtime.Object)
+	if code := res.Get(runtime.String("ExitCode")).Int(ctx); code != 0 {
+		panic(fmt.Errorf("os: %s: exit code %d: %s", cmd.name, code, bytes.TrimSpace(cmd.stderr.Bytes())))
+	}
+	return res.Get(runtime.String("Stdout"))
+}
+
+func (cmd *command) kill(ctx context.Context, args ...runtime.Val) runtime.Val {
+	if cmd.c == nil {
+		panic("os: command not started")
+	}
+	if e := cmd.c.Process.Kill(); e != nil && !errors.Is(e, os.ErrProcessDone) {
+		panic(e)
+	}
+	return runtime.Nil
+}
+
 func (o *OsMod) os_Mkdir(ctx context.Context, args ...runtime.Val) runtime.Val {
 	// No-op if no arg
 	if len(args) == 0 {
@@ -182,11 +380,23 @@ func (o *OsMod) os_Mkdir(ctx context.Context, args ...runtime.Val) runtime.Val {
 	return runtime.Nil
 }
 
-func createFileInfo(fi os.FileInfo) runtime.Val {
+// Returns the file info object of the file at path, described by fi. The
+// Target field is set only for symbolic links.
+func createFileInfo(ktx *runtime.Kontext, path string, fi os.FileInfo) runtime.Val {
 	o := runtime.NewObject()
 	o.Set(runtime.String("Name"), runtime.String(fi.Name()))
 	o.Set(runtime.String("Size"), runtime.Number(fi.Size()))
 	o.Set(runtime.String("IsDir"), runtime.Bool(fi.IsDir()))
+	o.Set(runtime.String("Mode"), runtime.Number(fi.Mode()))
+	o.Set(runtime.String("Perm"), runtime.Number(fi.Mode().Perm()))
+	o.Set(runtime.String("ModTime"), (&TimeMod{ktx: ktx}).newTime(fi.ModTime()))
+	isLink := fi.Mode()&os.ModeSymlink != 0
+	o.Set(runtime.String("IsSymlink"), runtime.Bool(isLink))
+	if isLink {
+		if t, e := os.Readlink(path); e == nil {
+			o.Set(runtime.String("Target"), runtime.String(t))
+		}
+	}
 	return o
 }
 
@@ -198,11 +408,40 @@ func (o *OsMod) os_ReadDir(ctx context.Context, args ...runtime.Val) runtime.Val
 	}
 	ob := runtime.NewObject()
 	for i, fi := range fis {
-		ob.Set(runtime.Number(i), createFileInfo(fi))
+		ob.Set(runtime.Number(i), createFileInfo(o.ktx, filepath.Join(args[0].String(ctx), fi.Name()), fi))
 	}
 	return ob
 }
 
+// Args:
+// 0 - The path of the file
+// Returns:
+// The file info object, following symbolic links.
+func (o *OsMod) os_Stat(ctx context.Context, args ...runtime.Val) runtime.Val {
+	runtime.ExpectAtLeastNArgs(1, args)
+	p := args[0].String(ctx)
+	fi, e := os.Stat(p)
+	if e != nil {
+		panic(e)
+	}
+	return createFileInfo(o.ktx, p, fi)
+}
+
+// Args:
+// 0 - The path of the file
+// Returns:
+// The file info object, describing the symbolic link itself if the file is
+// a symbolic link.
+func (o *OsMod) os_Lstat(ctx context.Context, args ...runtime.Val) runtime.Val {
+	runtime.ExpectAtLeastNArgs(1, args)
+	p := args[0].String(ctx)
+	fi, e := os.Lstat(p)
+	if e != nil {
+		panic(e)
+	}
+	return createFileInfo(o.ktx, p, fi)
+}
+
 func (o *OsMod) os_Remove(ctx context.Context, args ...runtime.Val) runtime.Val {
 	for _, v := range args {
 		if e := os.Remove(v.String(ctx)); e != nil {
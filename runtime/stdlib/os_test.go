@@ -5,7 +5,9 @@ import (
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/saward/agora/runtime"
 )
@@ -63,6 +65,53 @@ func TestOsOpen(t *testing.T) {
 	}
 }
 
+func TestOsRead(t *testing.T) {
+	ctx := context.Background()
+	ktx := runtime.NewKtx(nil, nil)
+	om := new(OsMod)
+	om.SetKtx(ktx)
+	fl := om.os_Open(ctx, runtime.String("./testdata/readfile.txt")).(*file)
+	defer fl.closeFile(ctx)
+	ret := fl.read(ctx, runtime.Number(1))
+	if b, ok := ret.(*buffer); !ok || string(b.b) != "o" {
+		t.Errorf("expected read to return buffer 'o', got '%v'", ret)
+	}
+	ret = fl.readAll(ctx)
+	if b, ok := ret.(*buffer); !ok || string(b.b) != "k\n\n" {
+		t.Errorf("expected read all to return buffer 'k\\n\\n', got '%v'", ret)
+	}
+	ret = fl.read(ctx, runtime.Number(10))
+	if ret != runtime.Nil {
+		t.Errorf("expected read at end of file to be nil, got '%v'", ret)
+	}
+	ret = fl.readAll(ctx)
+	if b, ok := ret.(*buffer); !ok || len(b.b) != 0 {
+		t.Errorf("expected read all at end of file to return an empty buffer, got '%v'", ret)
+	}
+
+	// Reading lines and bytes share the same buffer
+	fl.seek(ctx)
+	ret = fl.readLine(ctx)
+	if ret.String(ctx) != "ok" {
+		t.Errorf("expected read line to return 'ok', got '%v'", ret)
+	}
+	ret = fl.readAll(ctx)
+	if b, ok := ret.(*buffer); !ok || string(b.b) != "\n" {
+		t.Errorf("expected read all after read line to return buffer '\\n', got '%v'", ret)
+	}
+	// Seeking relative to the current offset ignores the buffered bytes
+	fl.seek(ctx)
+	fl.readLine(ctx)
+	ret = fl.seek(ctx, runtime.Number(-2), runtime.Number(1))
+	if ret.Int(ctx) != 1 {
+		t.Errorf("expected seek to return offset 1, got %d", ret.Int(ctx))
+	}
+	ret = fl.read(ctx, runtime.Number(2))
+	if b, ok := ret.(*buffer); !ok || string(b.b) != "k\n" {
+		t.Errorf("expected read after seek to return buffer 'k\\n', got '%v'", ret)
+	}
+}
+
 func TestOsWrite(t *testing.T) {
 	ctx := context.Background()
 	ktx := runtime.NewKtx(nil, nil)
@@ -89,6 +138,51 @@ func TestOsWrite(t *testing.T) {
 	}
 }
 
+func TestOsReadWrite(t *testing.T) {
+	ctx := context.Background()
+	ktx := runtime.NewKtx(nil, nil)
+	om := new(OsMod)
+	om.SetKtx(ktx)
+	f, err := ioutil.TempFile("", "agora-rw")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	fl := om.newFile(f)
+	defer fl.closeFile(ctx)
+
+	// Writing after a read starts after the bytes read
+	fl.writeLine(ctx, runtime.Number(1))
+	fl.writeLine(ctx, runtime.Number(2))
+	fl.seek(ctx)
+	if l := fl.readLine(ctx); l.String(ctx) != "1" {
+		t.Errorf("expected the first line to be 1, got '%v'", l)
+	}
+	fl.writeLine(ctx, runtime.Number(3))
+	b, err := ioutil.ReadFile(f.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != "1\n3\n" {
+		t.Errorf("expected the file to contain '1\\n3\\n', got %q", b)
+	}
+
+	// And so does fmt.Fprintf
+	fm := new(FmtMod)
+	fm.SetKtx(ktx)
+	fl.seek(ctx)
+	fl.read(ctx, runtime.Number(1))
+	if n := fm.fmt_Fprintf(ctx, fl, runtime.String("%d"), runtime.Number(4)); n.Int(ctx) != 1 {
+		t.Errorf("expected Fprintf to write 1 byte, got %v", n)
+	}
+	if b, err = ioutil.ReadFile(f.Name()); err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != "143\n" {
+		t.Errorf("expected the file to contain '143\\n', got %q", b)
+	}
+}
+
 func TestOsFields(t *testing.T) {
 	ctx := context.Background()
 	ktx := runtime.NewKtx(nil, nil)
@@ -136,6 +230,77 @@ func TestOsExec(t *testing.T) {
 	}
 }
 
+func TestOsCommand(t *testing.T) {
+	ctx := context.Background()
+	ktx := runtime.NewKtx(nil, nil)
+	om := new(OsMod)
+	om.SetKtx(ktx)
+	call := func(ob runtime.Val, nm string) runtime.Val {
+		return ob.(runtime.Object).Get(runtime.String(nm)).(runtime.Func).Call(ctx, nil)
+	}
+	get := func(ob runtime.Val, nm string) runtime.Val {
+		return ob.(runtime.Object).Get(runtime.String(nm))
+	}
+
+	// Stdout, stderr, exit code, env, dir and stdin
+	dir, err := ioutil.TempDir("", "agora-cmd")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	cmd := om.os_Command(ctx, runtime.String("sh"), runtime.String("-c"),
+		runtime.String(`read x; echo "$x $AGORA_TEST $(basename $PWD)"; echo err >&2; exit 3`)).(runtime.Object)
+	env := runtime.NewObject()
+	env.Set(runtime.String("AGORA_TEST"), runtime.String("env"))
+	cmd.Set(runtime.String("Env"), env)
+	cmd.Set(runtime.String("Dir"), runtime.String(dir))
+	cmd.Set(runtime.String("Stdin"), runtime.String("in\n"))
+	res := call(cmd, "Run")
+	if s, exp := get(res, "Stdout").String(ctx), "in env "+filepath.Base(dir)+"\n"; s != exp {
+		t.Errorf("expected stdout %q, got %q", exp, s)
+	}
+	if s := get(res, "Stderr").String(ctx); s != "err\n" {
+		t.Errorf("expected stderr %q, got %q", "err\n", s)
+	}
+	if c := get(res, "ExitCode"); c != runtime.Number(3) {
+		t.Errorf("expected exit code 3, got %v", c)
+	}
+	if err := assertErr(func() { call(cmd, "Run") }); err == nil {
+		t.Errorf("expected error when running twice")
+	}
+
+	// Output
+	cmd = om.os_Command(ctx, runtime.String("echo"), runtime.String("hello")).(runtime.Object)
+	if s := call(cmd, "Output").String(ctx); s != "hello\n" {
+		t.Errorf("expected %q, got %q", "hello\n", s)
+	}
+	cmd = om.os_Command(ctx, runtime.String("sh"), runtime.String("-c"), runtime.String("echo fail >&2; exit 1")).(runtime.Object)
+	if err := assertErr(func() { call(cmd, "Output") }); err == nil || !strings.Contains(err.Error(), "fail") {
+		t.Errorf("expected error with stderr, got %v", err)
+	}
+	cmd = om.os_Command(ctx, runtime.String("agora-no-such-command")).(runtime.Object)
+	if err := assertErr(func() { call(cmd, "Run") }); err == nil {
+		t.Errorf("expected error for unknown command")
+	}
+
+	// Start, Kill and Wait
+	cmd = om.os_Command(ctx, runtime.String("sleep"), runtime.String("10")).(runtime.Object)
+	call(cmd, "Start")
+	call(cmd, "Kill")
+	if c := get(call(cmd, "Wait"), "ExitCode"); c != runtime.Number(-1) {
+		t.Errorf("expected exit code -1 for a killed process, got %v", c)
+	}
+
+	// Cancellation
+	cctx, cancel := context.WithTimeout(ctx, 20*time.Millisecond)
+	defer cancel()
+	cmd = om.os_Command(cctx, runtime.String("sleep"), runtime.String("10")).(runtime.Object)
+	err = assertErr(func() { cmd.Get(runtime.String("Run")).(runtime.Func).Call(cctx, nil) })
+	if err != context.DeadlineExceeded {
+		t.Errorf("expected %v, got %v", context.DeadlineExceeded, err)
+	}
+}
+
 func TestOsGetenv(t *testing.T) {
 	ctx := context.Background()
 	ktx := runtime.NewKtx(nil, nil)
@@ -268,3 +433,58 @@ func TestOsMkRemRenReadDir(t *testing.T) {
 		t.Errorf("expected d2 to be deleted, got %s", e)
 	}
 }
+
+func TestOsStat(t *testing.T) {
+	ctx := context.Background()
+	ktx := runtime.NewKtx(nil, nil)
+	om := new(OsMod)
+	om.SetKtx(ktx)
+	dir, err := ioutil.TempDir("", "agora-stat")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	fn, ln := filepath.Join(dir, "f.txt"), filepath.Join(dir, "l.txt")
+	if err := ioutil.WriteFile(fn, []byte("hello"), 0640); err != nil {
+		t.Fatal(err)
+	}
+	mt := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	if err := os.Chtimes(fn, mt, mt); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink("f.txt", ln); err != nil {
+		t.Skip(err)
+	}
+
+	get := func(ob runtime.Val, nm string) runtime.Val {
+		return ob.(runtime.Object).Get(runtime.String(nm))
+	}
+	for i, p := range []string{fn, ln} {
+		fi := om.os_Stat(ctx, runtime.String(p))
+		if s := get(fi, "Size"); s != runtime.Number(5) {
+			t.Errorf("[%d] - expected size 5, got %v", i, s)
+		}
+		if m := get(fi, "Perm"); m != runtime.Number(0640) {
+			t.Errorf("[%d] - expected perm 0640, got %o", i, m.Int(ctx))
+		}
+		if u := get(fi, "ModTime").Int(ctx); u != mt.Unix() {
+			t.Errorf("[%d] - expected mtime %d, got %d", i, mt.Unix(), u)
+		}
+		if l := get(fi, "IsSymlink"); l != runtime.Bool(false) {
+			t.Errorf("[%d] - expected stat to follow the link", i)
+		}
+	}
+	fi := om.os_Lstat(ctx, runtime.String(ln))
+	if l := get(fi, "IsSymlink"); l != runtime.Bool(true) {
+		t.Errorf("expected a symlink")
+	}
+	if tg := get(fi, "Target"); tg != runtime.String("f.txt") {
+		t.Errorf("expected target f.txt, got %v", tg)
+	}
+	if d := get(om.os_Stat(ctx, runtime.String(dir)), "IsDir"); d != runtime.Bool(true) {
+		t.Errorf("expected a directory")
+	}
+	if err := assertErr(func() { om.os_Stat(ctx, runtime.String(filepath.Join(dir, "none"))) }); !os.IsNotExist(err) {
+		t.Errorf("expected not exist error, got %v", err)
+	}
+}
@@ -0,0 +1,271 @@
+package stdlib
+
+import (
+	"container/list"
+	"context"
+	"regexp"
+	"sync"
+
+	"github.com/saward/agora/runtime"
+)
+
+// The regexp module, as documented in
+// https://github.com/saward/agora/wiki/Standard-library
+type RegexpMod struct {
+	ktx *runtime.Kontext
+	ob  runtime.Object
+
+	// The most recently compiled patterns, so that compiling the same pattern
+	// again in this execution context is cheap. The list holds the patterns,
+	// most recently used first.
+	mu    sync.Mutex
+	cache map[string]*list.Element
+	lru   list.List
+}
+
+// The maximum number of compiled patterns cached by an execution context.
+const maxCachedRegexps = 64
+
+// An entry of the compiled patterns cache.
+type cachedRegexp struct {
+	pat string
+	re  *regexp.Regexp
+}
+
+func (r *RegexpMod) ID() string {
+	return "regexp"
+}
+
+func (r *RegexpMod) Run(_ context.Context, _ ...runtime.Val) (v runtime.Val, err error) {
+	defer runtime.PanicToError(&err)
+	if r.ob == nil {
+		// Prepare the object
+		r.ob = runtime.NewObject()
+		r.ob.Set(runtime.String("Compile"), runtime.NewNativeFunc(r.ktx, "regexp.Compile", r.regexp_Compile))
+		r.ob.Set(runtime.String("QuoteMeta"), runtime.NewNativeFunc(r.ktx, "regexp.QuoteMeta", r.regexp_QuoteMeta))
+	}
+	return r.ob, nil
+}
+
+func (r *RegexpMod) SetKtx(c *runtime.Kontext) {
+	r.ktx = c
+}
+
+func (r *RegexpMod) Clone() runtime.NativeModule {
+	return new(RegexpMod)
+}
+
+// Returns the compiled pattern, from the cache if it was recently compiled.
+// The least recently used pattern is evicted when the cache is full.
+func (r *RegexpMod) compile(pat string) *regexp.Regexp {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if e, ok := r.cache[pat]; ok {
+		r.lru.MoveToFront(e)
+		return e.Value.(*cachedRegexp).re
+	}
+	re, err := regexp.Compile(pat)
+	if err != nil {
+		panic(err)
+	}
+	if r.cache == nil {
+		r.cache = make(map[string]*list.Element)
+	}
+	if r.lru.Len() >= maxCachedRegexps {
+		e := r.lru.Back()
+		r.lru.Remove(e)
+		delete(r.cache, e.Value.(*cachedRegexp).pat)
+	}
+	r.cache[pat] = r.lru.PushFront(&cachedRegexp{pat, re})
+	return re
+}
+
+// Args:
+// 0 - The regular expression, in the syntax of Go's regexp package
+// Returns:
+// The regexp object, see the documentation for its methods.
+func (r *RegexpMod) regexp_Compile(ctx context.Context, args ...runtime.Val) runtime.Val {
+	runtime.ExpectAtLeastNArgs(1, args)
+	return r.newRegexp(r.compile(args[0].String(ctx)))
+}
+
+// Args:
+// 0 - The string to escape
+// Returns:
+// The string with all the regular expression metacharacters escaped.
+func (r *RegexpMod) regexp_QuoteMeta(ctx context.Context, args ...runtime.Val) runtime.Val {
+	runtime.ExpectAtLeastNArgs(1, args)
+	return runtime.String(regexp.QuoteMeta(args[0].String(ctx)))
+}
+
+type _regexp struct {
+	runtime.Object
+	re *regexp.Regexp
+}
+
+func (r *RegexpMod) newRegexp(re *regexp.Regexp) runtime.Val {
+	ob := &_regexp{
+		runtime.NewObject(),
+		re,
+	}
+	ob.Set(runtime.String("__string"), runtime.NewNativeFunc(r.ktx, "regexp._regexp.__string", func(_ context.Context, args ...runtime.Val) runtime.Val {
+		return runtime.String(ob.re.String())
+	}))
+	ob.Set(runtime.String("Match"), runtime.NewNativeFunc(r.ktx, "regexp._regexp.Match", ob.regexp_Match))
+	ob.Set(runtime.String("Find"), runtime.NewNativeFunc(r.ktx, "regexp._regexp.Find", ob.regexp_Find))
+	ob.Set(runtime.String("FindAll"), runtime.NewNativeFunc(r.ktx, "regexp._regexp.FindAll", ob.regexp_FindAll))
+	ob.Set(runtime.String("FindSubmatch"), runtime.NewNativeFunc(r.ktx, "regexp._regexp.FindSubmatch", ob.regexp_FindSubmatch))
+	ob.Set(runtime.String("FindAllSubmatch"), runtime.NewNativeFunc(r.ktx, "regexp._regexp.FindAllSubmatch", ob.regexp_FindAllSubmatch))
+	ob.Set(runtime.String("ReplaceAll"), runtime.NewNativeFunc(r.ktx, "regexp._regexp.ReplaceAll", ob.regexp_ReplaceAll))
+	ob.Set(runtime.String("Split"), runtime.NewNativeFunc(r.ktx, "regexp._regexp.Split", ob.regexp_Split))
+	return ob
+}
+
+// Returns the maximum number of results, provided as optional argument at
+// index i, or -1 for all results.
+func maxResults(ctx context.Context, args []runtime.Val, i int) int {
+	if len(args) > i {
+		return int(args[i].Int(ctx))
+	}
+	return -1
+}
+
+// Returns the match object of the submatch indices ix in src. The groups are
+// indexed by number, and the named groups are also set by name.
+func (r *_regexp) newMatch(src string, ix []int) runtime.Object {
+	ob := runtime.NewObject()
+	for i, nm := range r.re.SubexpNames() {
+		grp := runtime.NewObject()
+		start, end := ix[2*i], ix[2*i+1]
+		txt := ""
+		if start >= 0 {
+			txt = src[start:end]
+		}
+		grp.Set(runtime.String("Text"), runtime.String(txt))
+		grp.Set(runtime.String("Start"), runtime.Number(start))
+		grp.Set(runtime.String("End"), runtime.Number(end))
+		ob.Set(runtime.Number(i), grp)
+		if nm != "" {
+			ob.Set(runtime.String(nm), grp)
+		}
+	}
+	return ob
+}
+
+// Args:
+// 0 - The source string
+// Returns:
+// True if the source string contains a match of the regexp.
+func (r *_regexp) regexp_Match(ctx context.Context, args ...runtime.Val) runtime.Val {
+	runtime.ExpectAtLeastNArgs(1, args)
+	return runtime.Bool(r.re.MatchString(args[0].String(ctx)))
+}
+
+// Args:
+// 0 - The source string
+// Returns:
+// The text of the leftmost match in the source string, or nil if there is
+// none.
+func (r *_regexp) regexp_Find(ctx context.Context, args ...runtime.Val) runtime.Val {
+	runtime.ExpectAtLeastNArgs(1, args)
+	src := args[0].String(ctx)
+	ix := r.re.FindStringIndex(src)
+	if ix == nil {
+		return runtime.Nil
+	}
+	return runtime.String(src[ix[0]:ix[1]])
+}
+
+// Args:
+// 0 - The source string
+// 1 - The maximum number of matches (optional)
+// Returns:
+// An array-like object holding the text of the successive matches, or nil
+// if there is none.
+func (r *_regexp) regexp_FindAll(ctx context.Context, args ...runtime.Val) runtime.Val {
+	runtime.ExpectAtLeastNArgs(1, args)
+	mtches := r.re.FindAllString(args[0].String(ctx), maxResults(ctx, args, 1))
+	if mtches == nil {
+		return runtime.Nil
+	}
+	ob := runtime.NewObject()
+	for i, m := range mtches {
+		ob.Set(runtime.Number(i), runtime.String(m))
+	}
+	return ob
+}
+
+// Args:
+// 0 - The source string
+// Returns:
+// The match object of the leftmost match, or nil if there is none.
+func (r *_regexp) regexp_FindSubmatch(ctx context.Context, args ...runtime.Val) runtime.Val {
+	runtime.ExpectAtLeastNArgs(1, args)
+	src := args[0].String(ctx)
+	ix := r.re.FindStringSubmatchIndex(src)
+	if ix == nil {
+		return runtime.Nil
+	}
+	return r.newMatch(src, ix)
+}
+
+// Args:
+// 0 - The source string
+// 1 - The maximum number of matches (optional)
+// Returns:
+// An array-like object holding the match objects of the successive matches,
+// or nil if there is none.
+func (r *_regexp) regexp_FindAllSubmatch(ctx context.Context, args ...runtime.Val) runtime.Val {
+	runtime.ExpectAtLeastNArgs(1, args)
+	src := args[0].String(ctx)
+	ixs := r.re.FindAllStringSubmatchIndex(src, maxResults(ctx, args, 1))
+	if ixs == nil {
+		return runtime.Nil
+	}
+	ob := runtime.NewObject()
+	for i, ix := range ixs {
+		ob.Set(runtime.Number(i), r.newMatch(src, ix))
+	}
+	return ob
+}
+
+// Args:
+// 0 - The source string
+// 1 - The replacement, a string where $1 or ${name} are replaced by the
+// matching group, or a function called with the match object of each match
+// and that returns the replacement
+// Returns:
+// The source string with all matches replaced.
+func (r *_regexp) regexp_ReplaceAll(ctx context.Context, args ...runtime.Val) runtime.Val {
+	runtime.ExpectAtLeastNArgs(2, args)
+	src := args[0].String(ctx)
+	fn, ok := args[1].(runtime.Func)
+	if !ok {
+		return runtime.String(r.re.ReplaceAllString(src, args[1].String(ctx)))
+	}
+	var buf []byte
+	last := 0
+	for _, ix := range r.re.FindAllStringSubmatchIndex(src, -1) {
+		buf = append(buf, src[last:ix[0]]...)
+		buf = append(buf, fn.Call(ctx, nil, r.newMatch(src, ix)).String(ctx)...)
+		last = ix[1]
+	}
+	buf = append(buf, src[last:]...)
+	return runtime.String(buf)
+}
+
+// Args:
+// 0 - The source string
+// 1 - The maximum number of parts (optional)
+// Returns:
+// An array-like object holding the parts of the source string between the
+// matches.
+func (r *_regexp) regexp_Split(ctx context.Context, args ...runtime.Val) runtime.Val {
+	runtime.ExpectAtLeastNArgs(1, args)
+	parts := r.re.Split(args[0].String(ctx), maxResults(ctx, args, 1))
+	ob := runtime.NewObject()
+	for i, p := range parts {
+		ob.Set(runtime.Number(i), runtime.String(p))
+	}
+	return ob
+}
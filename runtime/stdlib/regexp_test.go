@@ -0,0 +1,137 @@
+package stdlib
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/saward/agora/runtime"
+)
+
+// Returns the string representation of the texts of the groups of the match
+// object, separated by |.
+func matchString(ctx context.Context, v runtime.Val) string {
+	ob := v.(runtime.Object)
+	var s []string
+	for i, n := int64(0), ob.Len(ctx).Int(ctx); i < n; i++ {
+		grp, ok := ob.Get(runtime.Number(i)).(runtime.Object)
+		if !ok {
+			break
+		}
+		s = append(s, grp.Get(runtime.String("Text")).String(ctx))
+	}
+	return strings.Join(s, "|")
+}
+
+func TestRegexp(t *testing.T) {
+	ctx := context.Background()
+	ktx := runtime.NewKtx(nil, nil)
+	rm := new(RegexpMod)
+	rm.SetKtx(ktx)
+	re := rm.regexp_Compile(ctx, runtime.String(`(?P<key>\w+)=(?P<val>\d+)?`)).(*_regexp)
+	src := runtime.String("a=1, b=, c=33")
+
+	if v := re.regexp_Match(ctx, src); v != runtime.Bool(true) {
+		t.Errorf("expected a match, got %v", v)
+	}
+	if v := re.regexp_Match(ctx, runtime.String("nope")); v != runtime.Bool(false) {
+		t.Errorf("expected no match, got %v", v)
+	}
+	if v := re.regexp_Find(ctx, src); v != runtime.String("a=1") {
+		t.Errorf("expected a=1, got %v", v)
+	}
+	if v := re.regexp_Find(ctx, runtime.String("")); v != runtime.Nil {
+		t.Errorf("expected nil, got %v", v)
+	}
+	if v := strings.Join(stringsOf(ctx, re.regexp_FindAll(ctx, src, runtime.Number(2))), ","); v != "a=1,b=" {
+		t.Errorf("expected a=1,b=, got %s", v)
+	}
+	if v := re.regexp_FindAll(ctx, runtime.String("")); v != runtime.Nil {
+		t.Errorf("expected nil, got %v", v)
+	}
+
+	m := re.regexp_FindSubmatch(ctx, src).(runtime.Object)
+	if s := matchString(ctx, m); s != "a=1|a|1" {
+		t.Errorf("expected a=1|a|1, got %s", s)
+	}
+	key := m.Get(runtime.String("key")).(runtime.Object)
+	if key.Get(runtime.String("Text")) != runtime.String("a") || key.Get(runtime.String("Start")) != runtime.Number(0) {
+		t.Errorf("unexpected named group %s", key)
+	}
+	all := re.regexp_FindAllSubmatch(ctx, src).(runtime.Object)
+	if n := all.Len(ctx).Int(ctx); n != 3 {
+		t.Fatalf("expected 3 matches, got %d", n)
+	}
+	val := all.Get(runtime.Number(1)).(runtime.Object).Get(runtime.String("val")).(runtime.Object)
+	if val.Get(runtime.String("Text")) != runtime.String("") || val.Get(runtime.String("Start")) != runtime.Number(-1) {
+		t.Errorf("expected an unmatched group, got %s", val)
+	}
+
+	if v := re.regexp_ReplaceAll(ctx, src, runtime.String("${val}:$key")); v != runtime.String("1:a, :b, 33:c") {
+		t.Errorf("unexpected replacement %v", v)
+	}
+	upper := runtime.NewNativeFunc(ktx, "upper", func(ctx context.Context, args ...runtime.Val) runtime.Val {
+		m := args[0].(runtime.Object)
+		return runtime.String(strings.ToUpper(m.Get(runtime.String("key")).(runtime.Object).Get(runtime.String("Text")).String(ctx)))
+	})
+	if v := re.regexp_ReplaceAll(ctx, src, upper); v != runtime.String("A, B, C") {
+		t.Errorf("unexpected replacement %v", v)
+	}
+
+	sp := rm.regexp_Compile(ctx, runtime.String(`\s*,\s*`)).(*_regexp)
+	if v := strings.Join(stringsOf(ctx, sp.regexp_Split(ctx, src)), "|"); v != "a=1|b=|c=33" {
+		t.Errorf("unexpected split %s", v)
+	}
+	if v := sp.String(ctx); v != `\s*,\s*` {
+		t.Errorf("expected the pattern as string, got %s", v)
+	}
+}
+
+func TestRegexpCache(t *testing.T) {
+	ctx := context.Background()
+	rm := new(RegexpMod)
+	rm.SetKtx(runtime.NewKtx(nil, nil))
+	a := rm.regexp_Compile(ctx, runtime.String(`a+`)).(*_regexp)
+	b := rm.regexp_Compile(ctx, runtime.String(`a+`)).(*_regexp)
+	if a == b || a.re != b.re {
+		t.Errorf("expected distinct objects sharing the compiled pattern")
+	}
+	other := new(RegexpMod)
+	other.SetKtx(runtime.NewKtx(nil, nil))
+	if c := other.regexp_Compile(ctx, runtime.String(`a+`)).(*_regexp); c.re == a.re {
+		t.Errorf("expected the cache to be per execution context")
+	}
+	if err := assertErr(func() { rm.regexp_Compile(ctx, runtime.String(`(`)) }); err == nil {
+		t.Errorf("expected an error for an invalid pattern")
+	}
+	// The cache is bounded, the least recently used pattern is evicted
+	for i := 0; i < maxCachedRegexps; i++ {
+		if i == maxCachedRegexps/2 {
+			rm.regexp_Compile(ctx, runtime.String(`a+`))
+		}
+		rm.regexp_Compile(ctx, runtime.String(fmt.Sprintf("b{%d}", i)))
+	}
+	if len(rm.cache) != maxCachedRegexps || rm.lru.Len() != maxCachedRegexps {
+		t.Errorf("expected %d cached patterns, got %d", maxCachedRegexps, len(rm.cache))
+	}
+	if _, ok := rm.cache[`a+`]; !ok {
+		t.Errorf("expected the recently used pattern to be kept")
+	}
+	if _, ok := rm.cache[`b{0}`]; ok {
+		t.Errorf("expected the least recently used pattern to be evicted")
+	}
+	if v := rm.regexp_QuoteMeta(ctx, runtime.String("a.b")); v != runtime.String(`a\.b`) {
+		t.Errorf("unexpected quoted string %v", v)
+	}
+}
+
+// Returns the strings of the array-like object v.
+func stringsOf(ctx context.Context, v runtime.Val) []string {
+	ob := v.(runtime.Object)
+	s := make([]string, ob.Len(ctx).Int(ctx))
+	for i := range s {
+		s[i] = ob.Get(runtime.Number(i)).String(ctx)
+	}
+	return s
+}
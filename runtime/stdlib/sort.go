@@ -0,0 +1,168 @@
+package stdlib
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/saward/agora/runtime"
+)
+
+// The sort module, as documented in
+// https://github.com/saward/agora/wiki/Standard-library
+type SortMod struct {
+	ktx *runtime.Kontext
+	ob  runtime.Object
+}
+
+func (s *SortMod) ID() string {
+	return "sort"
+}
+
+func (s *SortMod) Run(_ context.Context, _ ...runtime.Val) (v runtime.Val, err error) {
+	defer runtime.PanicToError(&err)
+	if s.ob == nil {
+		// Prepare the object
+		s.ob = runtime.NewObject()
+		s.ob.Set(runtime.String("Sort"), runtime.NewNativeFunc(s.ktx, "sort.Sort", s.sort_Sort))
+		s.ob.Set(runtime.String("Stable"), runtime.NewNativeFunc(s.ktx, "sort.Stable", s.sort_Stable))
+		s.ob.Set(runtime.String("IsSorted"), runtime.NewNativeFunc(s.ktx, "sort.IsSorted", s.sort_IsSorted))
+		s.ob.Set(runtime.String("Keys"), runtime.NewNativeFunc(s.ktx, "sort.Keys", s.sort_Keys))
+	}
+	return s.ob, nil
+}
+
+func (s *SortMod) SetKtx(c *runtime.Kontext) {
+	s.ktx = c
+}
+
+func (s *SortMod) Clone() runtime.NativeModule {
+	return new(SortMod)
+}
+
+// Returns the comparison function, the agora func provided as optional
+// argument at index i or the comparer of the execution context.
+func (s *SortMod) cmpFunc(ctx context.Context, args []runtime.Val, i int) func(a, b runtime.Val) int {
+	if len(args) > i && args[i] != runtime.Nil {
+		fn, ok := args[i].(runtime.Func)
+		if !ok {
+			panic(runtime.NewTypeError(runtime.Type(args[i]), "", "sort comparison"))
+		}
+		return func(a, b runtime.Val) int {
+			return int(fn.Call(ctx, nil, a, b).Int(ctx))
+		}
+	}
+	return func(a, b runtime.Val) int {
+		return s.ktx.Comparer.Cmp(ctx, a, b)
+	}
+}
+
+// Returns the values of the array to sort, and the function that stores the
+// sorted values back in the array. The array is either an array-like object,
+// indexed from 0 to its length - 1, or a custom value whose native value is
+// a slice of values, which is then sorted in place.
+func sortable(ctx context.Context, v runtime.Val) ([]runtime.Val, func([]runtime.Val)) {
+	switch v := v.(type) {
+	case runtime.Object:
+		n := int(v.Len(ctx).Int(ctx))
+		vals := make([]runtime.Val, n)
+		for i := range vals {
+			vals[i] = v.Get(runtime.Number(i))
+			if vals[i] == runtime.Nil {
+				panic(fmt.Sprintf("sort: object is not array-like, missing index %d", i))
+			}
+		}
+		return vals, func(vals []runtime.Val) {
+			for i, val := range vals {
+				v.Set(runtime.Number(i), val)
+			}
+		}
+	case runtime.Func:
+	default:
+		if v != runtime.Nil {
+			if vals, ok := v.Native(ctx).([]runtime.Val); ok {
+				return vals, func([]runtime.Val) {}
+			}
+		}
+	}
+	panic(runtime.NewTypeError(runtime.Type(v), "", "sort"))
+}
+
+// Sorts the array provided as first argument, using the comparison function
+// provided as optional second argument, and returns the array.
+func (s *SortMod) sortArray(ctx context.Context, args []runtime.Val, stable bool) runtime.Val {
+	runtime.ExpectAtLeastNArgs(1, args)
+	vals, store := sortable(ctx, args[0])
+	cmp := s.cmpFunc(ctx, args, 1)
+	less := func(i, j int) bool {
+		return cmp(vals[i], vals[j]) < 0
+	}
+	if stable {
+		sort.SliceStable(vals, less)
+	} else {
+		sort.Slice(vals, less)
+	}
+	store(vals)
+	return args[0]
+}
+
+// Args:
+// 0 - The array-like object to sort in place
+// 1 - The comparison function (optional), called with two values and that
+// returns a negative number if the first is lower than the second, 0 if they
+// are equal and a positive number otherwise
+// Returns:
+// The sorted array.
+func (s *SortMod) sort_Sort(ctx context.Context, args ...runtime.Val) runtime.Val {
+	return s.sortArray(ctx, args, false)
+}
+
+// Args:
+// 0 - The array-like object to sort in place
+// 1 - The comparison function (optional), see Sort
+// Returns:
+// The sorted array, where equal values keep their original order.
+func (s *SortMod) sort_Stable(ctx context.Context, args ...runtime.Val) runtime.Val {
+	return s.sortArray(ctx, args, true)
+}
+
+// Args:
+// 0 - The array-like object
+// 1 - The comparison function (optional), see Sort
+// Returns:
+// True if the array is sorted.
+func (s *SortMod) sort_IsSorted(ctx context.Context, args ...runtime.Val) runtime.Val {
+	runtime.ExpectAtLeastNArgs(1, args)
+	vals, _ := sortable(ctx, args[0])
+	cmp := s.cmpFunc(ctx, args, 1)
+	for i := 1; i < len(vals); i++ {
+		if cmp(vals[i], vals[i-1]) < 0 {
+			return runtime.Bool(false)
+		}
+	}
+	return runtime.Bool(true)
+}
+
+// Args:
+// 0 - The object
+// 1 - The comparison function (optional), see Sort
+// Returns:
+// An array-like object holding the keys of the object, sorted.
+func (s *SortMod) sort_Keys(ctx context.Context, args ...runtime.Val) runtime.Val {
+	runtime.ExpectAtLeastNArgs(1, args)
+	ob, ok := args[0].(runtime.Object)
+	if !ok {
+		panic(runtime.NewTypeError(runtime.Type(args[0]), "", "sort.Keys"))
+	}
+	keys := ob.Keys(ctx)
+	vals, _ := sortable(ctx, keys)
+	cmp := s.cmpFunc(ctx, args, 1)
+	sort.SliceStable(vals, func(i, j int) bool {
+		return cmp(vals[i], vals[j]) < 0
+	})
+	res := runtime.NewObject()
+	for i, k := range vals {
+		res.Set(runtime.Number(i), k)
+	}
+	return res
+}
@@ -0,0 +1,134 @@
+package stdlib
+
+import (
+	"context"
+	"testing"
+
+	"github.com/saward/agora/runtime"
+)
+
+// A custom value holding a native array of values.
+type valSlice []runtime.Val
+
+func (v valSlice) Int(context.Context) int64          { return 0 }
+func (v valSlice) Float(context.Context) float64      { return 0 }
+func (v valSlice) String(context.Context) string      { return "valSlice" }
+func (v valSlice) Bool(context.Context) bool          { return true }
+func (v valSlice) Native(context.Context) interface{} { return []runtime.Val(v) }
+
+func newArray(vals ...runtime.Val) runtime.Object {
+	ob := runtime.NewObject()
+	for i, v := range vals {
+		ob.Set(runtime.Number(i), v)
+	}
+	return ob
+}
+
+func checkArray(t *testing.T, ctx context.Context, i int, ob runtime.Object, exp ...runtime.Val) {
+	if n := ob.Len(ctx).Int(ctx); n != int64(len(exp)) {
+		t.Errorf("[%d] - expected length %d, got %d", i, len(exp), n)
+		return
+	}
+	for j, e := range exp {
+		if v := ob.Get(runtime.Number(j)); v != e {
+			t.Errorf("[%d] - expected %v at index %d, got %v", i, e, j, v)
+		}
+	}
+}
+
+func TestSortSort(t *testing.T) {
+	ctx := context.Background()
+	ktx := runtime.NewKtx(nil, nil)
+	sm := new(SortMod)
+	sm.SetKtx(ktx)
+
+	// Default comparer
+	ob := newArray(runtime.Number(3), runtime.Number(-1), runtime.Number(2), runtime.Number(10))
+	res := sm.sort_Sort(ctx, ob)
+	if res != ob {
+		t.Errorf("expected the sorted array to be returned")
+	}
+	checkArray(t, ctx, 0, ob, runtime.Number(-1), runtime.Number(2), runtime.Number(3), runtime.Number(10))
+	ob = newArray(runtime.String("b"), runtime.String("c"), runtime.String("a"))
+	sm.sort_Sort(ctx, ob)
+	checkArray(t, ctx, 1, ob, runtime.String("a"), runtime.String("b"), runtime.String("c"))
+
+	// Custom comparison, in descending order
+	desc := runtime.NewNativeFunc(ktx, "", func(ctx context.Context, args ...runtime.Val) runtime.Val {
+		return runtime.Number(ktx.Comparer.Cmp(ctx, args[1], args[0]))
+	})
+	ob = newArray(runtime.Number(1), runtime.Number(3), runtime.Number(2))
+	sm.sort_Sort(ctx, ob, desc)
+	checkArray(t, ctx, 2, ob, runtime.Number(3), runtime.Number(2), runtime.Number(1))
+	if !sm.sort_IsSorted(ctx, ob, desc).Bool(ctx) {
+		t.Errorf("expected array to be sorted in descending order")
+	}
+	if sm.sort_IsSorted(ctx, ob).Bool(ctx) {
+		t.Errorf("expected array not to be sorted in ascending order")
+	}
+
+	// Empty object
+	ob = runtime.NewObject()
+	sm.sort_Sort(ctx, ob)
+	checkArray(t, ctx, 3, ob)
+
+	// Native array
+	vs := valSlice{runtime.Number(2), runtime.Number(1)}
+	sm.sort_Sort(ctx, vs)
+	if vs[0] != runtime.Number(1) || vs[1] != runtime.Number(2) {
+		t.Errorf("expected native array to be sorted, got %v", vs)
+	}
+
+	// Errors
+	ob = runtime.NewObject()
+	ob.Set(runtime.String("a"), runtime.Number(1))
+	if err := assertErr(func() { sm.sort_Sort(ctx, ob) }); err == nil {
+		t.Errorf("expected error for a non array-like object")
+	}
+	if err := assertErr(func() { sm.sort_Sort(ctx, runtime.Number(1)) }); err == nil {
+		t.Errorf("expected error for a number")
+	}
+	if err := assertErr(func() { sm.sort_Sort(ctx, newArray(runtime.Number(1)), runtime.Number(1)) }); err == nil {
+		t.Errorf("expected error for a comparison that is not a func")
+	}
+}
+
+func TestSortStable(t *testing.T) {
+	ctx := context.Background()
+	ktx := runtime.NewKtx(nil, nil)
+	sm := new(SortMod)
+	sm.SetKtx(ktx)
+
+	item := func(k, v string) runtime.Object {
+		ob := runtime.NewObject()
+		ob.Set(runtime.String("K"), runtime.String(k))
+		ob.Set(runtime.String("V"), runtime.String(v))
+		return ob
+	}
+	byKey := runtime.NewNativeFunc(ktx, "", func(ctx context.Context, args ...runtime.Val) runtime.Val {
+		l, r := args[0].(runtime.Object), args[1].(runtime.Object)
+		return runtime.Number(ktx.Comparer.Cmp(ctx, l.Get(runtime.String("K")), r.Get(runtime.String("K"))))
+	})
+	a1, b1, a2, b2, a3 := item("a", "1"), item("b", "1"), item("a", "2"), item("b", "2"), item("a", "3")
+	ob := newArray(b1, a1, b2, a2, a3)
+	sm.sort_Stable(ctx, ob, byKey)
+	checkArray(t, ctx, 0, ob, a1, a2, a3, b1, b2)
+}
+
+func TestSortKeys(t *testing.T) {
+	ctx := context.Background()
+	ktx := runtime.NewKtx(nil, nil)
+	sm := new(SortMod)
+	sm.SetKtx(ktx)
+
+	ob := runtime.NewObject()
+	for _, k := range []string{"z", "b", "m", "a"} {
+		ob.Set(runtime.String(k), runtime.Bool(true))
+	}
+	ob.Set(runtime.Number(2), runtime.Bool(true))
+	keys := sm.sort_Keys(ctx, ob).(runtime.Object)
+	checkArray(t, ctx, 0, keys, runtime.Number(2), runtime.String("a"), runtime.String("b"), runtime.String("m"), runtime.String("z"))
+	if err := assertErr(func() { sm.sort_Keys(ctx, runtime.String("a")) }); err == nil {
+		t.Errorf("expected error for a string")
+	}
+}
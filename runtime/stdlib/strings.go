@@ -5,6 +5,8 @@ import (
 	"context"
 	"regexp"
 	"strings"
+	"unicode"
+	"unicode/utf8"
 
 	"github.com/saward/agora/runtime"
 )
@@ -41,6 +43,9 @@ func (s *StringsMod) Run(_ context.Context, _ ...runtime.Val) (v runtime.Val, er
 		s.ob.Set(runtime.String("Replace"), runtime.NewNativeFunc(s.ktx, "strings.Replace", s.strings_Replace))
 		s.ob.Set(runtime.String("Repeat"), runtime.NewNativeFunc(s.ktx, "strings.Repeat", s.strings_Repeat))
 		s.ob.Set(runtime.String("Trim"), runtime.NewNativeFunc(s.ktx, "strings.Trim", s.strings_Trim))
+		s.ob.Set(runtime.String("Fields"), runtime.NewNativeFunc(s.ktx, "strings.Fields", s.strings_Fields))
+		s.ob.Set(runtime.String("Title"), runtime.NewNativeFunc(s.ktx, "strings.Title", s.strings_Title))
+		s.ob.Set(runtime.String("Map"), runtime.NewNativeFunc(s.ktx, "strings.Map", s.strings_Map))
 	}
 	return s.ob, nil
 }
@@ -49,6 +54,10 @@ func (s *StringsMod) SetKtx(c *runtime.Kontext) {
 	s.ktx = c
 }
 
+func (s *StringsMod) Clone() runtime.NativeModule {
+	return new(StringsMod)
+}
+
 // Converts strings to uppercase, concatenating all strings.
 // Args:
 // 0..n - The strings to convert to upper case and concatenate
@@ -179,7 +188,7 @@ func (s *StringsMod) strings_ByteAt(ctx context.Context, args ...runtime.Val) ru
 	if at < 0 || at >= len(src) {
 		return runtime.String("")
 	}
-	return runtime.String(src[at])
+	return runtime.String(src[at : at+1])
 }
 
 // Args:
@@ -378,3 +387,59 @@ func (s *StringsMod) strings_Trim(ctx context.Context, args ...runtime.Val) runt
 	}
 	return runtime.String(strings.Trim(src, cut))
 }
+
+// Args:
+// 0 - the source string
+// Returns:
+// An array-like object holding the parts of the string separated by unicode
+// whitespace.
+func (s *StringsMod) strings_Fields(ctx context.Context, args ...runtime.Val) runtime.Val {
+	runtime.ExpectAtLeastNArgs(1, args)
+	ob := runtime.NewObject()
+	for i, f := range strings.Fields(args[0].String(ctx)) {
+		ob.Set(runtime.Number(i), runtime.String(f))
+	}
+	return ob
+}
+
+// Args:
+// 0 - the source string
+// Returns:
+// The string with the first letter of each word in title case, the words
+// being separated by runes that are not letters, digits or apostrophes.
+func (s *StringsMod) strings_Title(ctx context.Context, args ...runtime.Val) runtime.Val {
+	runtime.ExpectAtLeastNArgs(1, args)
+	prev := ' '
+	return runtime.String(strings.Map(func(r rune) rune {
+		inWord := unicode.IsLetter(prev) || unicode.IsDigit(prev) || prev == '\''
+		prev = r
+		if inWord {
+			return r
+		}
+		return unicode.ToTitle(r)
+	}, args[0].String(ctx)))
+}
+
+// Args:
+// 0 - the source string
+// 1 - the func called with each rune of the string, as a string
+// Returns:
+// The string with each rune replaced by the string conversion of the value
+// returned by the func, the rune being dropped if it returns nil.
+func (s *StringsMod) strings_Map(ctx context.Context, args ...runtime.Val) runtime.Val {
+	runtime.ExpectAtLeastNArgs(2, args)
+	fn, ok := args[1].(runtime.Func)
+	if !ok {
+		panic(runtime.NewTypeError(runtime.Type(args[1]), "", "strings.Map"))
+	}
+	src := args[0].String(ctx)
+	buf := bytes.NewBuffer(nil)
+	for i := 0; i < len(src); {
+		_, n := utf8.DecodeRuneInString(src[i:])
+		if v := fn.Call(ctx, nil, runtime.String(src[i:i+n])); v != runtime.Nil {
+			buf.WriteString(v.String(ctx))
+		}
+		i += n
+	}
+	return runtime.String(buf.String())
+}
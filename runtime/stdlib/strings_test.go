@@ -168,6 +168,11 @@ func TestStringsByteAt(t *testing.T) {
 	if ret.String(ctx) != "" {
 		t.Errorf("expected byte %s at index %d, got %s", "", ix, ret)
 	}
+	// Multi-byte runes are not decoded
+	ret = sm.strings_ByteAt(ctx, runtime.String("é"), runtime.Number(1))
+	if ret.String(ctx) != "\xa9" {
+		t.Errorf("expected byte %q at index 1, got %q", "\xa9", ret)
+	}
 }
 
 func TestStringsConcat(t *testing.T) {
@@ -387,3 +392,50 @@ func TestStringsTrim(t *testing.T) {
 		}
 	}
 }
+
+func TestStringsFieldsTitleMap(t *testing.T) {
+	ctx := context.Background()
+	ktx := runtime.NewKtx(nil, nil)
+	sm := new(StringsMod)
+	sm.SetKtx(ktx)
+	ob := sm.strings_Fields(ctx, runtime.String(" José\t de\u00a0la  Peña\n")).(runtime.Object)
+	exp := []string{"José", "de", "la", "Peña"}
+	if n := ob.Len(ctx).Int(ctx); n != int64(len(exp)) {
+		t.Fatalf("expected %d fields, got %d", len(exp), n)
+	}
+	for i, e := range exp {
+		if f := ob.Get(runtime.Number(i)).String(ctx); f != e {
+			t.Errorf("[%d] - expected %s, got %s", i, e, f)
+		}
+	}
+
+	cases := map[string]string{
+		"élise o'neil":     "Élise O'neil",
+		"jean-luc 2nd":     "Jean-Luc 2nd",
+		"ǆemal дмитрий":    "ǅemal Дмитрий",
+		"":                 "",
+		"already Title Up": "Already Title Up",
+	}
+	for src, exp := range cases {
+		if ret := sm.strings_Title(ctx, runtime.String(src)).String(ctx); ret != exp {
+			t.Errorf("expected title %q for %q, got %q", exp, src, ret)
+		}
+	}
+
+	fn := runtime.NewNativeFunc(ktx, "", func(ctx context.Context, args ...runtime.Val) runtime.Val {
+		switch r := args[0].String(ctx); r {
+		case "ñ":
+			return runtime.String("n")
+		case "-":
+			return runtime.Nil
+		default:
+			return runtime.String(r + r)
+		}
+	})
+	if ret := sm.strings_Map(ctx, runtime.String("ña-é"), fn).String(ctx); ret != "naaéé" {
+		t.Errorf("expected %q, got %q", "naaéé", ret)
+	}
+	if err := assertErr(func() { sm.strings_Map(ctx, runtime.String("x"), runtime.Nil) }); err == nil {
+		t.Errorf("expected error for a non-func")
+	}
+}
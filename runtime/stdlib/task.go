@@ -0,0 +1,315 @@
+package stdlib
+
+import (
+	"context"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/saward/agora/runtime"
+)
+
+// The task module, as documented in
+// https://github.com/saward/agora/wiki/Standard-library
+type TaskMod struct {
+	ktx *runtime.Kontext
+	ob  runtime.Object
+
+	// NewKtx returns the execution context in which a task is run. If it is
+	// nil, the context is a clone of the module's context, with the same
+	// native modules (see runtime.Kontext.Clone), and the standard streams
+	// of the module's context are wrapped so that the accesses of the module
+	// and its tasks are serialized.
+	NewKtx func() *runtime.Kontext
+}
+
+func (t *TaskMod) ID() string {
+	return "task"
+}
+
+func (t *TaskMod) Run(_ context.Context, _ ...runtime.Val) (v runtime.Val, err error) {
+	defer runtime.PanicToError(&err)
+	if t.ob == nil {
+		// Prepare the object
+		t.ob = runtime.NewObject()
+		t.ob.Set(runtime.String("Spawn"), runtime.NewNativeFunc(t.ktx, "task.Spawn", t.task_Spawn))
+		t.ob.Set(runtime.String("Chan"), runtime.NewNativeFunc(t.ktx, "task.Chan", t.task_Chan))
+		t.ob.Set(runtime.String("Select"), runtime.NewNativeFunc(t.ktx, "task.Select", t.task_Select))
+	}
+	return t.ob, nil
+}
+
+func (t *TaskMod) SetKtx(c *runtime.Kontext) {
+	t.ktx = c
+}
+
+func (t *TaskMod) Clone() runtime.NativeModule {
+	return &TaskMod{NewKtx: t.NewKtx}
+}
+
+// Returns the execution context in which to run a task.
+func (t *TaskMod) newKtx() *runtime.Kontext {
+	if t.NewKtx != nil {
+		return t.NewKtx()
+	}
+	lockStreams(t.ktx)
+	return t.ktx.Clone()
+}
+
+// Values are passed between execution contexts in a detached form, that
+// holds no reference to a context: numbers, strings, bools and nil as is,
+// the bytes of buffers, the shared state of channels, and the fields of
+// objects.
+type (
+	xferObject []xferField
+	xferField  struct {
+		k, v interface{}
+	}
+)
+
+// Returns the detached copy of the value v. Funcs cannot be copied, and
+// neither can objects that hold themselves.
+func copyOut(ctx context.Context, v runtime.Val, seen map[runtime.Object]bool) interface{} {
+	switch v := v.(type) {
+	case nil:
+		return runtime.Nil
+	case runtime.Number, runtime.String, runtime.Bool:
+		return v
+	case *channel:
+		return v.st
+	case *buffer:
+		return append([]byte(nil), v.b...)
+	case runtime.Func:
+		panic(runtime.NewTypeError(runtime.Type(v), "", "task copy"))
+	case runtime.Object:
+		if seen[v] {
+			panic("task: cycle in the object to copy")
+		}
+		seen[v] = true
+		defer delete(seen, v)
+		keys := v.Keys(ctx).(runtime.Object)
+		n := int(keys.Len(ctx).Int(ctx))
+		ob := make(xferObject, 0, n)
+		for i := 0; i < n; i++ {
+			k := keys.Get(runtime.Number(i))
+			ob = append(ob, xferField{copyOut(ctx, k, seen), copyOut(ctx, v.Get(k), seen)})
+		}
+		return ob
+	}
+	if v == runtime.Nil {
+		return v
+	}
+	panic(runtime.NewTypeError(runtime.Type(v), "", "task copy"))
+}
+
+// Returns the value of the detached copy x in the execution context ktx.
+func copyIn(ktx *runtime.Kontext, x interface{}) runtime.Val {
+	switch x := x.(type) {
+	case *chanState:
+		return newChannel(ktx, x)
+	case []byte:
+		return newBuffer(ktx, x)
+	case xferObject:
+		ob := runtime.NewObject()
+		for _, f := range x {
+			ob.Set(copyIn(ktx, f.k), copyIn(ktx, f.v))
+		}
+		return ob
+	}
+	return x.(runtime.Val)
+}
+
+// Args:
+// 0 - The ID of the module to run
+// 1..n - The arguments of the module, deep-copied
+// Returns:
+// The task object, see the documentation for its methods.
+func (t *TaskMod) task_Spawn(ctx context.Context, args ...runtime.Val) runtime.Val {
+	runtime.ExpectAtLeastNArgs(1, args)
+	id := args[0].String(ctx)
+	xargs := make([]interface{}, len(args)-1)
+	for i, a := range args[1:] {
+		xargs[i] = copyOut(ctx, a, make(map[runtime.Object]bool))
+	}
+	k := t.newKtx()
+	tctx, cancel := context.WithCancel(ctx)
+	done := make(chan struct{})
+	var (
+		res interface{}
+		err error
+	)
+	go func() {
+		defer close(done)
+		defer cancel()
+		defer runtime.PanicToError(&err)
+		m, e := k.Load(id)
+		if e != nil {
+			panic(e)
+		}
+		targs := make([]runtime.Val, len(xargs))
+		for i, x := range xargs {
+			targs[i] = copyIn(k, x)
+		}
+		v, e := m.Run(tctx, targs...)
+		if e != nil {
+			panic(e)
+		}
+		res = copyOut(tctx, v, make(map[runtime.Object]bool))
+	}()
+
+	ob := runtime.NewObject()
+	ob.Set(runtime.String("Wait"), runtime.NewNativeFunc(t.ktx, "task.Task.Wait", func(ctx context.Context, args ...runtime.Val) runtime.Val {
+		select {
+		case <-done:
+		case <-ctx.Done():
+			panic(ctx.Err())
+		}
+		if err != nil {
+			panic(err)
+		}
+		return copyIn(t.ktx, res)
+	}))
+	ob.Set(runtime.String("Cancel"), runtime.NewNativeFunc(t.ktx, "task.Task.Cancel", func(ctx context.Context, args ...runtime.Val) runtime.Val {
+		cancel()
+		return runtime.Nil
+	}))
+	return ob
+}
+
+// The state of a channel, shared by the channel objects of the execution
+// contexts that it is passed to.
+type chanState struct {
+	ch   chan interface{}
+	once sync.Once
+}
+
+type channel struct {
+	runtime.Object
+	ktx *runtime.Kontext
+	st  *chanState
+}
+
+func newChannel(ktx *runtime.Kontext, st *chanState) *channel {
+	ob := &channel{
+		runtime.NewObject(),
+		ktx,
+		st,
+	}
+	ob.Set(runtime.String("__next"), runtime.NewNativeFunc(ktx, "task.Chan.__next", ob.recv))
+	ob.Set(runtime.String("Send"), runtime.NewNativeFunc(ktx, "task.Chan.Send", ob.send))
+	ob.Set(runtime.String("Recv"), runtime.NewNativeFunc(ktx, "task.Chan.Recv", ob.recv))
+	ob.Set(runtime.String("Close"), runtime.NewNativeFunc(ktx, "task.Chan.Close", ob.close))
+	return ob
+}
+
+// Args:
+// 0 - The capacity of the channel (optional, defaults to 0, unbuffered)
+// Returns:
+// The channel object, see the documentation for its methods.
+func (t *TaskMod) task_Chan(ctx context.Context, args ...runtime.Val) runtime.Val {
+	n := 0
+	if len(args) > 0 {
+		n = int(args[0].Int(ctx))
+	}
+	return newChannel(t.ktx, &chanState{ch: make(chan interface{}, n)})
+}
+
+// Args:
+// 0 - The value to send, deep-copied
+// Returns:
+// Nil, when the value is received or buffered.
+func (c *channel) send(ctx context.Context, args ...runtime.Val) runtime.Val {
+	runtime.ExpectAtLeastNArgs(1, args)
+	x := copyOut(ctx, args[0], make(map[runtime.Object]bool))
+	select {
+	case c.st.ch <- x:
+	case <-ctx.Done():
+		panic(ctx.Err())
+	}
+	return runtime.Nil
+}
+
+// Returns:
+// The received value, or nil if the channel is closed.
+func (c *channel) recv(ctx context.Context, args ...runtime.Val) runtime.Val {
+	select {
+	case x, ok := <-c.st.ch:
+		if !ok {
+			return runtime.Nil
+		}
+		return copyIn(c.ktx, x)
+	case <-ctx.Done():
+		panic(ctx.Err())
+	}
+}
+
+func (c *channel) close(ctx context.Context, args ...runtime.Val) runtime.Val {
+	c.st.once.Do(func() {
+		close(c.st.ch)
+	})
+	return runtime.Nil
+}
+
+// Args:
+// 0..n - The cases, a channel to receive from, or an object with the Chan
+// and Send fields to send a value to a channel. If the last argument is a
+// number, it is the timeout in milliseconds, 0 to return immediately if no
+// case is ready.
+// Returns:
+// An object with the Index of the selected case, -1 on timeout, and for a
+// receive, the Value received and Ok, false if the channel is closed.
+func (t *TaskMod) task_Select(ctx context.Context, args ...runtime.Val) runtime.Val {
+	cases := []reflect.SelectCase{{
+		Dir:  reflect.SelectRecv,
+		Chan: reflect.ValueOf(ctx.Done()),
+	}}
+	if n := len(args); n > 0 {
+		if to, ok := args[n-1].(runtime.Number); ok {
+			args = args[:n-1]
+			if ms := to.Int(ctx); ms <= 0 {
+				cases = append(cases, reflect.SelectCase{Dir: reflect.SelectDefault})
+			} else {
+				cases = append(cases, reflect.SelectCase{
+					Dir:  reflect.SelectRecv,
+					Chan: reflect.ValueOf(time.After(time.Duration(ms) * time.Millisecond)),
+				})
+			}
+		}
+	}
+	first := len(cases)
+	for _, a := range args {
+		if c, ok := a.(*channel); ok {
+			cases = append(cases, reflect.SelectCase{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(c.st.ch)})
+			continue
+		}
+		ob, ok := a.(runtime.Object)
+		if !ok {
+			panic(runtime.NewTypeError(runtime.Type(a), "", "task.Select"))
+		}
+		c, ok := ob.Get(runtime.String("Chan")).(*channel)
+		if !ok {
+			panic(runtime.NewTypeError(runtime.Type(ob.Get(runtime.String("Chan"))), "", "task.Select"))
+		}
+		x := copyOut(ctx, ob.Get(runtime.String("Send")), make(map[runtime.Object]bool))
+		cases = append(cases, reflect.SelectCase{Dir: reflect.SelectSend, Chan: reflect.ValueOf(c.st.ch), Send: reflect.ValueOf(&x).Elem()})
+	}
+
+	i, v, ok := reflect.Select(cases)
+	if i == 0 {
+		panic(ctx.Err())
+	}
+	res := runtime.NewObject()
+	res.Set(runtime.String("Index"), runtime.Number(i-first))
+	if i < first {
+		// Timeout
+		res.Set(runtime.String("Index"), runtime.Number(-1))
+		return res
+	}
+	if cases[i].Dir == reflect.SelectRecv {
+		res.Set(runtime.String("Ok"), runtime.Bool(ok))
+		if ok {
+			res.Set(runtime.String("Value"), copyIn(t.ktx, v.Interface()))
+		}
+	}
+	return res
+}
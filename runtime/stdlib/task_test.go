@@ -0,0 +1,247 @@
+package stdlib
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/saward/agora/compiler"
+	"github.com/saward/agora/runtime"
+)
+
+func newTaskKtx(fsys fstest.MapFS) (*runtime.Kontext, *TaskMod) {
+	ktx := runtime.NewKtx(runtime.NewFSResolver(fsys), new(compiler.Compiler))
+	tm := new(TaskMod)
+	tm.SetKtx(ktx)
+	return ktx, tm
+}
+
+func TestTaskSpawn(t *testing.T) {
+	ctx := context.Background()
+	_, tm := newTaskKtx(fstest.MapFS{
+		"double.agora": {Data: []byte(`
+jobs := args[0]
+results := args[1]
+for job := range jobs {
+	job.n = job.n * 2
+	results.Send(job)
+}
+return "done"
+`)},
+		"fail.agora": {Data: []byte(`return 1 + {}`)},
+	})
+
+	jobs := tm.task_Chan(ctx).(*channel)
+	results := tm.task_Chan(ctx, runtime.Number(10)).(*channel)
+	var tasks []runtime.Object
+	for i := 0; i < 3; i++ {
+		tasks = append(tasks, tm.task_Spawn(ctx, runtime.String("double"), jobs, results).(runtime.Object))
+	}
+	job := runtime.NewObject()
+	sum := 0.0
+	for i := 1; i <= 5; i++ {
+		job.Set(runtime.String("n"), runtime.Number(i))
+		jobs.send(ctx, job)
+	}
+	jobs.close(ctx)
+	for i := 0; i < 5; i++ {
+		ob := results.recv(ctx).(runtime.Object)
+		sum += ob.Get(runtime.String("n")).Float(ctx)
+	}
+	if sum != 30 {
+		t.Errorf("expected sum of 30, got %v", sum)
+	}
+	// The sent job was copied
+	if n := job.Get(runtime.String("n")).Int(ctx); n != 5 {
+		t.Errorf("expected the job to be unchanged, got %d", n)
+	}
+	for i, tsk := range tasks {
+		res := tsk.Get(runtime.String("Wait")).(runtime.Func).Call(ctx, nil)
+		if res != runtime.String("done") {
+			t.Errorf("[%d] - expected done, got %v", i, res)
+		}
+	}
+
+	// Errors are raised by Wait
+	tsk := tm.task_Spawn(ctx, runtime.String("fail")).(runtime.Object)
+	if err := assertErr(func() { tsk.Get(runtime.String("Wait")).(runtime.Func).Call(ctx, nil) }); err == nil {
+		t.Errorf("expected error from the failed task")
+	}
+	tsk = tm.task_Spawn(ctx, runtime.String("unknown")).(runtime.Object)
+	if err := assertErr(func() { tsk.Get(runtime.String("Wait")).(runtime.Func).Call(ctx, nil) }); err == nil {
+		t.Errorf("expected error from an unknown module")
+	}
+
+	// Funcs cannot be copied
+	fn := runtime.NewNativeFunc(nil, "", func(context.Context, ...runtime.Val) runtime.Val { return runtime.Nil })
+	if err := assertErr(func() { tm.task_Spawn(ctx, runtime.String("double"), fn) }); err == nil {
+		t.Errorf("expected error when passing a func")
+	}
+	cyc := runtime.NewObject()
+	cyc.Set(runtime.String("self"), cyc)
+	if err := assertErr(func() { results.send(ctx, cyc) }); err == nil {
+		t.Errorf("expected error when sending a cyclic object")
+	}
+}
+
+func TestTaskKtx(t *testing.T) {
+	ctx := context.Background()
+	ktx, tm := newTaskKtx(fstest.MapFS{
+		"cfg.agora": {Data: []byte(`return import("cfg")`)},
+		"sys.agora": {Data: []byte(`return import("os").Getwd()`)},
+	})
+	ktx.RegisterNativeModule(tm)
+	ktx.RegisterNativeModule(&cfgMod{cfg: "x"})
+
+	// The tasks only have the native modules of the module's context
+	tsk := tm.task_Spawn(ctx, runtime.String("cfg")).(runtime.Object)
+	if res := tsk.Get(runtime.String("Wait")).(runtime.Func).Call(ctx, nil); res != runtime.String("x") {
+		t.Errorf("expected the cfg module, got %v", res)
+	}
+	tsk = tm.task_Spawn(ctx, runtime.String("sys")).(runtime.Object)
+	err := assertErr(func() { tsk.Get(runtime.String("Wait")).(runtime.Func).Call(ctx, nil) })
+	if err == nil || !strings.Contains(err.Error(), "module not found: os") {
+		t.Errorf("expected the os module not to be found, got %v", err)
+	}
+}
+
+func TestTaskLifetime(t *testing.T) {
+	ctx := context.Background()
+	ktx, tm := newTaskKtx(fstest.MapFS{
+		"main.agora": {Data: []byte(`task := import("task")
+fmt := import("fmt")
+tasks := {}
+for i := 0; i < 5; i++ {
+	tasks[i] = task.Spawn("print", i)
+	fmt.Println("main", i)
+}
+for j := 0; j < 5; j++ {
+	tasks[j].Wait()
+}
+return task.Spawn("loop")`)},
+		"print.agora": {Data: []byte(`fmt := import("fmt")
+fmt.Println("task", args[0])`)},
+		"loop.agora": {Data: []byte(`for {
+}`)},
+	})
+	stdout := bytes.NewBuffer(nil)
+	ktx.Stdout = stdout
+	ktx.RegisterNativeModule(tm)
+	ktx.RegisterNativeModule(new(FmtMod))
+	m, err := ktx.Load("main")
+	if err != nil {
+		t.Fatal(err)
+	}
+	v, err := m.Run(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The tasks are cancelled when the run of the module that spawned them
+	// returns
+	done := make(chan error)
+	go func() {
+		done <- assertErr(func() { v.(runtime.Object).Get(runtime.String("Wait")).(runtime.Func).Call(ctx, nil) })
+	}()
+	select {
+	case err := <-done:
+		if err != context.Canceled {
+			t.Errorf("expected %v, got %v", context.Canceled, err)
+		}
+	case <-time.After(time.Second):
+		t.Errorf("expected the task to be cancelled")
+	}
+	// The standard output of the module and its tasks is shared under a lock
+	if _, ok := ktx.Stdout.(*lockedStream); !ok {
+		t.Errorf("expected the standard output to be locked, got %T", ktx.Stdout)
+	}
+	if n := strings.Count(stdout.String(), "\n"); n != 10 {
+		t.Errorf("expected 10 lines, got %q", stdout.String())
+	}
+}
+
+func TestTaskCancel(t *testing.T) {
+	_, tm := newTaskKtx(fstest.MapFS{
+		"loop.agora": {Data: []byte(`for {
+}`)},
+	})
+
+	// Cancelling the parent's context stops the task
+	ctx, cancel := context.WithCancel(context.Background())
+	tsk := tm.task_Spawn(ctx, runtime.String("loop")).(runtime.Object)
+	time.Sleep(10 * time.Millisecond)
+	cancel()
+	err := assertErr(func() { tsk.Get(runtime.String("Wait")).(runtime.Func).Call(context.Background(), nil) })
+	if err != context.Canceled {
+		t.Errorf("expected %v, got %v", context.Canceled, err)
+	}
+
+	// Cancel stops the task
+	ctx = context.Background()
+	tsk = tm.task_Spawn(ctx, runtime.String("loop")).(runtime.Object)
+	tsk.Get(runtime.String("Cancel")).(runtime.Func).Call(ctx, nil)
+	err = assertErr(func() { tsk.Get(runtime.String("Wait")).(runtime.Func).Call(ctx, nil) })
+	if err != context.Canceled {
+		t.Errorf("expected %v, got %v", context.Canceled, err)
+	}
+
+	// Blocked receives are cancelled too
+	ctx, cancel = context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	ch := tm.task_Chan(ctx).(*channel)
+	if err := assertErr(func() { ch.recv(ctx) }); err != context.DeadlineExceeded {
+		t.Errorf("expected %v, got %v", context.DeadlineExceeded, err)
+	}
+}
+
+func TestTaskSelect(t *testing.T) {
+	ctx := context.Background()
+	_, tm := newTaskKtx(nil)
+
+	c1 := tm.task_Chan(ctx, runtime.Number(1)).(*channel)
+	c2 := tm.task_Chan(ctx, runtime.Number(1)).(*channel)
+	get := func(ob runtime.Val, k string) runtime.Val {
+		return ob.(runtime.Object).Get(runtime.String(k))
+	}
+
+	// Timeout and default
+	res := tm.task_Select(ctx, c1, c2, runtime.Number(0))
+	if i := get(res, "Index"); i != runtime.Number(-1) {
+		t.Errorf("expected index -1, got %v", i)
+	}
+	res = tm.task_Select(ctx, c1, runtime.Number(5))
+	if i := get(res, "Index"); i != runtime.Number(-1) {
+		t.Errorf("expected index -1 on timeout, got %v", i)
+	}
+
+	// Receive
+	c2.send(ctx, runtime.String("x"))
+	res = tm.task_Select(ctx, c1, c2)
+	if i, v, ok := get(res, "Index"), get(res, "Value"), get(res, "Ok"); i != runtime.Number(1) || v != runtime.String("x") || ok != runtime.Bool(true) {
+		t.Errorf("expected index 1, value x and ok, got %v, %v and %v", i, v, ok)
+	}
+
+	// Send
+	snd := runtime.NewObject()
+	snd.Set(runtime.String("Chan"), c1)
+	snd.Set(runtime.String("Send"), runtime.Number(3))
+	res = tm.task_Select(ctx, c2, snd)
+	if i := get(res, "Index"); i != runtime.Number(1) {
+		t.Errorf("expected index 1, got %v", i)
+	}
+	if v := c1.recv(ctx); v != runtime.Number(3) {
+		t.Errorf("expected 3 to be sent, got %v", v)
+	}
+
+	// Closed channel
+	c1.close(ctx)
+	res = tm.task_Select(ctx, c1)
+	if ok := get(res, "Ok"); ok != runtime.Bool(false) {
+		t.Errorf("expected not ok on a closed channel, got %v", ok)
+	}
+	if v := c1.recv(ctx); v != runtime.Nil {
+		t.Errorf("expected nil from a closed channel, got %v", v)
+	}
+}
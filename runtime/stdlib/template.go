@@ -0,0 +1,391 @@
+package stdlib
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	htmltemplate "html/template"
+	"io"
+	"io/ioutil"
+	"math"
+	texttemplate "text/template"
+	"time"
+
+	"github.com/saward/agora/runtime"
+)
+
+// The template module, as documented in
+// https://github.com/saward/agora/wiki/Standard-library
+type TemplateMod struct {
+	ktx *runtime.Kontext
+	ob  runtime.Object
+}
+
+func (t *TemplateMod) ID() string {
+	return "template"
+}
+
+func (t *TemplateMod) Run(_ context.Context, _ ...runtime.Val) (v runtime.Val, err error) {
+	defer runtime.PanicToError(&err)
+	if t.ob == nil {
+		// Prepare the object
+		t.ob = runtime.NewObject()
+		t.ob.Set(runtime.String("Parse"), runtime.NewNativeFunc(t.ktx, "template.Parse", t.template_Parse))
+		t.ob.Set(runtime.String("Load"), runtime.NewNativeFunc(t.ktx, "template.Load", t.template_Load))
+	}
+	return t.ob, nil
+}
+
+func (t *TemplateMod) SetKtx(c *runtime.Kontext) {
+	t.ktx = c
+}
+
+func (t *TemplateMod) Clone() runtime.NativeModule {
+	return new(TemplateMod)
+}
+
+// The template set, backed by either text/template or html/template, the
+// first template parsed being the main one.
+type tmpl struct {
+	runtime.Object
+	ktx  *runtime.Kontext
+	text *texttemplate.Template
+	html *htmltemplate.Template
+
+	// The agora funcs of the Funcs option, bound to the context of each
+	// execution
+	funcs map[string]runtime.Func
+}
+
+// Returns the template set configured by the options object opts, with its
+// main template named nm.
+func (t *TemplateMod) newTemplate(ctx context.Context, nm string, opts runtime.Val) *tmpl {
+	tp := &tmpl{
+		Object: runtime.NewObject(),
+		ktx:    t.ktx,
+		funcs:  make(map[string]runtime.Func),
+	}
+	if ob, ok := option(opts, "Funcs").(runtime.Object); ok {
+		keys := ob.Keys(ctx).(runtime.Object)
+		for i := int64(0); i < keys.Len(ctx).Int(ctx); i++ {
+			k := keys.Get(runtime.Number(i))
+			fn, ok := ob.Get(k).(runtime.Func)
+			if !ok {
+				panic(runtime.NewTypeError(runtime.Type(ob.Get(k)), "", "template func"))
+			}
+			tp.funcs[k.String(ctx)] = fn
+		}
+	}
+	funcs := tp.funcMap(ctx)
+	var left, right string
+	if d, ok := option(opts, "Delims").(runtime.Object); ok {
+		left, right = d.Get(runtime.Number(0)).String(ctx), d.Get(runtime.Number(1)).String(ctx)
+	}
+	missing := "missingkey=default"
+	if option(opts, "Strict").Bool(ctx) {
+		missing = "missingkey=error"
+	}
+	if option(opts, "HTML").Bool(ctx) {
+		tp.html = htmltemplate.New(nm).Funcs(funcs).Delims(left, right).Option(missing)
+	} else {
+		tp.text = texttemplate.New(nm).Funcs(funcs).Delims(left, right).Option(missing)
+	}
+
+	tp.Set(runtime.String("Name"), runtime.String(nm))
+	tp.Set(runtime.String("Execute"), runtime.NewNativeFunc(t.ktx, "template.Template.Execute", tp.execute))
+	tp.Set(runtime.String("ExecuteTemplate"), runtime.NewNativeFunc(t.ktx, "template.Template.ExecuteTemplate", tp.executeTemplate))
+	tp.Set(runtime.String("Parse"), runtime.NewNativeFunc(t.ktx, "template.Template.Parse", tp.parse))
+	tp.Set(runtime.String("Load"), runtime.NewNativeFunc(t.ktx, "template.Template.Load", tp.load))
+	return tp
+}
+
+// Parses src as the template named nm of the set.
+func (tp *tmpl) add(nm, src string) {
+	var err error
+	if tp.html != nil {
+		if nm != tp.html.Name() {
+			_, err = tp.html.New(nm).Parse(src)
+		} else {
+			_, err = tp.html.Parse(src)
+		}
+	} else {
+		if nm != tp.text.Name() {
+			_, err = tp.text.New(nm).Parse(src)
+		} else {
+			_, err = tp.text.Parse(src)
+		}
+	}
+	if err != nil {
+		panic(err)
+	}
+}
+
+// Returns the source of the template identified by id, as found by the
+// module resolver of the execution context.
+func resolveTemplate(ktx *runtime.Kontext, id string) string {
+	if ktx.Resolver == nil {
+		panic("template: no module resolver to load " + id)
+	}
+	r, err := ktx.Resolver.Resolve(id)
+	if err != nil {
+		panic(err)
+	}
+	if rc, ok := r.(io.ReadCloser); ok {
+		defer rc.Close()
+	}
+	b, err := ioutil.ReadAll(r)
+	if err != nil {
+		panic(err)
+	}
+	return string(b)
+}
+
+// Args:
+// 0 - The source of the template
+// 1 - The options (optional), an object with the Name, Funcs, HTML, Delims
+// and Strict fields
+// Returns:
+// The template object, see the documentation for its methods.
+func (t *TemplateMod) template_Parse(ctx context.Context, args ...runtime.Val) runtime.Val {
+	runtime.ExpectAtLeastNArgs(1, args)
+	var opts runtime.Val = runtime.Nil
+	if len(args) > 1 {
+		opts = args[1]
+	}
+	nm := "template"
+	if n := option(opts, "Name"); n != runtime.Nil {
+		nm = n.String(ctx)
+	}
+	tp := t.newTemplate(ctx, nm, opts)
+	tp.add(nm, args[0].String(ctx))
+	return tp
+}
+
+// Args:
+// 0 - The identifier of the template, resolved by the module resolver
+// 1 - The options (optional), see Parse
+// Returns:
+// The template object, named after its identifier.
+func (t *TemplateMod) template_Load(ctx context.Context, args ...runtime.Val) runtime.Val {
+	runtime.ExpectAtLeastNArgs(1, args)
+	var opts runtime.Val = runtime.Nil
+	if len(args) > 1 {
+		opts = args[1]
+	}
+	id := args[0].String(ctx)
+	tp := t.newTemplate(ctx, id, opts)
+	tp.add(id, resolveTemplate(t.ktx, id))
+	return tp
+}
+
+// Args:
+// 0 - The name of the template to add to the set
+// 1 - The source of the template
+// Returns:
+// The template object.
+func (tp *tmpl) parse(ctx context.Context, args ...runtime.Val) runtime.Val {
+	runtime.ExpectAtLeastNArgs(2, args)
+	tp.add(args[0].String(ctx), args[1].String(ctx))
+	return tp
+}
+
+// Args:
+// 0..n - The identifiers of the templates to add to the set, resolved by
+// the module resolver, each named after its identifier
+// Returns:
+// The template object.
+func (tp *tmpl) load(ctx context.Context, args ...runtime.Val) runtime.Val {
+	for _, a := range args {
+		id := a.String(ctx)
+		tp.add(id, resolveTemplate(tp.ktx, id))
+	}
+	return tp
+}
+
+// Executes the template named nm with the agora value data, and returns
+// the output.
+func (tp *tmpl) run(ctx context.Context, nm string, data runtime.Val) runtime.Val {
+	d := toTemplateData(ctx, tp, data, make(map[runtime.Object]interface{}))
+	// The set is cloned so that the funcs are bound to this execution,
+	// which also keeps the parsed set unexecuted, as html/template cannot
+	// clone an executed set.
+	var set interface {
+		ExecuteTemplate(io.Writer, string, interface{}) error
+	}
+	if tp.html != nil {
+		html, err := tp.html.Clone()
+		if err != nil {
+			panic(err)
+		}
+		set = html.Funcs(tp.funcMap(ctx))
+	} else {
+		text, err := tp.text.Clone()
+		if err != nil {
+			panic(err)
+		}
+		set = text.Funcs(tp.funcMap(ctx))
+	}
+	var buf bytes.Buffer
+	if err := set.ExecuteTemplate(&buf, nm, d); err != nil {
+		panic(err)
+	}
+	return runtime.String(buf.String())
+}
+
+// Args:
+// 0 - The data (optional)
+// Returns:
+// The output of the main template, as a string.
+func (tp *tmpl) execute(ctx context.Context, args ...runtime.Val) runtime.Val {
+	var data runtime.Val = runtime.Nil
+	if len(args) > 0 {
+		data = args[0]
+	}
+	nm := tp.Get(runtime.String("Name")).String(ctx)
+	return tp.run(ctx, nm, data)
+}
+
+// Args:
+// 0 - The name of the template of the set to execute
+// 1 - The data (optional)
+// Returns:
+// The output of the template, as a string.
+func (tp *tmpl) executeTemplate(ctx context.Context, args ...runtime.Val) runtime.Val {
+	runtime.ExpectAtLeastNArgs(1, args)
+	var data runtime.Val = runtime.Nil
+	if len(args) > 1 {
+		data = args[1]
+	}
+	return tp.run(ctx, args[0].String(ctx), data)
+}
+
+// Returns the func map of the agora funcs of the set, called with the
+// context ctx.
+func (tp *tmpl) funcMap(ctx context.Context) map[string]interface{} {
+	funcs := make(map[string]interface{}, len(tp.funcs))
+	for nm, fn := range tp.funcs {
+		funcs[nm] = tp.goFunc(ctx, fn, runtime.Nil)
+	}
+	return funcs
+}
+
+// Returns the Go func that calls the agora func fn with the context ctx and
+// the this value from a template, with its arguments converted to agora
+// values.
+func (tp *tmpl) goFunc(ctx context.Context, fn runtime.Func, this runtime.Val) func(...interface{}) (interface{}, error) {
+	return func(args ...interface{}) (res interface{}, err error) {
+		defer runtime.PanicToError(&err)
+		vals := make([]runtime.Val, len(args))
+		for i, a := range args {
+			vals[i] = fromTemplateData(tp.ktx, a)
+		}
+		return toTemplateData(ctx, tp, fn.Call(ctx, this, vals...), make(map[runtime.Object]interface{})), nil
+	}
+}
+
+// Returns the Go value used by templates for the agora value v: objects
+// are converted to maps, array-like objects to slices, funcs to Go funcs
+// that can be called with the call builtin, and integral numbers to
+// integers. The funcs held by an object are called with the object as this.
+// The seen objects map to their converted value, so that an object held
+// more than once, or that holds itself, is converted only once.
+func toTemplateData(ctx context.Context, tp *tmpl, v runtime.Val, seen map[runtime.Object]interface{}) interface{} {
+	switch v := v.(type) {
+	case nil:
+		return nil
+	case runtime.Number:
+		f := float64(v)
+		if f == math.Trunc(f) && math.Abs(f) < 1<<53 {
+			return int64(f)
+		}
+		return f
+	case runtime.String, runtime.Bool:
+		return v.Native(ctx)
+	case runtime.Func:
+		return tp.goFunc(ctx, v, runtime.Nil)
+	case *_time:
+		return v.t
+	case *buffer:
+		return string(v.b)
+	case *_regexp:
+		return v.re
+	case *file:
+		return v.f.Name()
+	case runtime.Object:
+		if d, ok := seen[v]; ok {
+			return d
+		}
+		if _, ok := v.Get(runtime.String("__native")).(runtime.Func); ok {
+			d := v.Native(ctx)
+			seen[v] = d
+			return d
+		}
+		// The converted value is recorded before its fields, so that the
+		// fields holding v get the same map or slice.
+		keys := v.Keys(ctx).(runtime.Object)
+		n := int(keys.Len(ctx).Int(ctx))
+		if isArray(ctx, keys, n) {
+			a := make([]interface{}, n)
+			seen[v] = a
+			for i := range a {
+				a[i] = fieldData(ctx, tp, v, v.Get(runtime.Number(i)), seen)
+			}
+			return a
+		}
+		m := make(map[string]interface{}, n)
+		seen[v] = m
+		for i := 0; i < n; i++ {
+			k := keys.Get(runtime.Number(i))
+			m[k.String(ctx)] = fieldData(ctx, tp, v, v.Get(k), seen)
+		}
+		return m
+	}
+	if v == runtime.Nil {
+		return nil
+	}
+	// A custom value, use its native representation
+	return v.Native(ctx)
+}
+
+// Returns the Go value used by templates for the value v of a field of the
+// object ob, funcs being called with ob as this.
+func fieldData(ctx context.Context, tp *tmpl, ob runtime.Object, v runtime.Val, seen map[runtime.Object]interface{}) interface{} {
+	if fn, ok := v.(runtime.Func); ok {
+		return tp.goFunc(ctx, fn, ob)
+	}
+	return toTemplateData(ctx, tp, v, seen)
+}
+
+// Returns the agora value for the Go value v passed as argument to a func
+// by a template.
+func fromTemplateData(ktx *runtime.Kontext, v interface{}) runtime.Val {
+	switch v := v.(type) {
+	case nil:
+		return runtime.Nil
+	case bool:
+		return runtime.Bool(v)
+	case string:
+		return runtime.String(v)
+	case int:
+		return runtime.Number(v)
+	case int64:
+		return runtime.Number(v)
+	case float64:
+		return runtime.Number(v)
+	case time.Time:
+		return (&TimeMod{ktx: ktx}).newTime(v)
+	case []interface{}:
+		ob := runtime.NewObject()
+		for i, e := range v {
+			ob.Set(runtime.Number(i), fromTemplateData(ktx, e))
+		}
+		return ob
+	case map[string]interface{}:
+		ob := runtime.NewObject()
+		for k, e := range v {
+			ob.Set(runtime.String(k), fromTemplateData(ktx, e))
+		}
+		return ob
+	}
+	return runtime.String(fmt.Sprint(v))
+}
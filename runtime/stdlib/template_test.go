@@ -0,0 +1,206 @@
+package stdlib
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/saward/agora/compiler"
+	"github.com/saward/agora/runtime"
+)
+
+func newTemplateMod(fsys fstest.MapFS) *TemplateMod {
+	var res runtime.ModuleResolver
+	if fsys != nil {
+		res = runtime.NewFSResolver(fsys)
+	}
+	tm := new(TemplateMod)
+	tm.SetKtx(runtime.NewKtx(res, nil))
+	return tm
+}
+
+func callMethod(ctx context.Context, ob runtime.Val, nm string, args ...runtime.Val) runtime.Val {
+	return ob.(runtime.Object).Get(runtime.String(nm)).(runtime.Func).Call(ctx, nil, args...)
+}
+
+func TestTemplateExecute(t *testing.T) {
+	ctx := context.Background()
+	tm := newTemplateMod(nil)
+
+	user := runtime.NewObject()
+	user.Set(runtime.String("Name"), runtime.String("Zoë"))
+	user.Set(runtime.String("Age"), runtime.Number(1000000))
+	user.Set(runtime.String("Ratio"), runtime.Number(0.5))
+	tags := runtime.NewObject()
+	tags.Set(runtime.Number(0), runtime.String("a"))
+	tags.Set(runtime.Number(1), runtime.String("b"))
+	user.Set(runtime.String("Tags"), tags)
+	user.Set(runtime.String("Since"), (&TimeMod{ktx: tm.ktx}).newTime(time.Date(2020, 5, 1, 0, 0, 0, 0, time.UTC)))
+	user.Set(runtime.String("Greet"), runtime.NewNativeFunc(tm.ktx, "", func(ctx context.Context, args ...runtime.Val) runtime.Val {
+		return runtime.String("hi " + args[0].String(ctx))
+	}))
+
+	src := `{{.Name}} {{.Age}} {{.Ratio}} {{range $i, $t := .Tags}}{{$i}}={{$t}} {{end}}{{.Since.Year}} {{call .Greet .Name}}{{if .Missing}}!{{end}}`
+	tp := tm.template_Parse(ctx, runtime.String(src))
+	exp := "Zoë 1000000 0.5 0=a 1=b 2020 hi Zoë"
+	if got := callMethod(ctx, tp, "Execute", user).String(ctx); got != exp {
+		t.Errorf("expected %q, got %q", exp, got)
+	}
+
+	// Funcs and strict mode
+	opts := runtime.NewObject()
+	funcs := runtime.NewObject()
+	funcs.Set(runtime.String("upper"), runtime.NewNativeFunc(tm.ktx, "", func(ctx context.Context, args ...runtime.Val) runtime.Val {
+		return runtime.String(strings.ToUpper(args[0].String(ctx)))
+	}))
+	funcs.Set(runtime.String("fail"), runtime.NewNativeFunc(tm.ktx, "", func(ctx context.Context, args ...runtime.Val) runtime.Val {
+		panic("failed")
+	}))
+	opts.Set(runtime.String("Funcs"), funcs)
+	opts.Set(runtime.String("Strict"), runtime.Bool(true))
+	tp = tm.template_Parse(ctx, runtime.String(`{{upper .Name}}`), opts)
+	if got := callMethod(ctx, tp, "Execute", user).String(ctx); got != "ZOË" {
+		t.Errorf("expected %q, got %q", "ZOË", got)
+	}
+	tp = tm.template_Parse(ctx, runtime.String(`{{.Missing}}`), opts)
+	if err := assertErr(func() { callMethod(ctx, tp, "Execute", user) }); err == nil {
+		t.Errorf("expected error for a missing key in strict mode")
+	}
+	tp = tm.template_Parse(ctx, runtime.String(`{{fail}}`), opts)
+	if err := assertErr(func() { callMethod(ctx, tp, "Execute") }); err == nil || !strings.Contains(err.Error(), "failed") {
+		t.Errorf("expected error from the func, got %v", err)
+	}
+
+	// Errors
+	if err := assertErr(func() { tm.template_Parse(ctx, runtime.String(`{{.Name`)) }); err == nil {
+		t.Errorf("expected parse error")
+	}
+	if err := assertErr(func() { tm.template_Parse(ctx, runtime.String(`{{unknown}}`)) }); err == nil {
+		t.Errorf("expected error for an unknown func")
+	}
+}
+
+func TestTemplateHTMLDelims(t *testing.T) {
+	ctx := context.Background()
+	tm := newTemplateMod(nil)
+	opts := runtime.NewObject()
+	opts.Set(runtime.String("HTML"), runtime.Bool(true))
+	delims := runtime.NewObject()
+	delims.Set(runtime.Number(0), runtime.String("<%"))
+	delims.Set(runtime.Number(1), runtime.String("%>"))
+	opts.Set(runtime.String("Delims"), delims)
+	tp := tm.template_Parse(ctx, runtime.String(`<p>{{x}} <% . %></p>`), opts)
+	exp := "<p>{{x}} &lt;b&gt;&amp;&lt;/b&gt;</p>"
+	if got := callMethod(ctx, tp, "Execute", runtime.String("<b>&</b>")).String(ctx); got != exp {
+		t.Errorf("expected %q, got %q", exp, got)
+	}
+}
+
+func TestTemplateLoad(t *testing.T) {
+	ctx := context.Background()
+	tm := newTemplateMod(fstest.MapFS{
+		"mail.tmpl":          {Data: []byte(`Hello {{.}},{{template "partials/sig.tmpl"}}`)},
+		"partials/sig.tmpl":  {Data: []byte(`{{define "sig"}}-- {{end}}{{template "sig"}}me`)},
+		"partials/list.tmpl": {Data: []byte(`{{range .}}[{{.}}]{{end}}`)},
+	})
+	tp := tm.template_Load(ctx, runtime.String("mail.tmpl"))
+	callMethod(ctx, tp, "Load", runtime.String("partials/sig.tmpl"), runtime.String("partials/list.tmpl"))
+	if got := callMethod(ctx, tp, "Execute", runtime.String("Bob")).String(ctx); got != "Hello Bob,-- me" {
+		t.Errorf("expected %q, got %q", "Hello Bob,-- me", got)
+	}
+	list := runtime.NewObject()
+	list.Set(runtime.Number(0), runtime.Number(1))
+	list.Set(runtime.Number(1), runtime.Number(2))
+	if got := callMethod(ctx, tp, "ExecuteTemplate", runtime.String("partials/list.tmpl"), list).String(ctx); got != "[1][2]" {
+		t.Errorf("expected %q, got %q", "[1][2]", got)
+	}
+	callMethod(ctx, tp, "Parse", runtime.String("inline"), runtime.String(`<{{.}}>`))
+	if got := callMethod(ctx, tp, "ExecuteTemplate", runtime.String("inline"), runtime.Bool(true)).String(ctx); got != "<true>" {
+		t.Errorf("expected %q, got %q", "<true>", got)
+	}
+
+	if err := assertErr(func() { tm.template_Load(ctx, runtime.String("none.tmpl")) }); err == nil {
+		t.Errorf("expected error for an unknown template")
+	}
+	tm = newTemplateMod(nil)
+	if err := assertErr(func() { tm.template_Load(ctx, runtime.String("mail.tmpl")) }); err == nil {
+		t.Errorf("expected error without resolver")
+	}
+}
+
+func TestTemplateObjects(t *testing.T) {
+	ctx := context.Background()
+	ktx := runtime.NewKtx(runtime.NewFSResolver(fstest.MapFS{
+		"main.agora": {Data: []byte(`template := import("template")
+regexp := import("regexp")
+user := {Name: "Zoë", Re: regexp.Compile("^Z")}
+user.Greet = func(s) {
+	return s + " " + this.Name
+}
+user.Self = user
+user.List = {}
+user.List[0] = user
+tp := template.Parse("{{call .Greet \"hi\"}} {{.Self.Self.Name}} {{(index .List 0).Name}} {{.Re.MatchString .Name}}")
+return tp.Execute(user)`)},
+	}), new(compiler.Compiler))
+	ktx.RegisterNativeModule(new(TemplateMod))
+	ktx.RegisterNativeModule(new(RegexpMod))
+	m, err := ktx.Load("main")
+	if err != nil {
+		t.Fatal(err)
+	}
+	v, err := m.Run(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Methods are called with their object as this, cyclic objects are
+	// converted once, and regexps are Go regexps.
+	exp := "hi Zoë Zoë Zoë true"
+	if got := v.String(ctx); got != exp {
+		t.Errorf("expected %q, got %q", exp, got)
+	}
+}
+
+type tmplKey struct{}
+
+// A func that returns the value of its context, without using the frames of
+// an execution context.
+type ctxFunc struct {
+	runtime.Func
+}
+
+func (f ctxFunc) Call(ctx context.Context, _ runtime.Val, _ ...runtime.Val) runtime.Val {
+	return runtime.Number(ctx.Value(tmplKey{}).(int))
+}
+
+func TestTemplateConcurrent(t *testing.T) {
+	tm := newTemplateMod(nil)
+	opts := runtime.NewObject()
+	funcs := runtime.NewObject()
+	funcs.Set(runtime.String("key"), ctxFunc{})
+	opts.Set(runtime.String("Funcs"), funcs)
+	for _, html := range []bool{false, true} {
+		opts.Set(runtime.String("HTML"), runtime.Bool(html))
+		tp := tm.template_Parse(context.Background(), runtime.String(`{{key}}`), opts).(*tmpl)
+
+		// Each execution calls the funcs with its own context
+		var wg sync.WaitGroup
+		for i := 0; i < 10; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				ctx := context.WithValue(context.Background(), tmplKey{}, i)
+				for j := 0; j < 10; j++ {
+					if got := tp.execute(ctx).String(ctx); got != strconv.Itoa(i) {
+						t.Errorf("[html: %t] expected %d, got %s", html, i, got)
+					}
+				}
+			}(i)
+		}
+		wg.Wait()
+	}
+}
@@ -0,0 +1,349 @@
+package stdlib
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/saward/agora/runtime"
+)
+
+// Error raised when an assertion of the testing module fails.
+type AssertionError string
+
+// Error interface implementation.
+func (e AssertionError) Error() string {
+	return string(e)
+}
+
+// Create a new AssertionError for the assertion nm, with the optional message
+// provided by the caller.
+func NewAssertionError(ctx context.Context, nm, reason string, msg []runtime.Val) AssertionError {
+	s := fmt.Sprintf("%s: %s", nm, reason)
+	if len(msg) > 0 {
+		s += ": " + joinVals(ctx, msg)
+	}
+	return AssertionError(s)
+}
+
+// The testing module, as documented in
+// https://github.com/saward/agora/wiki/Standard-library
+type TestingMod struct {
+	ktx *runtime.Kontext
+	ob  runtime.Object
+}
+
+func (t *TestingMod) ID() string {
+	return "testing"
+}
+
+func (t *TestingMod) Run(_ context.Context, _ ...runtime.Val) (v runtime.Val, err error) {
+	defer runtime.PanicToError(&err)
+	if t.ob == nil {
+		// Prepare the object
+		t.ob = runtime.NewObject()
+		t.ob.Set(runtime.String("Equal"), runtime.NewNativeFunc(t.ktx, "testing.Equal", t.testing_Equal))
+		t.ob.Set(runtime.String("NotEqual"), runtime.NewNativeFunc(t.ktx, "testing.NotEqual", t.testing_NotEqual))
+		t.ob.Set(runtime.String("DeepEqual"), runtime.NewNativeFunc(t.ktx, "testing.DeepEqual", t.testing_DeepEqual))
+		t.ob.Set(runtime.String("True"), runtime.NewNativeFunc(t.ktx, "testing.True", t.testing_True))
+		t.ob.Set(runtime.String("False"), runtime.NewNativeFunc(t.ktx, "testing.False", t.testing_False))
+		t.ob.Set(runtime.String("Panics"), runtime.NewNativeFunc(t.ktx, "testing.Panics", t.testing_Panics))
+		t.ob.Set(runtime.String("Fail"), runtime.NewNativeFunc(t.ktx, "testing.Fail", t.testing_Fail))
+	}
+	return t.ob, nil
+}
+
+func (t *TestingMod) SetKtx(ktx *runtime.Kontext) {
+	t.ktx = ktx
+}
+
+func (t *TestingMod) Clone() runtime.NativeModule {
+	return new(TestingMod)
+}
+
+// Returns the representation of the value in assertion messages.
+func reprVal(ctx context.Context, v runtime.Val) string {
+	if s, ok := v.(runtime.String); ok {
+		return strconv.Quote(string(s))
+	}
+	return v.String(ctx)
+}
+
+// Returns the string values of the arguments, separated by spaces.
+func joinVals(ctx context.Context, args []runtime.Val) string {
+	buf := bytes.NewBuffer(nil)
+	for i, v := range args {
+		if i > 0 {
+			buf.WriteByte(' ')
+		}
+		buf.WriteString(v.String(ctx))
+	}
+	return buf.String()
+}
+
+// Returns true if both values are equal. Objects are equal if they have the
+// same keys, and deeply equal values for those keys. Other values are compared
+// with the comparer of the execution context.
+func deepEqual(ctx context.Context, cmp runtime.Comparer, a, b runtime.Val) bool {
+	ao, aok := a.(runtime.Object)
+	bo, bok := b.(runtime.Object)
+	if !aok || !bok {
+		return aok == bok && cmp.Cmp(ctx, a, b) == 0
+	}
+	if ao == bo {
+		return true
+	}
+	if ao.Len(ctx).Int(ctx) != bo.Len(ctx).Int(ctx) {
+		return false
+	}
+	keys := ao.Keys(ctx).(runtime.Object)
+	for i, n := int64(0), keys.Len(ctx).Int(ctx); i < n; i++ {
+		k := keys.Get(runtime.Number(i))
+		if !deepEqual(ctx, cmp, ao.Get(k), bo.Get(k)) {
+			return false
+		}
+	}
+	return true
+}
+
+// Asserts that two values are equal, as compared by the == operator.
+// Args:
+// 0 - The value to test
+// 1 - The expected value
+// 2..n - The optional message of the failure
+// Returns:
+// nil, or raises an AssertionError
+func (t *TestingMod) testing_Equal(ctx context.Context, args ...runtime.Val) runtime.Val {
+	runtime.ExpectAtLeastNArgs(2, args)
+	if t.ktx.Comparer.Cmp(ctx, args[0], args[1]) != 0 {
+		panic(NewAssertionError(ctx, "Equal", fmt.Sprintf("expected %s, got %s",
+			reprVal(ctx, args[1]), reprVal(ctx, args[0])), args[2:]))
+	}
+	return runtime.Nil
+}
+
+// Asserts that two values are not equal, as compared by the != operator.
+// Args:
+// 0 - The value to test
+// 1 - The unexpected value
+// 2..n - The optional message of the failure
+// Returns:
+// nil, or raises an AssertionError
+func (t *TestingMod) testing_NotEqual(ctx context.Context, args ...runtime.Val) runtime.Val {
+	runtime.ExpectAtLeastNArgs(2, args)
+	if t.ktx.Comparer.Cmp(ctx, args[0], args[1]) == 0 {
+		panic(NewAssertionError(ctx, "NotEqual", fmt.Sprintf("expected a value other than %s",
+			reprVal(ctx, args[1])), args[2:]))
+	}
+	return runtime.Nil
+}
+
+// Asserts that two values are deeply equal, that is, objects are equal if they
+// have the same keys and their values are deeply equal.
+// Args:
+// 0 - The value to test
+// 1 - The expected value
+// 2..n - The optional message of the failure
+// Returns:
+// nil, or raises an AssertionError
+func (t *TestingMod) testing_DeepEqual(ctx context.Context, args ...runtime.Val) runtime.Val {
+	runtime.ExpectAtLeastNArgs(2, args)
+	if !deepEqual(ctx, t.ktx.Comparer, args[0], args[1]) {
+		panic(NewAssertionError(ctx, "DeepEqual", fmt.Sprintf("expected %s, got %s",
+			reprVal(ctx, args[1]), reprVal(ctx, args[0])), args[2:]))
+	}
+	return runtime.Nil
+}
+
+// Asserts that a value is true.
+// Args:
+// 0 - The value to test
+// 1..n - The optional message of the failure
+// Returns:
+// nil, or raises an AssertionError
+func (t *TestingMod) testing_True(ctx context.Context, args ...runtime.Val) runtime.Val {
+	runtime.ExpectAtLeastNArgs(1, args)
+	if !args[0].Bool(ctx) {
+		panic(NewAssertionError(ctx, "True", fmt.Sprintf("got %s", reprVal(ctx, args[0])), args[1:]))
+	}
+	return runtime.Nil
+}
+
+// Asserts that a value is false.
+// Args:
+// 0 - The value to test
+// 1..n - The optional message of the failure
+// Returns:
+// nil, or raises an AssertionError
+func (t *TestingMod) testing_False(ctx context.Context, args ...runtime.Val) runtime.Val {
+	runtime.ExpectAtLeastNArgs(1, args)
+	if args[0].Bool(ctx) {
+		panic(NewAssertionError(ctx, "False", fmt.Sprintf("got %s", reprVal(ctx, args[0])), args[1:]))
+	}
+	return runtime.Nil
+}
+
+// Asserts that calling a function raises an error.
+// Args:
+// 0 - The function to call, without arguments
+// 1..n - The optional message of the failure
+// Returns:
+// The error raised by the function, as returned by the recover builtin, or
+// raises an AssertionError
+func (t *TestingMod) testing_Panics(ctx context.Context, args ...runtime.Val) runtime.Val {
+	runtime.ExpectAtLeastNArgs(1, args)
+	fn, ok := args[0].(runtime.Func)
+	if !ok {
+		panic(runtime.NewTypeError(runtime.Type(args[0]), "", "testing.Panics"))
+	}
+	if err := callRecover(ctx, fn); err != nil {
+		return err
+	}
+	panic(NewAssertionError(ctx, "Panics", "the function did not raise an error", args[1:]))
+}
+
+// Calls the function, and returns the error it raised, converted to a value
+// like the recover builtin does, or nil if it did not raise an error.
+func callRecover(ctx context.Context, fn runtime.Func) (ret runtime.Val) {
+	defer func() {
+		if p := recover(); p != nil {
+			switch v := p.(type) {
+			case runtime.Val:
+				ret = v
+			case error:
+				ret = runtime.String(v.Error())
+			default:
+				ret = runtime.String(fmt.Sprintf("%v", v))
+			}
+		}
+	}()
+	fn.Call(ctx, runtime.Nil)
+	return nil
+}
+
+// Fails unconditionally.
+// Args:
+// 0..n - The optional message of the failure
+// Returns:
+// Raises an AssertionError
+func (t *TestingMod) testing_Fail(ctx context.Context, args ...runtime.Val) runtime.Val {
+	if len(args) == 0 {
+		panic(NewAssertionError(ctx, "Fail", "failed", nil))
+	}
+	panic(NewAssertionError(ctx, "Fail", joinVals(ctx, args), nil))
+}
+
+// The signal that stops the execution of a test function, raised by Fatal and
+// Skip.
+type stopTest struct{}
+
+// A TestCase holds the state of a test function run by `agora test`. The
+// test function receives the object of the TestCase as argument, with the
+// following methods:
+//
+//   - Name() returns the name of the test;
+//   - Log(args...) records the arguments in the logs of the test;
+//   - Error(args...) is like Log, and marks the test as failed;
+//   - Fatal(args...) is like Error, and stops the execution of the test;
+//   - Skip(args...) is like Log, and marks the test as skipped and stops its
+//     execution;
+//   - Failed() returns true if the test failed.
+type TestCase struct {
+	name    string
+	ob      runtime.Object
+	logs    []string
+	failed  bool
+	skipped bool
+}
+
+// NewTestCase returns a TestCase named nm, whose object is created in the
+// execution context ktx.
+func NewTestCase(ktx *runtime.Kontext, nm string) *TestCase {
+	tc := &TestCase{name: nm, ob: runtime.NewObject()}
+	tc.ob.Set(runtime.String("Name"), runtime.NewNativeFunc(ktx, "testing.T.Name", tc.t_Name))
+	tc.ob.Set(runtime.String("Log"), runtime.NewNativeFunc(ktx, "testing.T.Log", tc.t_Log))
+	tc.ob.Set(runtime.String("Error"), runtime.NewNativeFunc(ktx, "testing.T.Error", tc.t_Error))
+	tc.ob.Set(runtime.String("Fatal"), runtime.NewNativeFunc(ktx, "testing.T.Fatal", tc.t_Fatal))
+	tc.ob.Set(runtime.String("Skip"), runtime.NewNativeFunc(ktx, "testing.T.Skip", tc.t_Skip))
+	tc.ob.Set(runtime.String("Failed"), runtime.NewNativeFunc(ktx, "testing.T.Failed", tc.t_Failed))
+	return tc
+}
+
+// Name returns the name of the test.
+func (tc *TestCase) Name() string {
+	return tc.name
+}
+
+// Failed returns true if the test failed.
+func (tc *TestCase) Failed() bool {
+	return tc.failed
+}
+
+// Skipped returns true if the test was skipped.
+func (tc *TestCase) Skipped() bool {
+	return tc.skipped
+}
+
+// Logs returns the messages logged by the test, including the failed
+// assertions and the errors raised by the test function.
+func (tc *TestCase) Logs() []string {
+	return tc.logs
+}
+
+// Run calls the test function with the object of the TestCase as argument.
+// The test fails if the function raises an error, which is added to the logs.
+func (tc *TestCase) Run(ctx context.Context, fn runtime.Func) {
+	defer func() {
+		if p := recover(); p != nil {
+			switch v := p.(type) {
+			case stopTest:
+				// Already recorded by Fatal or Skip
+			case AssertionError:
+				tc.Fail(v.Error())
+			case runtime.Val:
+				tc.Fail("panic: " + v.String(ctx))
+			default:
+				tc.Fail(fmt.Sprintf("panic: %v", v))
+			}
+		}
+	}()
+	fn.Call(ctx, runtime.Nil, tc.ob)
+}
+
+// Fail marks the test as failed, and adds the message to the logs.
+func (tc *TestCase) Fail(msg string) {
+	tc.failed = true
+	tc.logs = append(tc.logs, msg)
+}
+
+func (tc *TestCase) t_Name(ctx context.Context, args ...runtime.Val) runtime.Val {
+	return runtime.String(tc.name)
+}
+
+func (tc *TestCase) t_Log(ctx context.Context, args ...runtime.Val) runtime.Val {
+	tc.logs = append(tc.logs, joinVals(ctx, args))
+	return runtime.Nil
+}
+
+func (tc *TestCase) t_Error(ctx context.Context, args ...runtime.Val) runtime.Val {
+	tc.Fail(joinVals(ctx, args))
+	return runtime.Nil
+}
+
+func (tc *TestCase) t_Fatal(ctx context.Context, args ...runtime.Val) runtime.Val {
+	tc.Fail(joinVals(ctx, args))
+	panic(stopTest{})
+}
+
+func (tc *TestCase) t_Skip(ctx context.Context, args ...runtime.Val) runtime.Val {
+	tc.skipped = true
+	if len(args) > 0 {
+		tc.logs = append(tc.logs, joinVals(ctx, args))
+	}
+	panic(stopTest{})
+}
+
+func (tc *TestCase) t_Failed(ctx context.Context, args ...runtime.Val) runtime.Val {
+	return runtime.Bool(tc.failed)
+}
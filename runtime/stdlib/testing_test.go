@@ -0,0 +1,145 @@
+package stdlib
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/saward/agora/runtime"
+)
+
+// Returns the error raised by the call of fn, or nil.
+func assertErr(fn func()) (err error) {
+	defer runtime.PanicToError(&err)
+	fn()
+	return nil
+}
+
+func TestTestingAssertions(t *testing.T) {
+	ctx := context.Background()
+	ktx := runtime.NewKtx(nil, nil)
+	tm := new(TestingMod)
+	tm.SetKtx(ktx)
+	ob := runtime.NewObject()
+	ob.Set(runtime.String("a"), runtime.Number(1))
+	ob2 := runtime.NewObject()
+	ob2.Set(runtime.String("a"), runtime.Number(1))
+	panicky := runtime.NewNativeFunc(ktx, "panicky", func(context.Context, ...runtime.Val) runtime.Val {
+		panic("boom")
+	})
+	quiet := runtime.NewNativeFunc(ktx, "quiet", func(context.Context, ...runtime.Val) runtime.Val {
+		return runtime.Nil
+	})
+
+	cases := []struct {
+		fn   func(context.Context, ...runtime.Val) runtime.Val
+		args []runtime.Val
+		exp  string
+	}{
+		0: {tm.testing_Equal, []runtime.Val{runtime.Number(1), runtime.Number(1)}, ""},
+		1: {tm.testing_Equal, []runtime.Val{runtime.String("a"), runtime.Number(1), runtime.String("msg")},
+			`Equal: expected 1, got "a": msg`},
+		2: {tm.testing_NotEqual, []runtime.Val{runtime.Number(1), runtime.Number(2)}, ""},
+		3: {tm.testing_NotEqual, []runtime.Val{runtime.Nil, runtime.Nil}, "NotEqual: expected a value other than nil"},
+		4: {tm.testing_Equal, []runtime.Val{ob, ob2}, "Equal: expected {a:1}, got {a:1}"},
+		5: {tm.testing_DeepEqual, []runtime.Val{ob, ob2}, ""},
+		6: {tm.testing_DeepEqual, []runtime.Val{ob, runtime.NewObject()}, "DeepEqual: expected {}, got {a:1}"},
+		7: {tm.testing_True, []runtime.Val{runtime.Bool(true)}, ""},
+		8: {tm.testing_False, []runtime.Val{runtime.Number(3)}, "False: got 3"},
+		9: {tm.testing_Panics, []runtime.Val{panicky}, ""},
+		10: {tm.testing_Panics, []runtime.Val{quiet, runtime.String("should"), runtime.String("panic")},
+			"Panics: the function did not raise an error: should panic"},
+		11: {tm.testing_Fail, nil, "Fail: failed"},
+		12: {tm.testing_Fail, []runtime.Val{runtime.String("not"), runtime.Number(2)}, "Fail: not 2"},
+	}
+	for i, c := range cases {
+		err := assertErr(func() { c.fn(ctx, c.args...) })
+		if c.exp == "" {
+			if err != nil {
+				t.Errorf("[%d] - expected no error, got %s", i, err)
+			}
+			continue
+		}
+		if _, ok := err.(AssertionError); !ok || err.Error() != c.exp {
+			t.Errorf("[%d] - expected assertion error %q, got %v", i, c.exp, err)
+		}
+	}
+	if v := tm.testing_Panics(ctx, panicky); v.String(ctx) != "boom" {
+		t.Errorf("expected the error to be returned, got %s", v)
+	}
+}
+
+func TestTestCase(t *testing.T) {
+	ctx := context.Background()
+	ktx := runtime.NewKtx(nil, nil)
+	tm := new(TestingMod)
+	tm.SetKtx(ktx)
+
+	cases := []struct {
+		fn      func(context.Context, *TestCase) runtime.Val
+		failed  bool
+		skipped bool
+		logs    string
+	}{
+		0: {
+			fn: func(ctx context.Context, tc *TestCase) runtime.Val {
+				return tc.t_Log(ctx, runtime.String("hello"), runtime.Number(1))
+			},
+			logs: "hello 1",
+		},
+		1: {
+			fn: func(ctx context.Context, tc *TestCase) runtime.Val {
+				tc.t_Error(ctx, runtime.String("first"))
+				return tc.t_Log(ctx, tc.t_Failed(ctx))
+			},
+			failed: true,
+			logs:   "first|true",
+		},
+		2: {
+			fn: func(ctx context.Context, tc *TestCase) runtime.Val {
+				tc.t_Fatal(ctx, runtime.String("stop"))
+				return tc.t_Log(ctx, runtime.String("unreachable"))
+			},
+			failed: true,
+			logs:   "stop",
+		},
+		3: {
+			fn: func(ctx context.Context, tc *TestCase) runtime.Val {
+				tc.t_Skip(ctx, runtime.String("later"))
+				return tc.t_Error(ctx, runtime.String("unreachable"))
+			},
+			skipped: true,
+			logs:    "later",
+		},
+		4: {
+			fn: func(ctx context.Context, tc *TestCase) runtime.Val {
+				return tm.testing_Equal(ctx, tc.t_Name(ctx), runtime.String("other"))
+			},
+			failed: true,
+			logs:   `Equal: expected "other", got "TestX"`,
+		},
+		5: {
+			fn: func(ctx context.Context, tc *TestCase) runtime.Val {
+				panic(runtime.String("raised"))
+			},
+			failed: true,
+			logs:   "panic: raised",
+		},
+	}
+	for i, c := range cases {
+		tc := NewTestCase(ktx, "TestX")
+		fn := c.fn
+		tc.Run(ctx, runtime.NewNativeFunc(ktx, "test", func(ctx context.Context, args ...runtime.Val) runtime.Val {
+			if len(args) != 1 || args[0] != tc.ob {
+				t.Errorf("[%d] - expected the test case object as argument", i)
+			}
+			return fn(ctx, tc)
+		}))
+		if tc.Failed() != c.failed || tc.Skipped() != c.skipped {
+			t.Errorf("[%d] - expected failed=%t skipped=%t, got %t %t", i, c.failed, c.skipped, tc.Failed(), tc.Skipped())
+		}
+		if logs := strings.Join(tc.Logs(), "|"); logs != c.logs {
+			t.Errorf("[%d] - expected logs %q, got %q", i, c.logs, logs)
+		}
+	}
+}
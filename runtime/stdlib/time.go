@@ -14,6 +14,32 @@ type TimeMod struct {
 	ob  runtime.Object
 }
 
+// The duration units, exposed as duration values.
+var units = []struct {
+	nm string
+	d  time.Duration
+}{
+	{"Nanosecond", time.Nanosecond},
+	{"Microsecond", time.Microsecond},
+	{"Millisecond", time.Millisecond},
+	{"Second", time.Second},
+	{"Minute", time.Minute},
+	{"Hour", time.Hour},
+}
+
+// The predefined layouts, exposed as strings.
+var layouts = map[string]string{
+	"ANSIC":       time.ANSIC,
+	"RFC822":      time.RFC822,
+	"RFC1123":     time.RFC1123,
+	"RFC3339":     time.RFC3339,
+	"RFC3339Nano": time.RFC3339Nano,
+	"Kitchen":     time.Kitchen,
+	"DateTime":    "2006-01-02 15:04:05",
+	"DateOnly":    "2006-01-02",
+	"TimeOnly":    "15:04:05",
+}
+
 func (t *TimeMod) ID() string {
 	return "time"
 }
@@ -26,6 +52,18 @@ func (t *TimeMod) Run(_ context.Context, _ ...runtime.Val) (v runtime.Val, err e
 		t.ob.Set(runtime.String("Date"), runtime.NewNativeFunc(t.ktx, "time.Date", t.time_Date))
 		t.ob.Set(runtime.String("Now"), runtime.NewNativeFunc(t.ktx, "time.Now", t.time_Now))
 		t.ob.Set(runtime.String("Sleep"), runtime.NewNativeFunc(t.ktx, "time.Sleep", t.time_Sleep))
+		t.ob.Set(runtime.String("Parse"), runtime.NewNativeFunc(t.ktx, "time.Parse", t.time_Parse))
+		t.ob.Set(runtime.String("Unix"), runtime.NewNativeFunc(t.ktx, "time.Unix", t.time_Unix))
+		t.ob.Set(runtime.String("UnixNano"), runtime.NewNativeFunc(t.ktx, "time.UnixNano", t.time_UnixNano))
+		t.ob.Set(runtime.String("Since"), runtime.NewNativeFunc(t.ktx, "time.Since", t.time_Since))
+		t.ob.Set(runtime.String("Duration"), runtime.NewNativeFunc(t.ktx, "time.Duration", t.time_Duration))
+		t.ob.Set(runtime.String("ParseDuration"), runtime.NewNativeFunc(t.ktx, "time.ParseDuration", t.time_ParseDuration))
+		for _, u := range units {
+			t.ob.Set(runtime.String(u.nm), t.newDuration(u.d))
+		}
+		for nm, l := range layouts {
+			t.ob.Set(runtime.String(nm), runtime.String(l))
+		}
 	}
 	return t.ob, nil
 }
@@ -34,9 +72,24 @@ func (t *TimeMod) SetKtx(c *runtime.Kontext) {
 	t.ktx = c
 }
 
+func (t *TimeMod) Clone() runtime.NativeModule {
+	return new(TimeMod)
+}
+
+// Args:
+// 0 - The duration, a duration value or a number of milliseconds
+// Returns:
+// Nil, after the duration has elapsed. It panics if the execution is
+// cancelled.
 func (t *TimeMod) time_Sleep(ctx context.Context, args ...runtime.Val) runtime.Val {
 	runtime.ExpectAtLeastNArgs(1, args)
-	time.Sleep(time.Duration(args[0].Int(ctx)) * time.Millisecond)
+	tm := time.NewTimer(toDuration(ctx, args[0], "time.Sleep"))
+	defer tm.Stop()
+	select {
+	case <-tm.C:
+	case <-ctx.Done():
+		panic(ctx.Err())
+	}
 	return runtime.Nil
 }
 
@@ -45,17 +98,144 @@ type _time struct {
 	t time.Time
 }
 
+type _duration struct {
+	runtime.Object
+	d time.Duration
+}
+
+// Returns the time held by the value v, which must be a time value.
+func toTime(v runtime.Val, op string) time.Time {
+	if t, ok := v.(*_time); ok {
+		return t.t
+	}
+	panic(runtime.NewTypeError(runtime.Type(v), "", op))
+}
+
+// Returns the duration held by the value v, which must be a duration value
+// or a number of milliseconds.
+func toDuration(ctx context.Context, v runtime.Val, op string) time.Duration {
+	d, ok := asDuration(ctx, v)
+	if !ok {
+		panic(runtime.NewTypeError(runtime.Type(v), "", op))
+	}
+	return d
+}
+
+// Returns the duration held by the value v, and false if v is neither a
+// duration value nor a number.
+func asDuration(ctx context.Context, v runtime.Val) (time.Duration, bool) {
+	switch v := v.(type) {
+	case *_duration:
+		return v.d, true
+	case runtime.Number:
+		return time.Duration(v.Float(ctx) * float64(time.Millisecond)), true
+	}
+	return 0, false
+}
+
+// Returns the result of the comparison of a and b, reversed if the object
+// is the right operand.
+func cmpResult(a, b int64, isLeft bool) runtime.Val {
+	res := 0
+	if a < b {
+		res = -1
+	} else if a > b {
+		res = 1
+	}
+	if !isLeft {
+		res = -res
+	}
+	return runtime.Number(res)
+}
+
 func (t *TimeMod) newTime(tm time.Time) runtime.Val {
 	ob := &_time{
 		runtime.NewObject(),
 		tm,
 	}
-	ob.Set(runtime.String("__int"), runtime.NewNativeFunc(t.ktx, "time._time.__int", func(_ context.Context, args ...runtime.Val) runtime.Val {
+	set := func(nm string, fn func(context.Context, ...runtime.Val) runtime.Val) {
+		ob.Set(runtime.String(nm), runtime.NewNativeFunc(t.ktx, "time._time."+nm, fn))
+	}
+	set("__int", func(_ context.Context, args ...runtime.Val) runtime.Val {
 		return runtime.Number(ob.t.Unix())
-	}))
-	ob.Set(runtime.String("__string"), runtime.NewNativeFunc(t.ktx, "time._time.__string", func(_ context.Context, args ...runtime.Val) runtime.Val {
+	})
+	set("__string", func(_ context.Context, args ...runtime.Val) runtime.Val {
 		return runtime.String(ob.t.Format(time.RFC3339))
-	}))
+	})
+	set("__cmp", func(ctx context.Context, args ...runtime.Val) runtime.Val {
+		runtime.ExpectAtLeastNArgs(2, args)
+		u, ok := args[0].(*_time)
+		if !ok {
+			// "greater" or "lower" has no sense for other values, return -1
+			return runtime.Number(-1)
+		}
+		if ob.t.Equal(u.t) {
+			return runtime.Number(0)
+		}
+		if ob.t.Before(u.t) {
+			return cmpResult(0, 1, args[1].Bool(ctx))
+		}
+		return cmpResult(1, 0, args[1].Bool(ctx))
+	})
+	set("__add", func(ctx context.Context, args ...runtime.Val) runtime.Val {
+		runtime.ExpectAtLeastNArgs(2, args)
+		d, ok := asDuration(ctx, args[0])
+		if !ok {
+			panic(runtime.NewTypeError(runtime.Type(ob), runtime.Type(args[0]), "add"))
+		}
+		return t.newTime(ob.t.Add(d))
+	})
+	set("__sub", func(ctx context.Context, args ...runtime.Val) runtime.Val {
+		runtime.ExpectAtLeastNArgs(2, args)
+		if u, ok := args[0].(*_time); ok {
+			if args[1].Bool(ctx) {
+				return t.newDuration(ob.t.Sub(u.t))
+			}
+			return t.newDuration(u.t.Sub(ob.t))
+		}
+		if d, ok := asDuration(ctx, args[0]); ok && args[1].Bool(ctx) {
+			return t.newTime(ob.t.Add(-d))
+		}
+		panic(runtime.NewTypeError(runtime.Type(ob), runtime.Type(args[0]), "sub"))
+	})
+	set("Format", func(ctx context.Context, args ...runtime.Val) runtime.Val {
+		runtime.ExpectAtLeastNArgs(1, args)
+		return runtime.String(ob.t.Format(args[0].String(ctx)))
+	})
+	set("Add", func(ctx context.Context, args ...runtime.Val) runtime.Val {
+		runtime.ExpectAtLeastNArgs(1, args)
+		return t.newTime(ob.t.Add(toDuration(ctx, args[0], "time._time.Add")))
+	})
+	set("Sub", func(ctx context.Context, args ...runtime.Val) runtime.Val {
+		runtime.ExpectAtLeastNArgs(1, args)
+		return t.newDuration(ob.t.Sub(toTime(args[0], "time._time.Sub")))
+	})
+	set("Before", func(ctx context.Context, args ...runtime.Val) runtime.Val {
+		runtime.ExpectAtLeastNArgs(1, args)
+		return runtime.Bool(ob.t.Before(toTime(args[0], "time._time.Before")))
+	})
+	set("After", func(ctx context.Context, args ...runtime.Val) runtime.Val {
+		runtime.ExpectAtLeastNArgs(1, args)
+		return runtime.Bool(ob.t.After(toTime(args[0], "time._time.After")))
+	})
+	set("Equal", func(ctx context.Context, args ...runtime.Val) runtime.Val {
+		runtime.ExpectAtLeastNArgs(1, args)
+		return runtime.Bool(ob.t.Equal(toTime(args[0], "time._time.Equal")))
+	})
+	set("In", func(ctx context.Context, args ...runtime.Val) runtime.Val {
+		runtime.ExpectAtLeastNArgs(1, args)
+		return t.newTime(ob.t.In(loadLocation(args[0].String(ctx))))
+	})
+	set("Truncate", func(ctx context.Context, args ...runtime.Val) runtime.Val {
+		runtime.ExpectAtLeastNArgs(1, args)
+		return t.newTime(ob.t.Truncate(toDuration(ctx, args[0], "time._time.Truncate")))
+	})
+	set("Unix", func(_ context.Context, args ...runtime.Val) runtime.Val {
+		return runtime.Number(ob.t.Unix())
+	})
+	set("UnixNano", func(_ context.Context, args ...runtime.Val) runtime.Val {
+		return runtime.Number(ob.t.UnixNano())
+	})
 	ob.Set(runtime.String("Year"), runtime.Number(tm.Year()))
 	ob.Set(runtime.String("Month"), runtime.Number(tm.Month()))
 	ob.Set(runtime.String("Day"), runtime.Number(tm.Day()))
@@ -63,9 +243,123 @@ func (t *TimeMod) newTime(tm time.Time) runtime.Val {
 	ob.Set(runtime.String("Minute"), runtime.Number(tm.Minute()))
 	ob.Set(runtime.String("Second"), runtime.Number(tm.Second()))
 	ob.Set(runtime.String("Nanosecond"), runtime.Number(tm.Nanosecond()))
+	ob.Set(runtime.String("Weekday"), runtime.Number(tm.Weekday()))
+	ob.Set(runtime.String("YearDay"), runtime.Number(tm.YearDay()))
+	zone, _ := tm.Zone()
+	ob.Set(runtime.String("Zone"), runtime.String(zone))
 	return ob
 }
 
+func (t *TimeMod) newDuration(d time.Duration) runtime.Val {
+	ob := &_duration{
+		runtime.NewObject(),
+		d,
+	}
+	set := func(nm string, fn func(context.Context, ...runtime.Val) runtime.Val) {
+		ob.Set(runtime.String(nm), runtime.NewNativeFunc(t.ktx, "time._duration."+nm, fn))
+	}
+	set("__int", func(_ context.Context, args ...runtime.Val) runtime.Val {
+		return runtime.Number(ob.d / time.Millisecond)
+	})
+	set("__float", func(_ context.Context, args ...runtime.Val) runtime.Val {
+		return runtime.Number(float64(ob.d) / float64(time.Millisecond))
+	})
+	set("__string", func(_ context.Context, args ...runtime.Val) runtime.Val {
+		return runtime.String(ob.d.String())
+	})
+	set("__cmp", func(ctx context.Context, args ...runtime.Val) runtime.Val {
+		runtime.ExpectAtLeastNArgs(2, args)
+		d, ok := asDuration(ctx, args[0])
+		if !ok {
+			// "greater" or "lower" has no sense for other values, return -1
+			return runtime.Number(-1)
+		}
+		return cmpResult(int64(ob.d), int64(d), args[1].Bool(ctx))
+	})
+	set("__unm", func(_ context.Context, args ...runtime.Val) runtime.Val {
+		return t.newDuration(-ob.d)
+	})
+	set("__add", func(ctx context.Context, args ...runtime.Val) runtime.Val {
+		runtime.ExpectAtLeastNArgs(2, args)
+		if u, ok := args[0].(*_time); ok {
+			return t.newTime(u.t.Add(ob.d))
+		}
+		d, ok := asDuration(ctx, args[0])
+		if !ok {
+			panic(runtime.NewTypeError(runtime.Type(ob), runtime.Type(args[0]), "add"))
+		}
+		return t.newDuration(ob.d + d)
+	})
+	set("__sub", func(ctx context.Context, args ...runtime.Val) runtime.Val {
+		runtime.ExpectAtLeastNArgs(2, args)
+		d, ok := asDuration(ctx, args[0])
+		if !ok {
+			panic(runtime.NewTypeError(runtime.Type(ob), runtime.Type(args[0]), "sub"))
+		}
+		if args[1].Bool(ctx) {
+			return t.newDuration(ob.d - d)
+		}
+		return t.newDuration(d - ob.d)
+	})
+	set("__mul", func(ctx context.Context, args ...runtime.Val) runtime.Val {
+		runtime.ExpectAtLeastNArgs(2, args)
+		n, ok := args[0].(runtime.Number)
+		if !ok {
+			panic(runtime.NewTypeError(runtime.Type(ob), runtime.Type(args[0]), "mul"))
+		}
+		return t.newDuration(time.Duration(float64(ob.d) * n.Float(ctx)))
+	})
+	set("__div", func(ctx context.Context, args ...runtime.Val) runtime.Val {
+		runtime.ExpectAtLeastNArgs(2, args)
+		switch v := args[0].(type) {
+		case *_duration:
+			if args[1].Bool(ctx) {
+				return runtime.Number(float64(ob.d) / float64(v.d))
+			}
+			return runtime.Number(float64(v.d) / float64(ob.d))
+		case runtime.Number:
+			if args[1].Bool(ctx) {
+				return t.newDuration(time.Duration(float64(ob.d) / v.Float(ctx)))
+			}
+		}
+		panic(runtime.NewTypeError(runtime.Type(ob), runtime.Type(args[0]), "div"))
+	})
+	set("__mod", func(ctx context.Context, args ...runtime.Val) runtime.Val {
+		runtime.ExpectAtLeastNArgs(2, args)
+		v, ok := args[0].(*_duration)
+		if !ok {
+			panic(runtime.NewTypeError(runtime.Type(ob), runtime.Type(args[0]), "mod"))
+		}
+		if args[1].Bool(ctx) {
+			return t.newDuration(ob.d % v.d)
+		}
+		return t.newDuration(v.d % ob.d)
+	})
+	set("Truncate", func(ctx context.Context, args ...runtime.Val) runtime.Val {
+		runtime.ExpectAtLeastNArgs(1, args)
+		return t.newDuration(ob.d.Truncate(toDuration(ctx, args[0], "time._duration.Truncate")))
+	})
+	set("Round", func(ctx context.Context, args ...runtime.Val) runtime.Val {
+		runtime.ExpectAtLeastNArgs(1, args)
+		return t.newDuration(ob.d.Round(toDuration(ctx, args[0], "time._duration.Round")))
+	})
+	ob.Set(runtime.String("Hours"), runtime.Number(d.Hours()))
+	ob.Set(runtime.String("Minutes"), runtime.Number(d.Minutes()))
+	ob.Set(runtime.String("Seconds"), runtime.Number(d.Seconds()))
+	ob.Set(runtime.String("Milliseconds"), runtime.Number(d.Milliseconds()))
+	ob.Set(runtime.String("Nanoseconds"), runtime.Number(d.Nanoseconds()))
+	return ob
+}
+
+// Returns the location named nm, panicking if it does not exist.
+func loadLocation(nm string) *time.Location {
+	loc, err := time.LoadLocation(nm)
+	if err != nil {
+		panic(err)
+	}
+	return loc
+}
+
 func (t *TimeMod) time_Now(ctx context.Context, args ...runtime.Val) runtime.Val {
 	return t.newTime(time.Now())
 }
@@ -99,3 +393,77 @@ func (t *TimeMod) time_Date(ctx context.Context, args ...runtime.Val) runtime.Va
 	}
 	return t.newTime(time.Date(yr, time.Month(mth), dy, hr, min, sec, nsec, time.UTC))
 }
+
+// Args:
+// 0 - The layout, as defined by Go's time package
+// 1 - The string to parse
+// 2 - The name of the location of times without zone (optional, defaults
+// to UTC)
+// Returns:
+// The time value.
+func (t *TimeMod) time_Parse(ctx context.Context, args ...runtime.Val) runtime.Val {
+	runtime.ExpectAtLeastNArgs(2, args)
+	loc := time.UTC
+	if len(args) > 2 {
+		loc = loadLocation(args[2].String(ctx))
+	}
+	tm, err := time.ParseInLocation(args[0].String(ctx), args[1].String(ctx), loc)
+	if err != nil {
+		panic(err)
+	}
+	return t.newTime(tm)
+}
+
+// Args:
+// 0 - The number of seconds since January 1, 1970 UTC
+// 1 - The number of nanoseconds (optional)
+// Returns:
+// The time value, in local time.
+func (t *TimeMod) time_Unix(ctx context.Context, args ...runtime.Val) runtime.Val {
+	runtime.ExpectAtLeastNArgs(1, args)
+	var nsec int64
+	if len(args) > 1 {
+		nsec = args[1].Int(ctx)
+	}
+	return t.newTime(time.Unix(args[0].Int(ctx), nsec))
+}
+
+// Args:
+// 0 - The number of nanoseconds since January 1, 1970 UTC
+// Returns:
+// The time value, in local time.
+func (t *TimeMod) time_UnixNano(ctx context.Context, args ...runtime.Val) runtime.Val {
+	runtime.ExpectAtLeastNArgs(1, args)
+	return t.newTime(time.Unix(0, args[0].Int(ctx)))
+}
+
+// Args:
+// 0 - The time value
+// Returns:
+// The duration elapsed since the time.
+func (t *TimeMod) time_Since(ctx context.Context, args ...runtime.Val) runtime.Val {
+	runtime.ExpectAtLeastNArgs(1, args)
+	return t.newDuration(time.Since(toTime(args[0], "time.Since")))
+}
+
+// Args:
+// 0 - The number of milliseconds
+// Returns:
+// The duration value.
+func (t *TimeMod) time_Duration(ctx context.Context, args ...runtime.Val) runtime.Val {
+	runtime.ExpectAtLeastNArgs(1, args)
+	return t.newDuration(toDuration(ctx, args[0], "time.Duration"))
+}
+
+// Args:
+// 0 - The duration string, as defined by Go's time package, e.g. "1h30m"
+// Returns:
+// The duration value.
+func (t *TimeMod) time_ParseDuration(ctx context.Context, args ...runtime.Val) runtime.Val {
+	runtime.ExpectAtLeastNArgs(1, args)
+	d, err := time.ParseDuration(args[0].String(ctx))
+	if err != nil {
+		panic(err)
+	}
+	return t.newDuration(d)
+}
@@ -182,3 +182,155 @@ func TestTimeDate(t *testing.T) {
 		}
 	}
 }
+
+func TestTimeParseFormat(t *testing.T) {
+	ctx := context.Background()
+	ktx := runtime.NewKtx(nil, nil)
+	tm := new(TimeMod)
+	tm.SetKtx(ktx)
+	ob := tm.time_Parse(ctx, runtime.String("2006-01-02 15:04"), runtime.String("2024-03-10 08:30")).(runtime.Object)
+	if h := ob.Get(runtime.String("Hour")); h.Int(ctx) != 8 {
+		t.Errorf("expected hour 8, got %d", h.Int(ctx))
+	}
+	if s := ob.String(ctx); s != "2024-03-10T08:30:00Z" {
+		t.Errorf("expected UTC time, got %s", s)
+	}
+	ret := ob.Get(runtime.String("Format")).(runtime.Func).Call(ctx, nil, runtime.String(time.Kitchen))
+	if ret.String(ctx) != "8:30AM" {
+		t.Errorf("expected 8:30AM, got %s", ret)
+	}
+	ob = tm.time_Parse(ctx, runtime.String(time.RFC3339), runtime.String("2024-03-10T08:30:00+02:00")).(runtime.Object)
+	if u := ob.Get(runtime.String("Unix")).(runtime.Func).Call(ctx, nil); u.Int(ctx) != 1710052200 {
+		t.Errorf("expected unix time 1710052200, got %d", u.Int(ctx))
+	}
+	ret = ob.Get(runtime.String("In")).(runtime.Func).Call(ctx, nil, runtime.String("UTC"))
+	if s := ret.String(ctx); s != "2024-03-10T06:30:00Z" {
+		t.Errorf("expected UTC time, got %s", s)
+	}
+	if err := assertErr(func() { tm.time_Parse(ctx, runtime.String(time.RFC3339), runtime.String("x")) }); err == nil {
+		t.Errorf("expected error on invalid time")
+	}
+	if err := assertErr(func() { ob.Get(runtime.String("In")).(runtime.Func).Call(ctx, nil, runtime.String("Nowhere/Invalid")) }); err == nil {
+		t.Errorf("expected error on invalid zone")
+	}
+}
+
+func TestTimeUnix(t *testing.T) {
+	ctx := context.Background()
+	ktx := runtime.NewKtx(nil, nil)
+	tm := new(TimeMod)
+	tm.SetKtx(ktx)
+	ob := tm.time_Unix(ctx, runtime.Number(1710052200), runtime.Number(5)).(runtime.Object)
+	if ns := ob.Get(runtime.String("Nanosecond")); ns.Int(ctx) != 5 {
+		t.Errorf("expected nanosecond 5, got %d", ns.Int(ctx))
+	}
+	if u := ob.Int(ctx); u != 1710052200 {
+		t.Errorf("expected unix time 1710052200, got %d", u)
+	}
+	ob = tm.time_UnixNano(ctx, runtime.Number(1500000000)).(runtime.Object)
+	if u := ob.Int(ctx); u != 1 {
+		t.Errorf("expected unix time 1, got %d", u)
+	}
+}
+
+func TestTimeArithmetic(t *testing.T) {
+	ctx := context.Background()
+	ktx := runtime.NewKtx(nil, nil)
+	tm := new(TimeMod)
+	tm.SetKtx(ktx)
+	ar, cmp := ktx.Arithmetic, ktx.Comparer
+	t1 := tm.time_Date(ctx, runtime.Number(2024), runtime.Number(3), runtime.Number(10), runtime.Number(8))
+	hr := tm.newDuration(time.Hour)
+
+	// time + duration and duration + time
+	t2 := ar.Add(ctx, t1, ar.Mul(ctx, runtime.Number(1.5), hr))
+	if s := t2.String(ctx); s != "2024-03-10T09:30:00Z" {
+		t.Errorf("expected 09:30, got %s", s)
+	}
+	if s := ar.Add(ctx, hr, t1).String(ctx); s != "2024-03-10T09:00:00Z" {
+		t.Errorf("expected 09:00, got %s", s)
+	}
+	// time - time and time - duration
+	d := ar.Sub(ctx, t2, t1)
+	if s := d.String(ctx); s != "1h30m0s" {
+		t.Errorf("expected 1h30m0s, got %s", s)
+	}
+	if s := ar.Sub(ctx, t2, runtime.Number(1800000)).String(ctx); s != "2024-03-10T09:00:00Z" {
+		t.Errorf("expected 09:00, got %s", s)
+	}
+	if err := assertErr(func() { ar.Sub(ctx, hr, t1) }); err == nil {
+		t.Errorf("expected error for duration - time")
+	}
+	if err := assertErr(func() { ar.Sub(ctx, runtime.Number(1), t1) }); err == nil {
+		t.Errorf("expected error for number - time")
+	}
+	// duration operations
+	if n := ar.Div(ctx, d, hr); n != runtime.Number(1.5) {
+		t.Errorf("expected 1.5, got %v", n)
+	}
+	if s := ar.Mod(ctx, d, hr).String(ctx); s != "30m0s" {
+		t.Errorf("expected 30m0s, got %s", s)
+	}
+	if s := ar.Unm(ctx, d).String(ctx); s != "-1h30m0s" {
+		t.Errorf("expected -1h30m0s, got %s", s)
+	}
+	if n := d.Int(ctx); n != 5400000 {
+		t.Errorf("expected 5400000 milliseconds, got %d", n)
+	}
+	if m := d.(runtime.Object).Get(runtime.String("Minutes")); m != runtime.Number(90) {
+		t.Errorf("expected 90 minutes, got %v", m)
+	}
+
+	// comparisons
+	if c := cmp.Cmp(ctx, t1, t2); c != -1 {
+		t.Errorf("expected t1 < t2, got %d", c)
+	}
+	if c := cmp.Cmp(ctx, t2, t1); c != 1 {
+		t.Errorf("expected t2 > t1, got %d", c)
+	}
+	if c := cmp.Cmp(ctx, ar.Add(ctx, t1, d), t2); c != 0 {
+		t.Errorf("expected equal times, got %d", c)
+	}
+	if c := cmp.Cmp(ctx, runtime.Number(1000), hr); c != -1 {
+		t.Errorf("expected 1000 < hr, got %d", c)
+	}
+	if c := cmp.Cmp(ctx, t1, runtime.Nil); c == 0 {
+		t.Errorf("expected time to be different from nil")
+	}
+	before := t1.(runtime.Object).Get(runtime.String("Before")).(runtime.Func)
+	if b := before.Call(ctx, nil, t2); b != runtime.Bool(true) {
+		t.Errorf("expected t1 before t2")
+	}
+	trunc := t2.(runtime.Object).Get(runtime.String("Truncate")).(runtime.Func)
+	if s := trunc.Call(ctx, nil, hr).String(ctx); s != "2024-03-10T09:00:00Z" {
+		t.Errorf("expected 09:00, got %s", s)
+	}
+}
+
+func TestTimeDuration(t *testing.T) {
+	ctx := context.Background()
+	ktx := runtime.NewKtx(nil, nil)
+	tm := new(TimeMod)
+	tm.SetKtx(ktx)
+	if s := tm.time_Duration(ctx, runtime.Number(1500)).String(ctx); s != "1.5s" {
+		t.Errorf("expected 1.5s, got %s", s)
+	}
+	d := tm.time_ParseDuration(ctx, runtime.String("2h45m"))
+	if f := d.Float(ctx); f != 9900000 {
+		t.Errorf("expected 9900000 milliseconds, got %f", f)
+	}
+	if err := assertErr(func() { tm.time_ParseDuration(ctx, runtime.String("x")) }); err == nil {
+		t.Errorf("expected error on invalid duration")
+	}
+	since := tm.time_Since(ctx, tm.time_Now(ctx))
+	if _, ok := since.(*_duration); !ok {
+		t.Errorf("expected a duration, got %T", since)
+	}
+
+	// Sleep is cancellable
+	cctx, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	if err := assertErr(func() { tm.time_Sleep(cctx, tm.newDuration(time.Second)) }); err != context.DeadlineExceeded {
+		t.Errorf("expected %v, got %v", context.DeadlineExceeded, err)
+	}
+}
@@ -0,0 +1,139 @@
+package stdlib
+
+import (
+	"context"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/saward/agora/runtime"
+)
+
+// The unicode module, as documented in
+// https://github.com/saward/agora/wiki/Standard-library
+type UnicodeMod struct {
+	ktx *runtime.Kontext
+	ob  runtime.Object
+}
+
+func (u *UnicodeMod) ID() string {
+	return "unicode"
+}
+
+func (u *UnicodeMod) Run(_ context.Context, _ ...runtime.Val) (v runtime.Val, err error) {
+	defer runtime.PanicToError(&err)
+	if u.ob == nil {
+		// Prepare the object
+		u.ob = runtime.NewObject()
+		u.ob.Set(runtime.String("RuneCount"), runtime.NewNativeFunc(u.ktx, "unicode.RuneCount", u.unicode_RuneCount))
+		u.ob.Set(runtime.String("Runes"), runtime.NewNativeFunc(u.ktx, "unicode.Runes", u.unicode_Runes))
+		u.ob.Set(runtime.String("Valid"), runtime.NewNativeFunc(u.ktx, "unicode.Valid", u.unicode_Valid))
+		u.ob.Set(runtime.String("IsLetter"), runtime.NewNativeFunc(u.ktx, "unicode.IsLetter", u.isFunc(unicode.IsLetter)))
+		u.ob.Set(runtime.String("IsDigit"), runtime.NewNativeFunc(u.ktx, "unicode.IsDigit", u.isFunc(unicode.IsDigit)))
+		u.ob.Set(runtime.String("IsSpace"), runtime.NewNativeFunc(u.ktx, "unicode.IsSpace", u.isFunc(unicode.IsSpace)))
+		u.ob.Set(runtime.String("IsUpper"), runtime.NewNativeFunc(u.ktx, "unicode.IsUpper", u.isFunc(unicode.IsUpper)))
+		u.ob.Set(runtime.String("IsLower"), runtime.NewNativeFunc(u.ktx, "unicode.IsLower", u.isFunc(unicode.IsLower)))
+		u.ob.Set(runtime.String("IsPunct"), runtime.NewNativeFunc(u.ktx, "unicode.IsPunct", u.isFunc(unicode.IsPunct)))
+		u.ob.Set(runtime.String("Fold"), runtime.NewNativeFunc(u.ktx, "unicode.Fold", u.unicode_Fold))
+		u.ob.Set(runtime.String("EqualFold"), runtime.NewNativeFunc(u.ktx, "unicode.EqualFold", u.unicode_EqualFold))
+	}
+	return u.ob, nil
+}
+
+func (u *UnicodeMod) SetKtx(c *runtime.Kontext) {
+	u.ktx = c
+}
+
+func (u *UnicodeMod) Clone() runtime.NativeModule {
+	return new(UnicodeMod)
+}
+
+// Args:
+// 0 - The string
+// Returns:
+// The number of runes in the string, an invalid UTF-8 byte counting as one
+// rune.
+func (u *UnicodeMod) unicode_RuneCount(ctx context.Context, args ...runtime.Val) runtime.Val {
+	runtime.ExpectAtLeastNArgs(1, args)
+	return runtime.Number(utf8.RuneCountInString(args[0].String(ctx)))
+}
+
+// Args:
+// 0 - The string
+// Returns:
+// The array-like object holding the runes of the string, each as a string.
+func (u *UnicodeMod) unicode_Runes(ctx context.Context, args ...runtime.Val) runtime.Val {
+	runtime.ExpectAtLeastNArgs(1, args)
+	src := args[0].String(ctx)
+	ob := runtime.NewObject()
+	for i, j := 0, 0; i < len(src); j++ {
+		_, n := utf8.DecodeRuneInString(src[i:])
+		ob.Set(runtime.Number(j), runtime.String(src[i:i+n]))
+		i += n
+	}
+	return ob
+}
+
+// Args:
+// 0 - The string
+// Returns:
+// True if the string is valid UTF-8.
+func (u *UnicodeMod) unicode_Valid(ctx context.Context, args ...runtime.Val) runtime.Val {
+	runtime.ExpectAtLeastNArgs(1, args)
+	return runtime.Bool(utf8.ValidString(args[0].String(ctx)))
+}
+
+// Returns the native func that checks the runes of a string with fn.
+//
+// Args:
+// 0 - The string
+// Returns:
+// True if the string is not empty and all its runes satisfy fn.
+func (u *UnicodeMod) isFunc(fn func(rune) bool) func(context.Context, ...runtime.Val) runtime.Val {
+	return func(ctx context.Context, args ...runtime.Val) runtime.Val {
+		runtime.ExpectAtLeastNArgs(1, args)
+		src := args[0].String(ctx)
+		if src == "" {
+			return runtime.Bool(false)
+		}
+		for _, r := range src {
+			if !fn(r) {
+				return runtime.Bool(false)
+			}
+		}
+		return runtime.Bool(true)
+	}
+}
+
+// Returns the simple case folding of the rune r, the smallest rune that is
+// equivalent to r under case folding.
+func foldRune(r rune) rune {
+	min := r
+	for f := unicode.SimpleFold(r); f != r; f = unicode.SimpleFold(f) {
+		if f < min {
+			min = f
+		}
+	}
+	return min
+}
+
+// Args:
+// 0 - The string
+// Returns:
+// The string with each rune replaced by its simple case folding, so that
+// strings equal under case folding have the same folded string. No unicode
+// normalization is applied.
+func (u *UnicodeMod) unicode_Fold(ctx context.Context, args ...runtime.Val) runtime.Val {
+	runtime.ExpectAtLeastNArgs(1, args)
+	return runtime.String(strings.Map(foldRune, args[0].String(ctx)))
+}
+
+// Args:
+// 0 - The first string
+// 1 - The second string
+// Returns:
+// True if the strings are equal under simple case folding.
+func (u *UnicodeMod) unicode_EqualFold(ctx context.Context, args ...runtime.Val) runtime.Val {
+	runtime.ExpectAtLeastNArgs(2, args)
+	return runtime.Bool(strings.EqualFold(args[0].String(ctx), args[1].String(ctx)))
+}
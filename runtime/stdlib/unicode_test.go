@@ -0,0 +1,104 @@
+package stdlib
+
+import (
+	"context"
+	"testing"
+
+	"github.com/saward/agora/runtime"
+)
+
+func TestUnicodeRunes(t *testing.T) {
+	ctx := context.Background()
+	ktx := runtime.NewKtx(nil, nil)
+	um := new(UnicodeMod)
+	um.SetKtx(ktx)
+	cases := []struct {
+		src   string
+		runes []string
+		valid bool
+	}{
+		0: {"", nil, true},
+		1: {"abc", []string{"a", "b", "c"}, true},
+		2: {"héllo", []string{"h", "é", "l", "l", "o"}, true},
+		3: {"日本", []string{"日", "本"}, true},
+		4: {"a\xffb", []string{"a", "\xff", "b"}, false},
+	}
+	for i, c := range cases {
+		if n := um.unicode_RuneCount(ctx, runtime.String(c.src)); n.Int(ctx) != int64(len(c.runes)) {
+			t.Errorf("[%d] - expected %d runes, got %d", i, len(c.runes), n.Int(ctx))
+		}
+		ob := um.unicode_Runes(ctx, runtime.String(c.src)).(runtime.Object)
+		if n := ob.Len(ctx).Int(ctx); n != int64(len(c.runes)) {
+			t.Errorf("[%d] - expected %d runes, got %d", i, len(c.runes), n)
+			continue
+		}
+		for j, r := range c.runes {
+			if got := ob.Get(runtime.Number(j)).String(ctx); got != r {
+				t.Errorf("[%d] - expected rune %d to be %q, got %q", i, j, r, got)
+			}
+		}
+		if v := um.unicode_Valid(ctx, runtime.String(c.src)); v.Bool(ctx) != c.valid {
+			t.Errorf("[%d] - expected valid to be %v", i, c.valid)
+		}
+	}
+}
+
+func TestUnicodeIs(t *testing.T) {
+	ctx := context.Background()
+	ktx := runtime.NewKtx(nil, nil)
+	um := new(UnicodeMod)
+	um.SetKtx(ktx)
+	if _, err := um.Run(ctx); err != nil {
+		t.Fatal(err)
+	}
+	cases := []struct {
+		fn  string
+		src string
+		exp bool
+	}{
+		0:  {"IsLetter", "é", true},
+		1:  {"IsLetter", "Ωmega", true},
+		2:  {"IsLetter", "a1", false},
+		3:  {"IsLetter", "", false},
+		4:  {"IsDigit", "٣4", true},
+		5:  {"IsDigit", "x", false},
+		6:  {"IsSpace", " \t ", true},
+		7:  {"IsSpace", "_", false},
+		8:  {"IsUpper", "ÉA", true},
+		9:  {"IsUpper", "Éa", false},
+		10: {"IsLower", "ß", true},
+		11: {"IsPunct", "¿!", true},
+	}
+	for i, c := range cases {
+		fn := um.ob.Get(runtime.String(c.fn)).(runtime.Func)
+		if ret := fn.Call(ctx, nil, runtime.String(c.src)); ret.Bool(ctx) != c.exp {
+			t.Errorf("[%d] - expected %s(%q) to be %v", i, c.fn, c.src, c.exp)
+		}
+	}
+}
+
+func TestUnicodeFold(t *testing.T) {
+	ctx := context.Background()
+	ktx := runtime.NewKtx(nil, nil)
+	um := new(UnicodeMod)
+	um.SetKtx(ktx)
+	pairs := [][2]string{
+		{"Straße", "STRAßE"},
+		{"ΣΊΣΥΦΟΣ", "σίσυφος"},
+		{"K", "k"}, // Kelvin sign
+		{"Jörg", "jÖRG"},
+	}
+	for i, p := range pairs {
+		f1 := um.unicode_Fold(ctx, runtime.String(p[0])).String(ctx)
+		f2 := um.unicode_Fold(ctx, runtime.String(p[1])).String(ctx)
+		if f1 != f2 {
+			t.Errorf("[%d] - expected same folding, got %q and %q", i, f1, f2)
+		}
+		if eq := um.unicode_EqualFold(ctx, runtime.String(p[0]), runtime.String(p[1])); !eq.Bool(ctx) {
+			t.Errorf("[%d] - expected equal under folding", i)
+		}
+	}
+	if eq := um.unicode_EqualFold(ctx, runtime.String("strasse"), runtime.String("straße")); eq.Bool(ctx) {
+		t.Errorf("expected no full case folding")
+	}
+}
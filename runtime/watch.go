@@ -0,0 +1,164 @@
+package runtime
+
+import (
+	"bytes"
+	"hash/fnv"
+	"io"
+	"io/fs"
+	"io/ioutil"
+	"sort"
+	"sync"
+	"time"
+)
+
+// A WatchingResolver is a ModuleResolver that keeps track of the modules it
+// resolved, and reports those whose source changed since then. The execution
+// context invalidates the changed modules on the next call to Load.
+type WatchingResolver interface {
+	ModuleResolver
+	Changed() []string
+}
+
+// The signature of a resolved module's source, used to detect changes.
+type srcSig struct {
+	modTime time.Time
+	size    int64
+	hash    uint64
+}
+
+// A WatchResolver wraps a ModuleResolver and watches the sources that it
+// resolves, so that the execution context picks up the changes to the modules
+// without having to be rebuilt. It is opt-in, as it resolves each watched
+// module again to check for changes, at most once per Interval.
+//
+// If the source returned by the wrapped resolver has a Stat method (such as
+// an *os.File or an fs.File), the modification time and size are compared,
+// otherwise the content is hashed.
+type WatchResolver struct {
+	Resolver ModuleResolver
+	Interval time.Duration // Minimum delay between two checks for changes
+
+	mu    sync.Mutex
+	sigs  map[string]srcSig
+	check time.Time
+}
+
+// NewWatchResolver returns a WatchResolver that wraps r, checking for changes
+// at most once per interval.
+func NewWatchResolver(r ModuleResolver, interval time.Duration) *WatchResolver {
+	return &WatchResolver{
+		Resolver: r,
+		Interval: interval,
+	}
+}
+
+// Resolve calls the wrapped resolver and records the signature of the source.
+func (w *WatchResolver) Resolve(id string) (io.Reader, error) {
+	r, _, err := w.ResolveKind(id)
+	return r, err
+}
+
+// ResolveKind calls the wrapped resolver and records the signature of the source.
+// The kind is empty if the wrapped resolver is not a KindResolver.
+func (w *WatchResolver) ResolveKind(id string) (io.Reader, string, error) {
+	r, kind, err := w.resolve(id)
+	if err != nil {
+		return nil, "", err
+	}
+	r, sig, err := signature(r)
+	if err != nil {
+		return nil, "", err
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.sigs == nil {
+		w.sigs = make(map[string]srcSig)
+	}
+	w.sigs[id] = sig
+	return r, kind, nil
+}
+
+// Qualify calls the wrapped resolver if it is a RelativeResolver, otherwise
+// it returns id unchanged.
+func (w *WatchResolver) Qualify(from, id string) string {
+	if rr, ok := w.Resolver.(RelativeResolver); ok {
+		return rr.Qualify(from, id)
+	}
+	return id
+}
+
+// Forget stops watching the module id, and forwards the call to the wrapped
+// resolver if it is a CachingResolver.
+func (w *WatchResolver) Forget(id string) {
+	w.mu.Lock()
+	delete(w.sigs, id)
+	w.mu.Unlock()
+	if cr, ok := w.Resolver.(CachingResolver); ok {
+		cr.Forget(id)
+	}
+}
+
+// Changed returns the IDs of the watched modules whose source changed since
+// they were resolved, sorted in lexical order. A module that cannot be resolved
+// anymore is considered changed. The changed modules are not watched anymore
+// until they are resolved again.
+func (w *WatchResolver) Changed() []string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	now := time.Now()
+	if now.Sub(w.check) < w.Interval {
+		return nil
+	}
+	w.check = now
+	var ids []string
+	for id, old := range w.sigs {
+		r, _, err := w.resolve(id)
+		if err == nil {
+			var sig srcSig
+			r, sig, err = signature(r)
+			closeReader(r)
+			if err == nil && sig == old {
+				continue
+			}
+		}
+		ids = append(ids, id)
+		delete(w.sigs, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// Call the wrapped resolver.
+func (w *WatchResolver) resolve(id string) (io.Reader, string, error) {
+	if kr, ok := w.Resolver.(KindResolver); ok {
+		return kr.ResolveKind(id)
+	}
+	r, err := w.Resolver.Resolve(id)
+	return r, "", err
+}
+
+// Close the reader if it is an io.Closer.
+func closeReader(r io.Reader) {
+	if rc, ok := r.(io.Closer); ok {
+		rc.Close()
+	}
+}
+
+// Compute the signature of the source. If the content has to be hashed, the
+// returned reader replaces r.
+func signature(r io.Reader) (io.Reader, srcSig, error) {
+	if st, ok := r.(interface {
+		Stat() (fs.FileInfo, error)
+	}); ok {
+		if fi, err := st.Stat(); err == nil {
+			return r, srcSig{modTime: fi.ModTime(), size: fi.Size()}, nil
+		}
+	}
+	h := fnv.New64a()
+	b, err := ioutil.ReadAll(io.TeeReader(r, h))
+	closeReader(r)
+	if err != nil {
+		return nil, srcSig{}, err
+	}
+	return bytes.NewReader(b), srcSig{size: int64(len(b)), hash: h.Sum64()}, nil
+}
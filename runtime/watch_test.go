@@ -0,0 +1,90 @@
+package runtime
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/saward/agora/compiler"
+)
+
+func TestInvalidate(t *testing.T) {
+	ctx := context.Background()
+	fsys := fstest.MapFS{
+		"a.agora": {Data: []byte(`return import("b") + import("c")`)},
+		"b.agora": {Data: []byte(`return import("c")`)},
+		"c.agora": {Data: []byte(`return "c"`)},
+		"d.agora": {Data: []byte(`return "d"`)},
+	}
+	ktx := NewKtx(NewFSResolver(fsys), new(compiler.Compiler))
+	ktx.TrackDeps = true
+	for _, id := range []string{"a", "d"} {
+		m, err := ktx.Load(id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := m.Run(ctx); err != nil {
+			t.Fatal(err)
+		}
+	}
+	ids := ktx.Invalidate("c")
+	sort.Strings(ids)
+	exp := []string{"a", "b", "c"}
+	if len(ids) != len(exp) {
+		t.Fatalf("expected %v to be invalidated, got %v", exp, ids)
+	}
+	for i, id := range exp {
+		if ids[i] != id {
+			t.Errorf("expected %v to be invalidated, got %v", exp, ids)
+		}
+	}
+	if _, ok := ktx.loadedMods["d"]; !ok {
+		t.Errorf("expected d to still be loaded")
+	}
+}
+
+func TestWatchResolverReload(t *testing.T) {
+	ctx := context.Background()
+	dir, err := ioutil.TempDir("", "agora-watch")
+	if err != nil {
+		panic(err)
+	}
+	defer os.RemoveAll(dir)
+	write := func(nm, src string, mt time.Time) {
+		fn := filepath.Join(dir, nm)
+		if err := ioutil.WriteFile(fn, []byte(src), 0644); err != nil {
+			panic(err)
+		}
+		if err := os.Chtimes(fn, mt, mt); err != nil {
+			panic(err)
+		}
+	}
+	mt := time.Now().Add(-time.Hour)
+	write("main.agora", `return import("dep")`, mt)
+	write("dep.agora", `return "v1"`, mt)
+
+	ktx := NewKtx(NewWatchResolver(NewFSResolver(os.DirFS(dir)), 0), new(compiler.Compiler))
+	ktx.TrackDeps = true
+	run := func(exp string) {
+		m, err := ktx.Load("main")
+		if err != nil {
+			t.Fatal(err)
+		}
+		v, err := m.Run(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if v.String(ctx) != exp {
+			t.Errorf("expected '%s', got '%s'", exp, v.String(ctx))
+		}
+	}
+	run("v1")
+	run("v1")
+	write("dep.agora", `return "v2"`, mt.Add(time.Minute))
+	run("v2")
+}